@@ -0,0 +1,56 @@
+// Command migrate runs one-off Firestore data migrations.
+//
+// Usage:
+//
+//	go run ./cmd/migrate subcollections
+//	go run ./cmd/migrate rekey-users
+//
+// It reads FIREBASE_SERVICE_ACCOUNT_KEY the same way the server does.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"backend/storage"
+	"backend/utils"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: migrate subcollections|rekey-users")
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, relying on system environment variables")
+	}
+
+	utils.InitFirebase()
+	defer utils.CloseFirestoreClient()
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "subcollections":
+		n, err := storage.MigrateEmbeddedUsers(ctx, utils.FirestoreClient)
+		if err != nil {
+			log.Fatalf("Migration failed after %d users: %v", n, err)
+		}
+		log.Printf("Moved lists, visits and watches of %d users into subcollections", n)
+	case "rekey-users":
+		moved, skipped, err := storage.RekeyUsers(ctx, utils.FirestoreClient)
+		if err != nil {
+			log.Fatalf("Migration failed after %d users: %v", moved, err)
+		}
+		log.Printf("Moved %d users to documents keyed by their user ID", moved)
+		for _, docID := range skipped {
+			log.Printf("Skipped users/%s: a document for the same user ID already exists", docID)
+		}
+	default:
+		log.Fatalf("Unknown migration %q", os.Args[1])
+	}
+}
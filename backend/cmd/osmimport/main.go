@@ -0,0 +1,56 @@
+// Command osmimport builds a local place file from an OSM PBF extract, such
+// as one from download.geofabrik.de, keeping the eating and drinking places
+// the map shows.
+//
+// Usage:
+//
+//	go run ./cmd/osmimport [-out places.db] extract.osm.pbf
+//
+// Point PLACE_STORE at the file to have the server search and look up
+// places in it instead of on Overpass. An existing file is replaced once
+// the import succeeds, so the server may keep running meanwhile; it picks up
+// the new file on restart.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"backend/placestore"
+)
+
+func main() {
+	out := flag.String("out", "places.db", "place file to write")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: osmimport [-out places.db] extract.osm.pbf")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	w, err := placestore.Create(ctx, *out)
+	if err != nil {
+		log.Fatalf("Error creating %s: %v", *out, err)
+	}
+	stats, err := importPBF(ctx, flag.Arg(0), w)
+	if err != nil {
+		w.Abort()
+		log.Fatalf("Import failed after %d places: %v", w.Count(), err)
+	}
+	if err := w.Commit(); err != nil {
+		log.Fatalf("Error writing %s: %v", *out, err)
+	}
+	log.Printf("Wrote %d nodes, %d ways and %d relations to %s in %s", stats.Nodes, stats.Ways, stats.Relations, *out, time.Since(start).Round(time.Second))
+	if stats.Skipped > 0 {
+		log.Printf("Skipped %d ways and relations with no nodes in the extract", stats.Skipped)
+	}
+}
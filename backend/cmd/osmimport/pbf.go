@@ -0,0 +1,167 @@
+package main
+
+import (
+	"backend/placestore"
+	"backend/services"
+	"context"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+)
+
+// importStats counts what an import wrote, by OSM type, and the places it
+// skipped because none of their nodes are in the extract.
+type importStats struct {
+	Nodes     int
+	Ways      int
+	Relations int
+	Skipped   int
+}
+
+// pendingPlace is a way or relation waiting for the coordinates of its
+// nodes.
+type pendingPlace struct {
+	tags  map[string]string
+	nodes []osm.NodeID
+}
+
+// importPBF writes the eating and drinking places of the OSM PBF extract at
+// path to w. Ways and relations are placed at the centre of their bounding
+// box, as Overpass places them. The extract is read three times, relations
+// first, so that only the nodes and ways the places need are kept in memory.
+func importPBF(ctx context.Context, path string, w *placestore.Writer) (importStats, error) {
+	var stats importStats
+
+	relations := make(map[osm.RelationID]pendingPlace)
+	memberWays := make(map[osm.WayID][]osm.RelationID)
+	err := scanPBF(ctx, path, func(s *osmpbf.Scanner) { s.SkipNodes, s.SkipWays = true, true }, func(object osm.Object) error {
+		relation := object.(*osm.Relation)
+		if !isEatery(relation.Tags) {
+			return nil
+		}
+		relations[relation.ID] = pendingPlace{tags: relation.Tags.Map()}
+		for _, member := range relation.Members {
+			if member.Type == osm.TypeWay {
+				memberWays[osm.WayID(member.Ref)] = append(memberWays[osm.WayID(member.Ref)], relation.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	ways := make(map[osm.WayID]pendingPlace)
+	neededNodes := make(map[osm.NodeID]bool)
+	err = scanPBF(ctx, path, func(s *osmpbf.Scanner) { s.SkipNodes, s.SkipRelations = true, true }, func(object osm.Object) error {
+		way := object.(*osm.Way)
+		relationIDs, isMember := memberWays[way.ID]
+		eatery := isEatery(way.Tags)
+		if !eatery && !isMember {
+			return nil
+		}
+		nodes := way.Nodes.NodeIDs()
+		for _, id := range nodes {
+			neededNodes[id] = true
+		}
+		if eatery {
+			ways[way.ID] = pendingPlace{tags: way.Tags.Map(), nodes: nodes}
+		}
+		for _, relationID := range relationIDs {
+			relation := relations[relationID]
+			relation.nodes = append(relation.nodes, nodes...)
+			relations[relationID] = relation
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	coords := make(map[osm.NodeID][2]float64, len(neededNodes))
+	err = scanPBF(ctx, path, func(s *osmpbf.Scanner) { s.SkipWays, s.SkipRelations = true, true }, func(object osm.Object) error {
+		node := object.(*osm.Node)
+		if neededNodes[node.ID] {
+			coords[node.ID] = [2]float64{node.Lat, node.Lon}
+		}
+		if !isEatery(node.Tags) {
+			return nil
+		}
+		stats.Nodes++
+		return w.Add(ctx, "node", strconv.FormatInt(int64(node.ID), 10), node.Lat, node.Lon, node.Tags.Map())
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	for id, way := range ways {
+		lat, long, ok := centre(way.nodes, coords)
+		if !ok {
+			stats.Skipped++
+			continue
+		}
+		if err := w.Add(ctx, "way", strconv.FormatInt(int64(id), 10), lat, long, way.tags); err != nil {
+			return stats, err
+		}
+		stats.Ways++
+	}
+	for id, relation := range relations {
+		lat, long, ok := centre(relation.nodes, coords)
+		if !ok {
+			stats.Skipped++
+			continue
+		}
+		if err := w.Add(ctx, "relation", strconv.FormatInt(int64(id), 10), lat, long, relation.tags); err != nil {
+			return stats, err
+		}
+		stats.Relations++
+	}
+	return stats, nil
+}
+
+// scanPBF reads the extract at path once, passing each element that setup
+// does not skip to handle.
+func scanPBF(ctx context.Context, path string, setup func(s *osmpbf.Scanner), handle func(object osm.Object) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := osmpbf.New(ctx, f, runtime.GOMAXPROCS(0))
+	defer scanner.Close()
+	setup(scanner)
+	for scanner.Scan() {
+		if err := handle(scanner.Object()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func isEatery(tags osm.Tags) bool {
+	return slices.Contains(services.EateryAmenities, tags.Find("amenity"))
+}
+
+// centre returns the centre of the bounding box of the nodes that are in
+// the extract, and false if none is.
+func centre(nodes []osm.NodeID, coords map[osm.NodeID][2]float64) (float64, float64, bool) {
+	south, west := math.Inf(1), math.Inf(1)
+	north, east := math.Inf(-1), math.Inf(-1)
+	found := false
+	for _, id := range nodes {
+		point, ok := coords[id]
+		if !ok {
+			continue
+		}
+		found = true
+		south, north = math.Min(south, point[0]), math.Max(north, point[0])
+		west, east = math.Min(west, point[1]), math.Max(east, point[1])
+	}
+	return (south + north) / 2, (west + east) / 2, found
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/paulmach/osm"
+)
+
+func TestCentre(t *testing.T) {
+	coords := map[osm.NodeID][2]float64{
+		1: {52.0, 13.0},
+		2: {53.0, 13.4},
+		3: {52.2, 14.0},
+	}
+
+	// Node 4 is not in the extract and is left out.
+	lat, long, ok := centre([]osm.NodeID{1, 2, 3, 4}, coords)
+	if !ok || lat != 52.5 || long != 13.5 {
+		t.Errorf("centre = %v, %v, %v; want the centre of the bounding box, 52.5, 13.5", lat, long, ok)
+	}
+	if _, _, ok := centre([]osm.NodeID{4, 5}, coords); ok {
+		t.Error("centre of nodes outside the extract: got ok, want false")
+	}
+}
+
+func TestIsEatery(t *testing.T) {
+	tags := map[string]bool{
+		"cafe":     true,
+		"pub":      true,
+		"bank":     false,
+		"":         false,
+		"Cafe":     false,
+		"cafe;bar": false,
+	}
+	for amenity, want := range tags {
+		if got := isEatery(osm.Tags{{Key: "amenity", Value: amenity}}); got != want {
+			t.Errorf("isEatery(amenity=%q) = %v, want %v", amenity, got, want)
+		}
+	}
+}
+
+func TestImportMissingExtract(t *testing.T) {
+	if _, err := importPBF(t.Context(), filepath.Join(t.TempDir(), "missing.osm.pbf"), nil); err == nil {
+		t.Error("importPBF of a missing extract: got no error")
+	}
+}
@@ -0,0 +1,16 @@
+package data
+
+import "time"
+
+// APIKey is a credential for scripts and internal tools. Only a hash of the
+// secret is stored; the secret itself is shown once when the key is created
+// or rotated.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"-"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	RotatedAt *time.Time `json:"rotatedAt"`
+}
@@ -0,0 +1,24 @@
+package data
+
+import "time"
+
+// CatalogPlace is the shared record of an OSM place that the lists, visits
+// and watches of all users refer to. Key is "<osm type>/<osm id>", such as
+// "node/123", so that a node and a way with the same ID stay apart.
+// Missing places were looked up but are no longer in OSM.
+type CatalogPlace struct {
+	Key          string            `json:"key"`
+	OsmType      string            `json:"osm_type"`
+	OsmID        string            `json:"osm_id"`
+	Name         string            `json:"name"`
+	Lat          float64           `json:"lat"`
+	Long         float64           `json:"long"`
+	Cuisine      string            `json:"cuisine"`
+	Address      string            `json:"address"`
+	OpeningHours string            `json:"opening_hours"`
+	Website      string            `json:"website"`
+	Phone        string            `json:"phone"`
+	Tags         map[string]string `json:"tags"`
+	Missing      bool              `json:"-"`
+	FetchedAt    time.Time         `json:"fetched_at"`
+}
@@ -0,0 +1,16 @@
+package data
+
+import "time"
+
+// Change records the latest change to one of a user's lists, visited places
+// or watched places. Kind is "list", "visit" or "watch" and Key the list ID
+// or the place key, such as "node/123", or a bare OSM ID for places without
+// a type. Seq orders the changes of a user; deleted entities keep their
+// change as a tombstone.
+type Change struct {
+	Kind      string    `json:"kind"`
+	Key       string    `json:"key"`
+	Seq       int64     `json:"seq"`
+	Deleted   bool      `json:"deleted"`
+	ChangedAt time.Time `json:"changedAt"`
+}
@@ -0,0 +1,41 @@
+package data
+
+import "time"
+
+// Import is an import waiting for review. Its entries are only added to
+// lists once the import is committed.
+type Import struct {
+	ID        string        `json:"id"`
+	Source    string        `json:"source"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Entries   []ImportEntry `json:"entries"`
+}
+
+// ImportEntry is one saved place of an import. Match is the OSM place it
+// will be added as, and Candidates are nearby places to choose from.
+type ImportEntry struct {
+	ID          int          `json:"id"`
+	List        string       `json:"list"`
+	Title       string       `json:"title"`
+	Address     string       `json:"address"`
+	Note        string       `json:"note"`
+	URL         string       `json:"url"`
+	SavedAt     *time.Time   `json:"savedAt"`
+	HasLocation bool         `json:"hasLocation"`
+	Lat         float64      `json:"lat"`
+	Long        float64      `json:"long"`
+	Status      string       `json:"status"`
+	Match       *PlaceMatch  `json:"match"`
+	Candidates  []PlaceMatch `json:"candidates"`
+}
+
+// PlaceMatch is an OSM place found for an imported entry. Distance is in
+// metres from the entry, or 0 if the entry has no location.
+type PlaceMatch struct {
+	OsmID    string  `json:"osm_id"`
+	OsmType  string  `json:"osm_type"`
+	Name     string  `json:"name"`
+	Long     float64 `json:"long"`
+	Lat      float64 `json:"lat"`
+	Distance float64 `json:"distance"`
+}
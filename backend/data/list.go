@@ -2,7 +2,19 @@ package data
 
 // List represents a collection of places on the map.
 type List struct {
-	ID       string  `json:"id"`
-	ListName string  `json:"list_name"`
-	Places   []Place `json:"places"`
+	ID          string `json:"id"`
+	ListName    string `json:"list_name"`
+	Description string `json:"description"`
+	Colour      string `json:"colour"`
+	Icon        string `json:"icon"`
+	// Visibility is "private" or "unlisted", or "public" on older lists,
+	// which is read as unlisted. Shared lists can be read by anyone holding
+	// ShareToken.
+	Visibility string  `json:"visibility"`
+	ShareToken string  `json:"share_token"`
+	Places     []Place `json:"places"`
+	// OwnerID and Role tell whose list it is and what the reading user may
+	// do with it. They are filled in when the list is read.
+	OwnerID string `json:"owner_id" firestore:"-"`
+	Role    string `json:"role" firestore:"-"`
 }
@@ -0,0 +1,16 @@
+package data
+
+import "time"
+
+// ListMember gives a user other than the owner access to a list. Invited
+// members have Status "pending" until they accept.
+type ListMember struct {
+	OwnerID    string     `json:"ownerId"`
+	ListID     string     `json:"listId"`
+	UserID     string     `json:"userId"`
+	Role       string     `json:"role"`
+	Status     string     `json:"status"`
+	InvitedBy  string     `json:"invitedBy"`
+	InvitedAt  time.Time  `json:"invitedAt"`
+	AcceptedAt *time.Time `json:"acceptedAt"`
+}
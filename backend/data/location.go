@@ -0,0 +1,19 @@
+package data
+
+import "time"
+
+// Location is what reverse geocoding found at a point. Locations are cached
+// by Key, the point rounded to three decimals, about 100 m, such as
+// "51.513,-0.140", and Lat and Long are the rounded point. All fields but
+// the point are empty where nothing was found, such as at sea.
+type Location struct {
+	Key           string    `json:"-"`
+	Lat           float64   `json:"lat"`
+	Long          float64   `json:"long"`
+	City          string    `json:"city"`
+	Neighbourhood string    `json:"neighbourhood"`
+	Country       string    `json:"country"`
+	CountryCode   string    `json:"country_code"`
+	DisplayName   string    `json:"display_name"`
+	FetchedAt     time.Time `json:"fetched_at"`
+}
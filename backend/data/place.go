@@ -1,9 +1,27 @@
 package data
 
+import "time"
+
 // Place represents a specific place with OSM data enclosed.
 type Place struct {
 	OsmID   string  `json:"osm_id"`
 	OsmType string  `json:"osm_type"`
 	Long    float64 `json:"long"`
 	Lat     float64 `json:"lat"`
+	// City, Neighbourhood and Country are where the place is. They are
+	// filled in by reverse geocoding after the place is saved, unless the
+	// client gives them.
+	City          string `json:"city"`
+	Neighbourhood string `json:"neighbourhood"`
+	Country       string `json:"country"`
+	// Note, AddedAt and AddedBy describe the place's entry in a list.
+	Note    string     `json:"note"`
+	AddedAt *time.Time `json:"added_at"`
+	AddedBy string     `json:"added_by"`
+	// Position is the entry's index in its list. It follows from the order of
+	// the list and is filled in when the list is read.
+	Position int `json:"position" firestore:"-"`
+	// Details is the place's catalog entry. It is filled in when the place
+	// is read and is not stored with it.
+	Details *CatalogPlace `json:"details,omitempty" firestore:"-"`
 }
@@ -3,9 +3,20 @@ package data
 import "time"
 
 type UserPlace struct {
-	OsmID     string     `json:"osmID"`
+	OsmID string `json:"osmID"`
+	// OsmType is "node", "way" or "relation", or empty for places recorded
+	// before the type was kept.
+	OsmType   string     `json:"osmType"`
 	Tags      []string   `json:"tags"`
 	Rating    *int8      `json:"rating"`
 	VisitedAt *time.Time `json:"visitedAt"`
 	RatedAt   *time.Time `json:"ratedAt"`
+	// Visits is the visit history of a visited place, oldest first.
+	Visits []Visit `json:"visits"`
+	// VisitCount and LastVisitedAt summarise Visits. They are filled in when
+	// the place is read and are not stored.
+	VisitCount    int        `json:"visitCount" firestore:"-"`
+	LastVisitedAt *time.Time `json:"lastVisitedAt" firestore:"-"`
+	// Details is the place's catalog entry, filled in like VisitCount.
+	Details *CatalogPlace `json:"details,omitempty" firestore:"-"`
 }
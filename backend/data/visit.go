@@ -0,0 +1,12 @@
+package data
+
+import "time"
+
+// Visit is one dated trip to a visited place.
+type Visit struct {
+	ID        string    `json:"id"`
+	VisitedAt time.Time `json:"visitedAt"`
+	Rating    *int8     `json:"rating"`
+	Notes     string    `json:"notes"`
+	Tags      []string  `json:"tags"`
+}
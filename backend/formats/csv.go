@@ -0,0 +1,171 @@
+package formats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{
+	"collection", "name", "osm_type", "osm_id", "latitude", "longitude",
+	"note", "tags", "rating", "visit_count", "last_visited_at", "visit_dates", "added_at",
+}
+
+// WriteCSV writes one row per place. Multiple tags and visit dates are
+// separated by semicolons. Text cells that a spreadsheet would run as a
+// formula are escaped with a leading apostrophe.
+func WriteCSV(w io.Writer, features []Feature) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, f := range features {
+		var lat, lon, rating, visitCount string
+		if f.HasLocation {
+			lat, lon = formatFloat(f.Lat), formatFloat(f.Lon)
+		}
+		if f.Rating != nil {
+			rating = strconv.Itoa(int(*f.Rating))
+		}
+		if f.VisitCount > 0 {
+			visitCount = strconv.Itoa(f.VisitCount)
+		}
+		visitDates := make([]string, len(f.VisitDates))
+		for i, date := range f.VisitDates {
+			visitDates[i] = date.UTC().Format(time.RFC3339)
+		}
+
+		err := writer.Write([]string{
+			csvText(f.Collection), csvText(f.Name), csvText(f.OsmType), csvText(f.OsmID), lat, lon,
+			csvText(f.Note), csvText(strings.Join(f.Tags, ";")), rating, visitCount,
+			formatTime(f.LastVisitedAt), strings.Join(visitDates, ";"), formatTime(f.AddedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvFormulaPrefixes are the characters that make a spreadsheet read a
+// cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvText escapes a text cell that would be read as a formula.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune(csvFormulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// csvUnescape undoes csvText, so exported files import unchanged.
+func csvUnescape(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(text[1])) {
+		return text[1:]
+	}
+	return text
+}
+
+var (
+	csvLatColumns   = []string{"latitude", "lat", "y"}
+	csvLonColumns   = []string{"longitude", "lon", "lng", "long", "x"}
+	csvNameColumns  = []string{"name", "title"}
+	csvNoteColumns  = []string{"note", "description", "comment"}
+	csvURLColumns   = []string{"url", "link"}
+	wktPointPattern = regexp.MustCompile(`(?i)^\s*POINT\s*\(\s*(\S+)\s+(\S+)\s*\)\s*$`)
+)
+
+// ReadCSV reads a CSV file with a header row. Coordinates come from
+// latitude/longitude columns or from a WKT column with POINT values, as
+// exported by Google My Maps.
+func ReadCSV(r io.Reader) ([]Feature, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := headerColumns(header)
+	_, hasWKT := columns["wkt"]
+	if !hasWKT && (findColumn(columns, csvLatColumns) < 0 || findColumn(columns, csvLonColumns) < 0) {
+		return nil, nil, fmt.Errorf("invalid CSV: needs latitude and longitude columns or a WKT column")
+	}
+
+	var features []Feature
+	var rowErrors []RowError
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		value := func(names ...string) string {
+			if i := findColumn(columns, names); i >= 0 && i < len(record) {
+				return csvUnescape(strings.TrimSpace(record[i]))
+			}
+			return ""
+		}
+
+		lat, lon, ok := csvCoordinates(value, hasWKT)
+		if !ok {
+			rowErrors = append(rowErrors, RowError{Row: line, Message: "missing or invalid coordinates"})
+			continue
+		}
+		f := Feature{
+			Row:         line,
+			Name:        value(csvNameColumns...),
+			Note:        value(csvNoteColumns...),
+			HasLocation: true,
+			Lat:         lat,
+			Lon:         lon,
+		}
+		setOSMRef(&f, value("osm_id"), value("osm_type"), value(csvURLColumns...), f.Name)
+		features = append(features, f)
+	}
+	return features, rowErrors, nil
+}
+
+func csvCoordinates(value func(names ...string) string, hasWKT bool) (float64, float64, bool) {
+	var latText, lonText string
+	if match := wktPointPattern.FindStringSubmatch(value("wkt")); hasWKT && match != nil {
+		lonText, latText = match[1], match[2]
+	} else {
+		latText, lonText = value(csvLatColumns...), value(csvLonColumns...)
+	}
+	lat, errLat := strconv.ParseFloat(latText, 64)
+	lon, errLon := strconv.ParseFloat(lonText, 64)
+	if errLat != nil || errLon != nil || !validCoordinates(lat, lon) {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// headerColumns maps the lower-cased column names of a CSV header to their
+// index. A byte order mark before the first name is dropped.
+func headerColumns(header []string) map[string]int {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	return columns
+}
+
+func findColumn(columns map[string]int, names []string) int {
+	for _, name := range names {
+		if i, ok := columns[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,52 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	features := []Feature{{
+		Collection:  "@lunch",
+		Name:        "=HYPERLINK(\"http://example.com\")",
+		OsmType:     "node",
+		OsmID:       "1",
+		HasLocation: true,
+		Lat:         -33.8688,
+		Lon:         151.2093,
+		Note:        "+1 for the noodles",
+		Tags:        []string{"-cheap", "quiet"},
+	}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, features); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the CSV: %v", err)
+	}
+
+	row := records[1]
+	want := map[int]string{
+		0: "'@lunch",
+		1: "'=HYPERLINK(\"http://example.com\")",
+		4: "-33.8688",
+		6: "'+1 for the noodles",
+		7: "'-cheap;quiet",
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("column %s = %q, want %q", records[0][column], row[column], value)
+		}
+	}
+
+	// Exported files import with their original names and notes.
+	imported, rowErrors, err := ReadCSV(bytes.NewReader(buf.Bytes()))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ReadCSV: %v, row errors %v", err, rowErrors)
+	}
+	if imported[0].Name != features[0].Name || imported[0].Note != features[0].Note || imported[0].Lat != features[0].Lat {
+		t.Errorf("ReadCSV = %+v, want the exported name, note and coordinates", imported[0])
+	}
+}
@@ -0,0 +1,111 @@
+// Package formats converts places to and from the geo file formats users
+// move data between apps with: GeoJSON, KML, GPX and CSV.
+package formats
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format is a supported file format.
+type Format string
+
+const (
+	GeoJSON Format = "geojson"
+	KML     Format = "kml"
+	GPX     Format = "gpx"
+	CSV     Format = "csv"
+)
+
+// ParseFormat accepts a format name in any case.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(name)); format {
+	case GeoJSON, KML, GPX, CSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, use geojson, kml, gpx or csv", name)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case GeoJSON:
+		return "application/geo+json"
+	case KML:
+		return "application/vnd.google-earth.kml+xml"
+	case GPX:
+		return "application/gpx+xml"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Feature is one exported place. Collection names where it comes from, such
+// as a list name, "visited" or "watched".
+type Feature struct {
+	// Row is the feature's position in an imported file.
+	Row        int
+	Collection string
+	Name       string
+	OsmID      string
+	OsmType    string
+	// HasLocation is false for places whose coordinates are unknown; they
+	// are left out of formats that need coordinates.
+	HasLocation   bool
+	Lat           float64
+	Lon           float64
+	Note          string
+	Tags          []string
+	Rating        *int8
+	VisitCount    int
+	LastVisitedAt *time.Time
+	VisitDates    []time.Time
+	AddedAt       *time.Time
+}
+
+// Write encodes features in the given format. title names the document in
+// formats that have one.
+func Write(w io.Writer, format Format, title string, features []Feature) error {
+	switch format {
+	case GeoJSON:
+		return WriteGeoJSON(w, features)
+	case KML:
+		return WriteKML(w, title, features)
+	case GPX:
+		return WriteGPX(w, title, features)
+	case CSV:
+		return WriteCSV(w, features)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// description sums up a feature's note, tags, rating and visits as text for
+// formats without structured fields for them.
+func description(f Feature) string {
+	var parts []string
+	if f.Note != "" {
+		parts = append(parts, f.Note)
+	}
+	if len(f.Tags) > 0 {
+		parts = append(parts, "Tags: "+strings.Join(f.Tags, ", "))
+	}
+	if f.Rating != nil {
+		parts = append(parts, fmt.Sprintf("Rating: %d", *f.Rating))
+	}
+	if f.VisitCount > 0 && f.LastVisitedAt != nil {
+		parts = append(parts, fmt.Sprintf("Visits: %d, last on %s", f.VisitCount, f.LastVisitedAt.Format(time.DateOnly)))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,151 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   *geoJSONGeometry  `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	Name          string      `json:"name"`
+	Collection    string      `json:"collection,omitempty"`
+	OsmID         string      `json:"osm_id,omitempty"`
+	OsmType       string      `json:"osm_type,omitempty"`
+	Note          string      `json:"note,omitempty"`
+	Tags          []string    `json:"tags,omitempty"`
+	Rating        *int8       `json:"rating,omitempty"`
+	VisitCount    int         `json:"visit_count,omitempty"`
+	LastVisitedAt *time.Time  `json:"last_visited_at,omitempty"`
+	VisitDates    []time.Time `json:"visit_dates,omitempty"`
+	AddedAt       *time.Time  `json:"added_at,omitempty"`
+}
+
+// WriteGeoJSON writes a FeatureCollection of points. Places without a
+// location get a null geometry.
+func WriteGeoJSON(w io.Writer, features []Feature) error {
+	collection := geoJSONCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, len(features))}
+	for i, f := range features {
+		feature := geoJSONFeature{
+			Type: "Feature",
+			Properties: geoJSONProperties{
+				Name:          f.Name,
+				Collection:    f.Collection,
+				OsmID:         f.OsmID,
+				OsmType:       f.OsmType,
+				Note:          f.Note,
+				Tags:          f.Tags,
+				Rating:        f.Rating,
+				VisitCount:    f.VisitCount,
+				LastVisitedAt: f.LastVisitedAt,
+				VisitDates:    f.VisitDates,
+				AddedAt:       f.AddedAt,
+			},
+		}
+		if f.HasLocation {
+			feature.Geometry = &geoJSONGeometry{Type: "Point", Coordinates: []float64{f.Lon, f.Lat}}
+		}
+		collection.Features[i] = feature
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(collection)
+}
+
+type geoJSONInput struct {
+	Type       string                 `json:"type"`
+	Features   []geoJSONInput         `json:"features"`
+	Geometry   *geoJSONInputGeometry  `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	ID         interface{}            `json:"id"`
+}
+
+type geoJSONInputGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ReadGeoJSON reads the Point features of a FeatureCollection or a single
+// Feature.
+func ReadGeoJSON(r io.Reader) ([]Feature, []RowError, error) {
+	var input geoJSONInput
+	if err := json.NewDecoder(r).Decode(&input); err != nil {
+		return nil, nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	items := input.Features
+	switch input.Type {
+	case "FeatureCollection":
+	case "Feature":
+		items = []geoJSONInput{input}
+	default:
+		return nil, nil, fmt.Errorf("invalid GeoJSON: expected a Feature or FeatureCollection, got %q", input.Type)
+	}
+
+	var features []Feature
+	var rowErrors []RowError
+	for i, item := range items {
+		row := i + 1
+		if item.Geometry == nil || item.Geometry.Type != "Point" {
+			rowErrors = append(rowErrors, RowError{Row: row, Message: "feature is not a point"})
+			continue
+		}
+		var coordinates []float64
+		if err := json.Unmarshal(item.Geometry.Coordinates, &coordinates); err != nil || len(coordinates) < 2 || !validCoordinates(coordinates[1], coordinates[0]) {
+			rowErrors = append(rowErrors, RowError{Row: row, Message: "invalid point coordinates"})
+			continue
+		}
+
+		props := item.Properties
+		f := Feature{
+			Row:         row,
+			Name:        firstString(props, "name", "title"),
+			Note:        firstString(props, "note", "description", "desc"),
+			HasLocation: true,
+			Lat:         coordinates[1],
+			Lon:         coordinates[0],
+		}
+		setOSMRef(&f, firstString(props, "osm_id"), firstString(props, "osm_type"), firstString(props, "@id", "id"), stringValue(item.ID), f.Name)
+		features = append(features, f)
+	}
+	return features, rowErrors, nil
+}
+
+// firstString returns the first of keys that holds a non-empty value.
+func firstString(props map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value := stringValue(props[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package formats
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const gpxNamespace = "http://www.topografix.com/GPX/1/1"
+
+type gpxRoot struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Xmlns     string        `xml:"xmlns,attr"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Metadata  gpxMetadata   `xml:"metadata"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+}
+
+type gpxWaypoint struct {
+	Lat         float64 `xml:"lat,attr"`
+	Lon         float64 `xml:"lon,attr"`
+	Time        string  `xml:"time,omitempty"`
+	Name        string  `xml:"name"`
+	Description string  `xml:"desc,omitempty"`
+	Type        string  `xml:"type,omitempty"`
+}
+
+// WriteGPX writes a waypoint per place, as understood by OsmAnd and most
+// GPS apps. The collection becomes the waypoint type, which OsmAnd shows as
+// a favourites group. Places without a location are left out.
+func WriteGPX(w io.Writer, title string, features []Feature) error {
+	root := gpxRoot{Xmlns: gpxNamespace, Version: "1.1", Creator: "EatFinder", Metadata: gpxMetadata{Name: title}}
+	for _, f := range features {
+		if !f.HasLocation {
+			continue
+		}
+		root.Waypoints = append(root.Waypoints, gpxWaypoint{
+			Lat:         f.Lat,
+			Lon:         f.Lon,
+			Time:        formatTime(f.LastVisitedAt),
+			Name:        f.Name,
+			Description: description(f),
+			Type:        f.Collection,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(root)
+}
+
+type gpxInput struct {
+	Waypoints []struct {
+		Lat         float64 `xml:"lat,attr"`
+		Lon         float64 `xml:"lon,attr"`
+		Name        string  `xml:"name"`
+		Description string  `xml:"desc"`
+		Comment     string  `xml:"cmt"`
+		Links       []struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"wpt"`
+}
+
+// ReadGPX reads the waypoints of a GPX file. Tracks and routes are ignored.
+func ReadGPX(r io.Reader) ([]Feature, []RowError, error) {
+	var input gpxInput
+	if err := xml.NewDecoder(r).Decode(&input); err != nil {
+		return nil, nil, fmt.Errorf("invalid GPX: %w", err)
+	}
+
+	var features []Feature
+	var rowErrors []RowError
+	for i, wpt := range input.Waypoints {
+		row := i + 1
+		if !validCoordinates(wpt.Lat, wpt.Lon) {
+			rowErrors = append(rowErrors, RowError{Row: row, Message: "invalid waypoint coordinates"})
+			continue
+		}
+
+		f := Feature{
+			Row:         row,
+			Name:        strings.TrimSpace(wpt.Name),
+			Note:        strings.TrimSpace(wpt.Description),
+			HasLocation: true,
+			Lat:         wpt.Lat,
+			Lon:         wpt.Lon,
+		}
+		if f.Note == "" {
+			f.Note = strings.TrimSpace(wpt.Comment)
+		}
+		refs := []string{f.Name}
+		for _, link := range wpt.Links {
+			refs = append(refs, link.Href)
+		}
+		setOSMRef(&f, "", "", refs...)
+		features = append(features, f)
+	}
+	return features, rowErrors, nil
+}
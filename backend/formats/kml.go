@@ -0,0 +1,182 @@
+package formats
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+type kmlRoot struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name    string      `xml:"name"`
+	Folders []kmlFolder `xml:"Folder"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name         string           `xml:"name"`
+	Description  string           `xml:"description,omitempty"`
+	ExtendedData *kmlExtendedData `xml:"ExtendedData,omitempty"`
+	Point        kmlPoint         `xml:"Point"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteKML writes one folder per collection, which Google My Maps imports as
+// separate layers. Places without a location are left out.
+func WriteKML(w io.Writer, title string, features []Feature) error {
+	root := kmlRoot{Xmlns: kmlNamespace, Document: kmlDocument{Name: title}}
+	folders := make(map[string]int)
+	for _, f := range features {
+		if !f.HasLocation {
+			continue
+		}
+		i, ok := folders[f.Collection]
+		if !ok {
+			i = len(root.Document.Folders)
+			folders[f.Collection] = i
+			root.Document.Folders = append(root.Document.Folders, kmlFolder{Name: f.Collection})
+		}
+
+		placemark := kmlPlacemark{
+			Name:        f.Name,
+			Description: description(f),
+			Point:       kmlPoint{Coordinates: formatFloat(f.Lon) + "," + formatFloat(f.Lat) + ",0"},
+		}
+		if data := kmlFields(f); len(data) > 0 {
+			placemark.ExtendedData = &kmlExtendedData{Data: data}
+		}
+		root.Document.Folders[i].Placemarks = append(root.Document.Folders[i].Placemarks, placemark)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(root)
+}
+
+func kmlFields(f Feature) []kmlData {
+	var data []kmlData
+	add := func(name, value string) {
+		if value != "" {
+			data = append(data, kmlData{Name: name, Value: value})
+		}
+	}
+	add("osm_id", f.OsmID)
+	add("osm_type", f.OsmType)
+	if f.Rating != nil {
+		add("rating", strconv.Itoa(int(*f.Rating)))
+	}
+	if f.VisitCount > 0 {
+		add("visit_count", strconv.Itoa(f.VisitCount))
+	}
+	add("last_visited_at", formatTime(f.LastVisitedAt))
+	add("added_at", formatTime(f.AddedAt))
+	return data
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+type kmlPlacemarkInput struct {
+	Name         string `xml:"name"`
+	Description  string `xml:"description"`
+	ExtendedData struct {
+		Data []kmlData `xml:"Data"`
+	} `xml:"ExtendedData"`
+	Point *kmlPoint `xml:"Point"`
+}
+
+// ReadKML reads every point Placemark, however deeply it is nested in
+// folders.
+func ReadKML(r io.Reader) ([]Feature, []RowError, error) {
+	decoder := xml.NewDecoder(r)
+	var features []Feature
+	var rowErrors []RowError
+	row := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid KML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+
+		row++
+		var placemark kmlPlacemarkInput
+		if err := decoder.DecodeElement(&placemark, &start); err != nil {
+			return nil, nil, fmt.Errorf("invalid KML: %w", err)
+		}
+		if placemark.Point == nil {
+			rowErrors = append(rowErrors, RowError{Row: row, Message: "placemark is not a point"})
+			continue
+		}
+		lat, lon, ok := parseKMLCoordinates(placemark.Point.Coordinates)
+		if !ok {
+			rowErrors = append(rowErrors, RowError{Row: row, Message: "invalid point coordinates"})
+			continue
+		}
+
+		data := make(map[string]string)
+		for _, d := range placemark.ExtendedData.Data {
+			data[d.Name] = strings.TrimSpace(d.Value)
+		}
+		f := Feature{
+			Row:         row,
+			Name:        strings.TrimSpace(placemark.Name),
+			Note:        strings.TrimSpace(placemark.Description),
+			HasLocation: true,
+			Lat:         lat,
+			Lon:         lon,
+		}
+		setOSMRef(&f, data["osm_id"], data["osm_type"], f.Name, f.Note)
+		features = append(features, f)
+	}
+	return features, rowErrors, nil
+}
+
+// parseKMLCoordinates reads "lon,lat[,alt]".
+func parseKMLCoordinates(coordinates string) (float64, float64, bool) {
+	parts := strings.Split(strings.TrimSpace(coordinates), ",")
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if errLon != nil || errLat != nil || !validCoordinates(lat, lon) {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
@@ -0,0 +1,80 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RowError reports a feature of an imported file that could not be read.
+// Row is the feature's 1-based position in the file, or its line for CSV.
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"error"`
+}
+
+var osmRefPattern = regexp.MustCompile(`(?i)\b(node|way|relation)[/ ]?(\d+)\b`)
+
+// FormatFromFilename guesses the format from a file extension.
+func FormatFromFilename(filename string) (Format, error) {
+	switch ext := strings.ToLower(path.Ext(filename)); ext {
+	case ".json":
+		return GeoJSON, nil
+	case "":
+		return "", fmt.Errorf("cannot tell the format of %q, please name it", filename)
+	default:
+		return ParseFormat(strings.TrimPrefix(ext, "."))
+	}
+}
+
+// Read decodes the point features of a file. Features that cannot be used
+// are reported as RowErrors; an error is only returned if the file as a
+// whole cannot be read.
+func Read(r io.Reader, format Format) ([]Feature, []RowError, error) {
+	switch format {
+	case GeoJSON:
+		return ReadGeoJSON(r)
+	case KML:
+		return ReadKML(r)
+	case GPX:
+		return ReadGPX(r)
+	case CSV:
+		return ReadCSV(r)
+	}
+	return nil, nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// parseOSMRef finds an OSM element reference such as "node/123", as used in
+// Overpass exports and openstreetmap.org URLs.
+func parseOSMRef(s string) (string, string, bool) {
+	match := osmRefPattern.FindStringSubmatch(s)
+	if match == nil {
+		return "", "", false
+	}
+	return strings.ToLower(match[1]), match[2], true
+}
+
+// setOSMRef fills in the OSM identifiers of f from an explicit ID and type,
+// or else from the first of refs that holds a reference.
+func setOSMRef(f *Feature, osmID string, osmType string, refs ...string) {
+	osmID, osmType = strings.TrimSpace(osmID), strings.ToLower(strings.TrimSpace(osmType))
+	if osmID != "" {
+		if refType, refID, ok := parseOSMRef(osmID); ok {
+			osmType, osmID = refType, refID
+		}
+		f.OsmID, f.OsmType = osmID, osmType
+		return
+	}
+	for _, ref := range refs {
+		if refType, refID, ok := parseOSMRef(ref); ok {
+			f.OsmID, f.OsmType = refID, refType
+			return
+		}
+	}
+}
+
+func validCoordinates(lat float64, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
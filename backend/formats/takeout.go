@@ -0,0 +1,296 @@
+package formats
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StarredPlacesList names the list that the places of a Takeout "Saved
+// Places.json" file are imported into.
+const StarredPlacesList = "Starred places"
+
+const (
+	// maxTakeoutFileSize caps how much of each file in a Takeout archive is
+	// read, and maxTakeoutArchiveSize how much of all of them together, so a
+	// small upload cannot unpack into a huge one.
+	maxTakeoutFileSize    = 20 << 20
+	maxTakeoutArchiveSize = 50 << 20
+	// maxTakeoutFiles caps how many saved place files an archive may hold.
+	maxTakeoutFiles = 500
+)
+
+// googleCoordinatePatterns find coordinates in Google Maps URLs, in the
+// forms ".../@52.5,13.4,17z", "...!3d52.5!4d13.4", "?q=52.5,13.4" and
+// ".../search/52.5,+13.4".
+var googleCoordinatePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`!3d(-?\d+(?:\.\d+)?)!4d(-?\d+(?:\.\d+)?)`),
+	regexp.MustCompile(`@(-?\d+(?:\.\d+)?),(-?\d+(?:\.\d+)?)`),
+	regexp.MustCompile(`[?&](?:q|query|ll)=(-?\d+(?:\.\d+)?),\+?(-?\d+(?:\.\d+)?)`),
+	regexp.MustCompile(`/search/(-?\d+(?:\.\d+)?),\+?(-?\d+(?:\.\d+)?)`),
+}
+
+// TakeoutPlace is a place saved in Google Maps, as found in a Google
+// Takeout export. List names the Google list it was saved to.
+type TakeoutPlace struct {
+	List        string
+	Title       string
+	Address     string
+	Note        string
+	URL         string
+	SavedAt     *time.Time
+	HasLocation bool
+	Lat         float64
+	Lon         float64
+}
+
+type takeoutSavedPlaces struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		// Field names are matched case-insensitively, which covers both the
+		// current export ("location", "address") and the older one
+		// ("Location", "Address").
+		Properties struct {
+			Date          string `json:"date"`
+			Published     string `json:"Published"`
+			Title         string `json:"Title"`
+			Comment       string `json:"Comment"`
+			GoogleMapsURL string `json:"google_maps_url"`
+			LegacyURL     string `json:"Google Maps URL"`
+			Location      struct {
+				Name           string `json:"name"`
+				BusinessName   string `json:"Business Name"`
+				Address        string `json:"address"`
+				GeoCoordinates struct {
+					Latitude  json.RawMessage `json:"Latitude"`
+					Longitude json.RawMessage `json:"Longitude"`
+				} `json:"Geo Coordinates"`
+			} `json:"location"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// ReadTakeout reads a file from a Google Takeout export: "Saved
+// Places.json", a saved list CSV such as "Saved/Want to go.csv", or a
+// Takeout .zip archive holding any of them. Other files in an archive are
+// ignored.
+func ReadTakeout(filename string, content []byte) ([]TakeoutPlace, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".zip":
+		return readTakeoutArchive(content)
+	case ".json":
+		return ReadSavedPlaces(bytes.NewReader(content))
+	case ".csv":
+		return ReadSavedList(bytes.NewReader(content), takeoutListName(filename))
+	}
+	return nil, fmt.Errorf("%q is not a Takeout file, expected Saved Places.json, a saved list .csv or a .zip archive", filename)
+}
+
+func readTakeoutArchive(content []byte) ([]TakeoutPlace, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	var places []TakeoutPlace
+	files, remaining := 0, maxTakeoutArchiveSize
+	for _, file := range archive.File {
+		name := file.Name
+		isSavedPlaces := strings.EqualFold(path.Base(name), "Saved Places.json")
+		isSavedList := strings.EqualFold(path.Ext(name), ".csv") && strings.EqualFold(path.Base(path.Dir(name)), "Saved")
+		if !isSavedPlaces && !isSavedList {
+			continue
+		}
+		if files++; files > maxTakeoutFiles {
+			return nil, fmt.Errorf("the archive holds more than %d saved place files", maxTakeoutFiles)
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		fileContent, err := io.ReadAll(io.LimitReader(r, int64(min(maxTakeoutFileSize, remaining))+1))
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(fileContent) > maxTakeoutFileSize {
+			return nil, fmt.Errorf("%s is larger than %d MB", name, maxTakeoutFileSize>>20)
+		}
+		if len(fileContent) > remaining {
+			return nil, fmt.Errorf("the saved places in the archive are larger than %d MB", maxTakeoutArchiveSize>>20)
+		}
+		remaining -= len(fileContent)
+
+		filePlaces, err := ReadTakeout(name, fileContent)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		places = append(places, filePlaces...)
+	}
+	if len(places) == 0 {
+		return nil, fmt.Errorf("the archive holds no saved places")
+	}
+	return places, nil
+}
+
+// ReadSavedPlaces reads the starred places of a Takeout "Saved
+// Places.json" file. Places saved without coordinates get them from their
+// Google Maps URL, if it has any.
+func ReadSavedPlaces(r io.Reader) ([]TakeoutPlace, error) {
+	var input takeoutSavedPlaces
+	if err := json.NewDecoder(r).Decode(&input); err != nil {
+		return nil, fmt.Errorf("invalid Saved Places.json: %w", err)
+	}
+	if input.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("invalid Saved Places.json: expected a FeatureCollection, got %q", input.Type)
+	}
+
+	places := []TakeoutPlace{}
+	for _, feature := range input.Features {
+		props := feature.Properties
+		place := TakeoutPlace{
+			List:    StarredPlacesList,
+			Title:   firstNonEmpty(props.Location.Name, props.Location.BusinessName, props.Title),
+			Address: strings.TrimSpace(props.Location.Address),
+			Note:    strings.TrimSpace(props.Comment),
+			URL:     firstNonEmpty(props.GoogleMapsURL, props.LegacyURL),
+			SavedAt: parseTakeoutTime(firstNonEmpty(props.Date, props.Published)),
+		}
+
+		if coordinates := feature.Geometry.Coordinates; len(coordinates) >= 2 && (coordinates[0] != 0 || coordinates[1] != 0) {
+			place.Lat, place.Lon, place.HasLocation = coordinates[1], coordinates[0], validCoordinates(coordinates[1], coordinates[0])
+		}
+		if !place.HasLocation {
+			place.Lat, place.Lon, place.HasLocation = parseLegacyCoordinates(props.Location.GeoCoordinates.Latitude, props.Location.GeoCoordinates.Longitude)
+		}
+		if !place.HasLocation {
+			place.Lat, place.Lon, place.HasLocation = GoogleMapsCoordinates(place.URL)
+		}
+		if place.Title == "" && place.URL == "" && !place.HasLocation {
+			continue
+		}
+		places = append(places, place)
+	}
+	return places, nil
+}
+
+// ReadSavedList reads a saved list CSV from Takeout. These files have the
+// columns Title, Note, URL and, in newer exports, Tags and Comment, but no
+// coordinates apart from those some Google Maps URLs carry.
+func ReadSavedList(r io.Reader, listName string) ([]TakeoutPlace, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid saved list CSV: %w", err)
+	}
+	columns := headerColumns(header)
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("invalid saved list CSV: missing Title column")
+	}
+
+	places := []TakeoutPlace{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid saved list CSV: %w", err)
+		}
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		place := TakeoutPlace{
+			List:  listName,
+			Title: value("title"),
+			Note:  joinNonEmpty("\n", value("note"), value("comment")),
+			URL:   value("url"),
+		}
+		// The row after the header holds the list's description, which is
+		// usually empty.
+		if place.Title == "" && place.URL == "" {
+			continue
+		}
+		place.Lat, place.Lon, place.HasLocation = GoogleMapsCoordinates(place.URL)
+		places = append(places, place)
+	}
+	return places, nil
+}
+
+// GoogleMapsCoordinates extracts the coordinates from a Google Maps URL.
+func GoogleMapsCoordinates(url string) (float64, float64, bool) {
+	for _, pattern := range googleCoordinatePatterns {
+		match := pattern.FindStringSubmatch(url)
+		if match == nil {
+			continue
+		}
+		lat, errLat := strconv.ParseFloat(match[1], 64)
+		lon, errLon := strconv.ParseFloat(match[2], 64)
+		if errLat == nil && errLon == nil && validCoordinates(lat, lon) && (lat != 0 || lon != 0) {
+			return lat, lon, true
+		}
+	}
+	return 0, 0, false
+}
+
+// parseLegacyCoordinates reads the "Geo Coordinates" of older exports,
+// which hold numbers as strings.
+func parseLegacyCoordinates(latitude json.RawMessage, longitude json.RawMessage) (float64, float64, bool) {
+	lat, errLat := strconv.ParseFloat(strings.Trim(string(latitude), `"`), 64)
+	lon, errLon := strconv.ParseFloat(strings.Trim(string(longitude), `"`), 64)
+	if errLat != nil || errLon != nil || !validCoordinates(lat, lon) || (lat == 0 && lon == 0) {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+func parseTakeoutTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// takeoutListName names a list after its CSV file, "Saved/Want to go.csv"
+// becoming "Want to go".
+func takeoutListName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	return strings.TrimSpace(strings.TrimSuffix(name, path.Ext(name)))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var kept []string
+	for _, value := range values {
+		if value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return strings.Join(kept, sep)
+}
@@ -0,0 +1,66 @@
+package formats
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// takeoutArchive zips files, given by name, into a Takeout archive.
+func takeoutArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("adding %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadTakeoutArchive(t *testing.T) {
+	archive := takeoutArchive(t, map[string]string{
+		"Takeout/Saved/Want to go.csv": "Title,Note,URL\nCafe,,\"https://www.google.com/maps/place/Cafe/@52.5,13.4,17z\"\n",
+		"Takeout/Other/ignored.csv":    "Title\nIgnored\n",
+	})
+
+	places, err := ReadTakeout("takeout.zip", archive)
+	if err != nil {
+		t.Fatalf("ReadTakeout: %v", err)
+	}
+	if len(places) != 1 || places[0].List != "Want to go" || places[0].Title != "Cafe" || places[0].Lat != 52.5 {
+		t.Errorf("ReadTakeout = %+v, want the cafe from the Want to go list", places)
+	}
+}
+
+func TestReadTakeoutArchiveLimits(t *testing.T) {
+	manyFiles := make(map[string]string)
+	for i := 0; i <= maxTakeoutFiles; i++ {
+		manyFiles[fmt.Sprintf("Takeout/Saved/List %d.csv", i)] = "Title\nCafe\n"
+	}
+	if _, err := ReadTakeout("takeout.zip", takeoutArchive(t, manyFiles)); err == nil || !strings.Contains(err.Error(), "saved place files") {
+		t.Errorf("ReadTakeout of %d files: got %v, want an error about the number of files", len(manyFiles), err)
+	}
+
+	// Each file is below the per-file cap, but together they are above the
+	// cap for the archive.
+	row := "Cafe,,\"https://www.google.com/maps/place/Cafe/@52.5,13.4,17z\"\n"
+	bigFile := "Title,Note,URL\n" + strings.Repeat(row, (maxTakeoutFileSize-1024)/len(row))
+	bigFiles := make(map[string]string)
+	for i := 0; i*maxTakeoutFileSize <= maxTakeoutArchiveSize; i++ {
+		bigFiles[fmt.Sprintf("Takeout/Saved/List %d.csv", i)] = bigFile
+	}
+	if _, err := ReadTakeout("takeout.zip", takeoutArchive(t, bigFiles)); err == nil || !strings.Contains(err.Error(), "in the archive are larger") {
+		t.Errorf("ReadTakeout of %d large files: got %v, want an error about the archive size", len(bigFiles), err)
+	}
+}
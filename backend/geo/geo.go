@@ -0,0 +1,108 @@
+// Package geo holds the geometry of place queries: geohashes, the cells
+// covering an area, and distances on the earth's surface.
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+// EarthRadius is the radius of the earth in metres.
+const EarthRadius = 6371000
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode returns the geohash of a point with precision characters.
+func Encode(lat float64, long float64, precision int) string {
+	latLow, latHigh := -90.0, 90.0
+	longLow, longHigh := -180.0, 180.0
+	var b strings.Builder
+	b.Grow(precision)
+	bit, char, even := 0, 0, true
+	for b.Len() < precision {
+		// Bits alternate between longitude and latitude, longitude first.
+		if even {
+			mid := (longLow + longHigh) / 2
+			if long >= mid {
+				char = char<<1 | 1
+				longLow = mid
+			} else {
+				char <<= 1
+				longHigh = mid
+			}
+		} else {
+			mid := (latLow + latHigh) / 2
+			if lat >= mid {
+				char = char<<1 | 1
+				latLow = mid
+			} else {
+				char <<= 1
+				latHigh = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			b.WriteByte(base32[char])
+			bit, char = 0, 0
+		}
+	}
+	return b.String()
+}
+
+// CellSize returns the height and width in degrees of the geohash cells
+// with precision characters.
+func CellSize(precision int) (float64, float64) {
+	bits := 5 * precision
+	longBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(longBits))
+}
+
+// Cover returns the geohash cells with precision characters that together
+// cover the area from south to north and west to east, or nil if more than
+// maxCells are needed.
+func Cover(south float64, west float64, north float64, east float64, precision int, maxCells int) []string {
+	height, width := CellSize(precision)
+	// Snap to the cell edges so every cell is visited once.
+	firstLat := math.Floor((south+90)/height)*height - 90
+	firstLong := math.Floor((west+180)/width)*width - 180
+	rows := int(math.Floor((north-firstLat)/height)) + 1
+	cols := int(math.Floor((east-firstLong)/width)) + 1
+	if rows*cols > maxCells {
+		return nil
+	}
+
+	cells := make([]string, 0, rows*cols)
+	for row := 0; row < rows; row++ {
+		lat := math.Min(firstLat+(float64(row)+0.5)*height, 90)
+		for col := 0; col < cols; col++ {
+			long := math.Min(firstLong+(float64(col)+0.5)*width, 180)
+			cells = append(cells, Encode(lat, long, precision))
+		}
+	}
+	return cells
+}
+
+// Distance returns the great-circle distance in metres between two points.
+func Distance(lat1 float64, long1 float64, lat2 float64, long2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (long2 - long1) * math.Pi / 180
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BoxAround returns the south, west, north and east edges of the smallest
+// box holding every point within radius metres of a point. The box is
+// clipped at the poles and at the antimeridian.
+func BoxAround(lat float64, long float64, radius float64) (float64, float64, float64, float64) {
+	dLat := radius / EarthRadius * 180 / math.Pi
+	south, north := math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+	if south == -90 || north == 90 {
+		return south, -180, north, 180
+	}
+	// The box is widest at the edge nearest the pole.
+	widest := math.Max(math.Abs(south), math.Abs(north)) * math.Pi / 180
+	dLong := math.Min(dLat/math.Cos(widest), 180)
+	return south, math.Max(long-dLong, -180), north, math.Min(long+dLong, 180)
+}
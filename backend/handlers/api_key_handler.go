@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func CreateAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	key, secret, err := services.CreateAPIKey(c.Request.Context(), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created, store the secret now as it cannot be shown again",
+		"key":     key,
+		"secret":  secret,
+	})
+}
+
+func GetAPIKeys(c *gin.Context) {
+	keys, err := services.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keys": keys,
+	})
+}
+
+func RotateAPIKey(c *gin.Context) {
+	key, secret, err := services.RotateAPIKey(c.Request.Context(), c.Param("keyID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key rotated, the previous secret no longer works",
+		"key":     key,
+		"secret":  secret,
+	})
+}
+
+func DeleteAPIKey(c *gin.Context) {
+	err := services.DeleteAPIKey(c.Request.Context(), c.Param("keyID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler writes the last error a handler attached with c.Error as a
+// JSON body of the form {"error": "...", "code": "..."}. Domain errors from
+// the services are mapped to their status codes; anything unrecognised is
+// logged and reported as a 500 without leaking its message.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		status, code, message := describeError(c.Errors.Last())
+		c.JSON(status, gin.H{"error": message, "code": code})
+	}
+}
+
+func describeError(ginErr *gin.Error) (int, string, string) {
+	if ginErr.IsType(gin.ErrorTypeBind) {
+		return http.StatusBadRequest, "invalid_request", ginErr.Error()
+	}
+
+	var domainErr *services.Error
+	if errors.As(ginErr.Err, &domainErr) {
+		return statusForKind(domainErr.Kind), domainErr.Code, domainErr.Message
+	}
+
+	log.Printf("Internal error: %v", ginErr.Err)
+	return http.StatusInternalServerError, "internal_error", "internal server error"
+}
+
+func statusForKind(kind services.ErrorKind) int {
+	switch kind {
+	case services.KindNotFound:
+		return http.StatusNotFound
+	case services.KindAlreadyExists, services.KindConflict:
+		return http.StatusConflict
+	case services.KindValidation:
+		return http.StatusUnprocessableEntity
+	case services.KindForbidden:
+		return http.StatusForbidden
+	case services.KindUnavailable:
+		return http.StatusBadGateway
+	case services.KindRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
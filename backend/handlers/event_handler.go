@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventHeartbeat keeps proxies from closing idle event streams.
+	eventHeartbeat = 25 * time.Second
+	// eventRetryMillis is how long browsers wait before reconnecting.
+	eventRetryMillis = 3000
+)
+
+// StreamEvents streams changes to the user's lists, visits and watches as
+// server-sent events. Clients resume after a reconnect with the
+// Last-Event-ID header, which EventSource sends by itself, or with
+// ?lastEventId=.
+func StreamEvents(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+
+	backlog, events, cancel, err := services.SubscribeEvents(c.Request.Context(), c.Param("id"), lastEventID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventRetryMillis)
+	for _, event := range backlog {
+		if err := writeEvent(c.Writer, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(c.Writer, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, event services.Event) error {
+	data := []byte("{}")
+	if event.Data != nil {
+		var err error
+		if data, err = json.Marshal(event.Data); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
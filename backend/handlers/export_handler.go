@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/formats"
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportList downloads a list as ?format=geojson|kml|gpx|csv.
+func ExportList(c *gin.Context) {
+	format, err := formats.ParseFormat(c.DefaultQuery("format", string(formats.GeoJSON)))
+	if err != nil {
+		c.Error(services.ValidationError("%s", err.Error()))
+		return
+	}
+
+	name, features, err := services.ExportList(c.Request.Context(), c.Param("id"), c.Param("listID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeExport(c, format, name, features)
+}
+
+// ExportAccount downloads all lists, visits and watches of a user in one
+// file.
+func ExportAccount(c *gin.Context) {
+	format, err := formats.ParseFormat(c.DefaultQuery("format", string(formats.GeoJSON)))
+	if err != nil {
+		c.Error(services.ValidationError("%s", err.Error()))
+		return
+	}
+
+	features, err := services.ExportAccount(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeExport(c, format, "EatFinder export "+time.Now().Format(time.DateOnly), features)
+}
+
+func writeExport(c *gin.Context, format formats.Format, title string, features []formats.Feature) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(title)+format.Extension()))
+	c.Status(http.StatusOK)
+	if err := formats.Write(c.Writer, format, title, features); err != nil {
+		// The status line is already out, so the client sees a cut-off file.
+		log.Printf("Error writing %s export: %v", format, err)
+	}
+}
+
+// exportFilename keeps letters, digits, dashes and underscores of title.
+func exportFilename(title string) string {
+	name := make([]rune, 0, len(title))
+	for _, r := range title {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			name = append(name, r)
+		case r == ' ':
+			name = append(name, '_')
+		}
+	}
+	if len(name) == 0 {
+		return "export"
+	}
+	return string(name)
+}
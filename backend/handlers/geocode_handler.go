@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReverseGeocode returns the location at ?lat= and ?long=, taking the place
+// of calls to Nominatim's /reverse. Like there, ?lon= may be given for
+// ?long=.
+func ReverseGeocode(c *gin.Context) {
+	point, err := parsePoint(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if point == nil {
+		c.Error(services.ValidationError("lat and long are required"))
+		return
+	}
+
+	location, err := services.ReverseGeocode(c.Request.Context(), point.Lat, point.Lon)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
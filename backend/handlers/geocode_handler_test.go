@@ -0,0 +1,37 @@
+package handlers_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"net/http"
+	"testing"
+)
+
+type fakeGeocoder struct{}
+
+func (fakeGeocoder) ReverseGeocode(ctx context.Context, lat float64, long float64) (data.Location, error) {
+	return data.Location{City: "Berlin", Country: "Germany"}, nil
+}
+
+func TestReverseGeocodeRoute(t *testing.T) {
+	router, apiKey := testServer(t)
+	services.SetReverseGeocoder(fakeGeocoder{})
+	t.Cleanup(func() { services.SetReverseGeocoder(nil) })
+
+	// The second request is answered from the cache, so the geocoder's rate
+	// limit does not slow the test down.
+	for _, query := range []string{"lat=52.5&long=13.4", "lat=52.5&lon=13.4"} {
+		var location data.Location
+		if status := serve(t, router, apiKey, http.MethodGet, "/api/geocode/reverse?"+query, "", &location); status != http.StatusOK || location.City != "Berlin" {
+			t.Errorf("GET ?%s: status %d, body %+v; want 200 with Berlin", query, status, location)
+		}
+	}
+
+	for _, query := range []string{"", "lat=52.5", "lat=NaN&long=13.4", "lat=52.5&long=Inf", "lat=52.5&long=-Inf", "lat=91&long=13.4"} {
+		var invalid errorBody
+		if status := serve(t, router, apiKey, http.MethodGet, "/api/geocode/reverse?"+query, "", &invalid); status != http.StatusUnprocessableEntity || invalid.Code != "validation_failed" {
+			t.Errorf("GET ?%s: status %d, body %+v; want 422 validation_failed", query, status, invalid)
+		}
+	}
+}
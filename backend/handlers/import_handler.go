@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"backend/data"
+	"backend/formats"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxImportFileSize  = 5 << 20
+	maxTakeoutFileSize = 20 << 20
+)
+
+type importEntryRequest struct {
+	Status string           `json:"status" binding:"required"`
+	Match  *data.PlaceMatch `json:"match"`
+}
+
+// ImportPlaces reads a multipart upload with the fields file, list (the name
+// of the list to create or extend) and optionally format, which otherwise
+// is taken from the file extension.
+func ImportPlaces(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(services.ValidationError("file must be at most %d MB", maxImportFileSize>>20))
+			return
+		}
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.Error(services.ValidationError("file must be at most %d MB", maxImportFileSize>>20))
+		return
+	}
+
+	format, err := importFormat(c.PostForm("format"), fileHeader.Filename)
+	if err != nil {
+		c.Error(services.ValidationError("%s", err.Error()))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	features, rowErrors, err := formats.Read(file, format)
+	if err != nil {
+		c.Error(services.ValidationError("%s", err.Error()))
+		return
+	}
+
+	result, err := services.ImportPlaces(c.Request.Context(), c.Param("id"), c.PostForm("list"), features, rowErrors, utils.Actor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func importFormat(name string, filename string) (formats.Format, error) {
+	if name != "" {
+		return formats.ParseFormat(name)
+	}
+	return formats.FormatFromFilename(filename)
+}
+
+// StartTakeoutImport reads one or more files uploaded as file: a Google
+// Takeout "Saved Places.json", saved list CSVs from the Takeout "Saved"
+// folder, or the Takeout .zip archive. The places are matched to OSM places
+// and returned as an import to review.
+func StartTakeoutImport(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTakeoutFileSize+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(services.ValidationError("files must be at most %d MB", maxTakeoutFileSize>>20))
+			return
+		}
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	fileHeaders := form.File["file"]
+	if len(fileHeaders) == 0 {
+		c.Error(services.ValidationError("upload at least one file as file"))
+		return
+	}
+
+	var places []formats.TakeoutPlace
+	for _, fileHeader := range fileHeaders {
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		filePlaces, err := formats.ReadTakeout(fileHeader.Filename, content)
+		if err != nil {
+			c.Error(services.ValidationError("%s", err.Error()))
+			return
+		}
+		places = append(places, filePlaces...)
+	}
+
+	imp, err := services.StartTakeoutImport(c.Request.Context(), c.Param("id"), places)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, imp)
+}
+
+func GetImports(c *gin.Context) {
+	imports, err := services.ListImports(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, imports)
+}
+
+func GetImport(c *gin.Context) {
+	imp, err := services.GetImport(c.Request.Context(), c.Param("id"), c.Param("importID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, imp)
+}
+
+// ReviewImportEntry confirms an entry, optionally with a place of its
+// choosing, or drops it.
+func ReviewImportEntry(c *gin.Context) {
+	entryID, err := strconv.Atoi(c.Param("entryID"))
+	if err != nil {
+		c.Error(services.ErrImportEntryNotFound)
+		return
+	}
+	var req importEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	review := services.ImportEntryReview{Status: req.Status, Match: req.Match}
+	entry, err := services.ReviewImportEntry(c.Request.Context(), c.Param("id"), c.Param("importID"), entryID, review)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func CommitImport(c *gin.Context) {
+	result, err := services.CommitImport(c.Request.Context(), c.Param("id"), c.Param("importID"), utils.Actor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func DeleteImport(c *gin.Context) {
+	if err := services.DeleteImport(c.Request.Context(), c.Param("id"), c.Param("importID")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
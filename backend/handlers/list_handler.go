@@ -5,6 +5,7 @@ import (
 
 	"backend/data"
 	"backend/services"
+	"backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,31 +14,109 @@ func CreateList(c *gin.Context) {
 	userId := c.Param("id")
 	var newList data.List
 	if err := c.ShouldBindJSON(&newList); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	listID, err := services.CreateList(c.Request.Context(), userId, newList)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
+		return
+	}
+	// Return the list as stored, without fields such as share_token that
+	// cannot be set on creation.
+	list, err := services.GetList(c.Request.Context(), userId, listID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "List created successfully",
-		"list":    newList,
+		"list":    list,
 		"listID":  listID,
 	})
 }
 
+type listPatchRequest struct {
+	ListName    *string `json:"list_name"`
+	Description *string `json:"description"`
+	Colour      *string `json:"colour"`
+	Icon        *string `json:"icon"`
+}
+
+// GetLists returns all of the user's lists, or the list given by ?name=.
+func GetLists(c *gin.Context) {
+	if c.Query("name") != "" {
+		GetList(c)
+		return
+	}
+
+	lists, err := services.GetLists(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lists": lists,
+	})
+}
+
+func GetListByID(c *gin.Context) {
+	list, err := services.GetList(c.Request.Context(), c.Param("id"), c.Param("listID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"list": list,
+	})
+}
+
+func UpdateList(c *gin.Context) {
+	var req listPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	patch := services.ListPatch{ListName: req.ListName, Description: req.Description, Colour: req.Colour, Icon: req.Icon}
+	list, err := services.UpdateListDetails(c.Request.Context(), c.Param("id"), c.Param("listID"), patch)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "List updated successfully",
+		"list":    list,
+	})
+}
+
+// DeleteListByID deletes the list given by :listID. Requests that carry
+// ?osmID= come from clients that remove a place from a list by name, so they
+// are passed on to RemoveFromList.
+func DeleteListByID(c *gin.Context) {
+	if c.Query("osmID") != "" {
+		RemoveFromList(c)
+		return
+	}
+
+	err := services.DeleteList(c.Request.Context(), c.Param("id"), c.Param("listID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 func GetList(c *gin.Context) {
-	docId, err := services.GetListByName(c.Request.Context(), c.Param("id"), c.Query("name"))
+	docId, err := services.GetList(c.Request.Context(), c.Param("id"), c.Query("name"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting list: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -49,43 +128,133 @@ func GetList(c *gin.Context) {
 func DeleteList(c *gin.Context) {
 	err := services.DeleteListByName(c.Request.Context(), c.Param("id"), c.Query("name"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting list: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+type listPlaceRequest struct {
+	Note     *string `json:"note"`
+	Position *int    `json:"position"`
+}
+
+type reorderListRequest struct {
+	OsmIDs []string `json:"osmIDs" binding:"required"`
+}
+
+type transferListPlaceRequest struct {
+	TargetList string `json:"targetList" binding:"required"`
+}
+
+// AddToList adds a place to a list. Adding a place that is already in the
+// list returns the existing entry with 200 instead of 201.
 func AddToList(c *gin.Context) {
 
 	var newPlace data.Place
 	if err := c.ShouldBindJSON(&newPlace); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	place, err := services.AppendPlace(c.Request.Context(), c.Param("id"), c.Param("listName"), newPlace)
+	place, added, err := services.AppendPlace(c.Request.Context(), c.Param("id"), c.Param("listID"), newPlace, utils.Actor(c))
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
 		return
 	}
 
+	if !added {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Place is already in the list",
+			"place":   place,
+		})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Place added successfully",
 		"place":   place,
 	})
 }
 
+// RemoveFromList removes the place given by :osmID, or by ?osmID= on the
+// older name-based route.
 func RemoveFromList(c *gin.Context) {
-	err := services.RemovePlace(c.Request.Context(), c.Param("id"), c.Param("listName"), c.Query("osmID"))
+	err := services.RemovePlace(c.Request.Context(), c.Param("id"), c.Param("listID"), placeKey(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting place: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+func UpdateListPlace(c *gin.Context) {
+	var req listPlaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	patch := services.ListPlacePatch{Note: req.Note, Position: req.Position}
+	place, err := services.UpdateListPlace(c.Request.Context(), c.Param("id"), c.Param("listID"), placeKey(c), patch)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Place updated successfully",
+		"place":   place,
+	})
+}
+
+func ReorderList(c *gin.Context) {
+	var req reorderListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	list, err := services.ReorderList(c.Request.Context(), c.Param("id"), c.Param("listID"), req.OsmIDs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "List reordered successfully",
+		"list":    list,
+	})
+}
+
+func MoveListPlace(c *gin.Context) {
+	transferListPlace(c, false)
+}
+
+func CopyListPlace(c *gin.Context) {
+	transferListPlace(c, true)
+}
+
+func transferListPlace(c *gin.Context, keepSource bool) {
+	var req transferListPlaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	place, err := services.TransferListPlace(c.Request.Context(), c.Param("id"), c.Param("listID"), placeKey(c), req.TargetList, keepSource, utils.Actor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	message := "Place moved successfully"
+	if keepSource {
+		message = "Place copied successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"place":   place,
+	})
+}
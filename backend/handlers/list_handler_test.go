@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"backend/routes"
+	"backend/services"
+	"backend/storage"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testServer serves the API routes on an empty in-memory repository.
+// Requests authenticate with the returned API key, which can write.
+func testServer(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	services.SetRepository(storage.NewMemoryRepository())
+	_, apiKey, err := services.CreateAPIKey(context.Background(), "tests", []string{services.ScopeWrite}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	// The tests sign in with the API key, so the JWKS holds no keys.
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	t.Cleanup(jwks.Close)
+	t.Setenv("CLERK_JWKS_URL", jwks.URL)
+
+	router := gin.New()
+	if err := routes.SetupRoutes(router); err != nil {
+		t.Fatalf("SetupRoutes: %v", err)
+	}
+	return router, apiKey
+}
+
+// serve sends a request with an optional JSON body and decodes the JSON
+// response, if any, into out.
+func serve(t *testing.T, router *gin.Engine, apiKey string, method string, path string, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if out != nil && rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func TestListRoutes(t *testing.T) {
+	router, apiKey := testServer(t)
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users", `{"id":"u1"}`, nil); status != http.StatusCreated {
+		t.Fatalf("POST /api/users: status %d, want 201", status)
+	}
+
+	var created struct {
+		ListID string `json:"listID"`
+	}
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users/u1/lists", `{"list_name":"Lunch"}`, &created); status != http.StatusCreated {
+		t.Fatalf("POST lists: status %d, want 201", status)
+	}
+	var taken errorBody
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users/u1/lists", `{"list_name":"Lunch"}`, &taken); status != http.StatusConflict || taken.Code != "list_name_taken" {
+		t.Errorf("POST lists with a taken name: status %d, body %+v; want 409 list_name_taken", status, taken)
+	}
+
+	listPath := "/api/users/u1/lists/" + created.ListID
+	place := `{"osm_id":"1","osm_type":"node","lat":52.5,"long":13.4}`
+	if status := serve(t, router, apiKey, http.MethodPost, listPath+"/places", place, nil); status != http.StatusCreated {
+		t.Errorf("POST places: status %d, want 201", status)
+	}
+	if status := serve(t, router, apiKey, http.MethodPost, listPath+"/places", place, nil); status != http.StatusOK {
+		t.Errorf("POST places again: status %d, want 200", status)
+	}
+	var invalid errorBody
+	if status := serve(t, router, apiKey, http.MethodPost, listPath+"/places", `{"lat":1}`, &invalid); status != http.StatusUnprocessableEntity || invalid.Code != "validation_failed" {
+		t.Errorf("POST places without an OSM ID: status %d, body %+v; want 422 validation_failed", status, invalid)
+	}
+
+	var got struct {
+		List struct {
+			ListName string `json:"list_name"`
+			Places   []struct {
+				OsmID string `json:"osm_id"`
+			} `json:"places"`
+		} `json:"list"`
+	}
+	if status := serve(t, router, apiKey, http.MethodGet, listPath, "", &got); status != http.StatusOK {
+		t.Fatalf("GET list: status %d, want 200", status)
+	}
+	if got.List.ListName != "Lunch" || len(got.List.Places) != 1 || got.List.Places[0].OsmID != "1" {
+		t.Errorf("GET list = %+v, want Lunch holding place 1", got.List)
+	}
+
+	if status := serve(t, router, apiKey, http.MethodDelete, listPath+"/places/1", "", nil); status != http.StatusNoContent {
+		t.Errorf("DELETE place: status %d, want 204", status)
+	}
+	if status := serve(t, router, apiKey, http.MethodDelete, listPath, "", nil); status != http.StatusNoContent {
+		t.Errorf("DELETE list: status %d, want 204", status)
+	}
+	var missing errorBody
+	if status := serve(t, router, apiKey, http.MethodGet, listPath, "", &missing); status != http.StatusNotFound || missing.Code != "list_not_found" {
+		t.Errorf("GET deleted list: status %d, body %+v; want 404 list_not_found", status, missing)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type inviteMemberRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	Role   string `json:"role" binding:"required"`
+}
+
+type memberRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// InviteMember invites another user to a list as an editor or viewer.
+func InviteMember(c *gin.Context) {
+	var req inviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	member, err := services.InviteMember(c.Request.Context(), c.Param("id"), c.Param("listID"), req.UserID, req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func GetListMembers(c *gin.Context) {
+	members, err := services.GetListMembers(c.Request.Context(), c.Param("id"), c.Param("listID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func UpdateMemberRole(c *gin.Context) {
+	var req memberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	member, err := services.UpdateMemberRole(c.Request.Context(), c.Param("id"), c.Param("listID"), c.Param("memberID"), req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember removes a member from a list, or lets members leave a list
+// by removing themselves.
+func RemoveMember(c *gin.Context) {
+	err := services.RemoveMember(c.Request.Context(), c.Param("id"), c.Param("listID"), c.Param("memberID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetInvitations returns the list invitations the user has not answered.
+func GetInvitations(c *gin.Context) {
+	invitations, err := services.GetInvitations(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func AcceptInvitation(c *gin.Context) {
+	member, err := services.RespondToInvitation(c.Request.Context(), c.Param("id"), c.Param("listID"), true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func DeclineInvitation(c *gin.Context) {
+	_, err := services.RespondToInvitation(c.Request.Context(), c.Param("id"), c.Param("listID"), false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package handlers_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// sendWebhook signs body as Clerk does and posts it to the webhook route.
+func sendWebhook(t *testing.T, router http.Handler, body string) int {
+	t.Helper()
+	secret := "whsec_" + base64.StdEncoding.EncodeToString([]byte("test webhook secret"))
+	t.Setenv("CLERK_WEBHOOK_SECRET", secret)
+	wh, err := svix.NewWebhook(secret)
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	now := time.Now()
+	signature, err := wh.Sign("msg_1", now, []byte(body))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/clerk", strings.NewReader(body))
+	req.Header.Set("svix-id", "msg_1")
+	req.Header.Set("svix-timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("svix-signature", signature)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestDeletedUserLeavesSharedLists(t *testing.T) {
+	router, apiKey := testServer(t)
+	ctx := context.Background()
+	for _, userID := range []string{"owner", "member"} {
+		if _, err := services.CreateUser(ctx, &data.User{ID: userID}); err != nil {
+			t.Fatalf("CreateUser(%s): %v", userID, err)
+		}
+	}
+	listID, err := services.CreateList(ctx, "owner", data.List{ListName: "Team lunch"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	if _, err := services.InviteMember(ctx, "owner", listID, "member", services.RoleEditor); err != nil {
+		t.Fatalf("InviteMember: %v", err)
+	}
+
+	if status := sendWebhook(t, router, `{"type":"user.deleted","data":{"id":"member"}}`); status != http.StatusOK {
+		t.Fatalf("user.deleted webhook: status %d, want 200", status)
+	}
+	var members []data.ListMember
+	if status := serve(t, router, apiKey, http.MethodGet, "/api/users/owner/lists/"+listID+"/members", "", &members); status != http.StatusOK || len(members) != 0 {
+		t.Errorf("GET members after the member was deleted: status %d, body %+v; want 200 with none", status, members)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCatalogPlace returns the catalog entry of the OSM place given by
+// :osmType and :osmID.
+func GetCatalogPlace(c *gin.Context) {
+	place, err := services.GetCatalogPlace(c.Request.Context(), c.Param("osmType"), c.Param("osmID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, place)
+}
+
+// SearchPlaces returns the eateries inside ?bbox=south,west,north,east,
+// optionally narrowed by ?amenity=, ?cuisine= (both comma separated) and
+// ?name=.
+func SearchPlaces(c *gin.Context) {
+	box, err := parseBoundingBox(c.Query("bbox"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	filter := services.MapFilter{
+		Amenities: splitQuery(c.Query("amenity")),
+		Cuisines:  splitQuery(c.Query("cuisine")),
+		Name:      c.Query("name"),
+	}
+
+	places, err := services.SearchPlaces(c.Request.Context(), utils.Actor(c), box, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, places)
+}
+
+func parseBoundingBox(value string) (services.BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return services.BoundingBox{}, services.ValidationError("bbox must be south,west,north,east")
+	}
+	var coords [4]float64
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return services.BoundingBox{}, services.ValidationError("bbox must be south,west,north,east")
+		}
+		coords[i] = coord
+	}
+	return services.BoundingBox{South: coords[0], West: coords[1], North: coords[2], East: coords[3]}, nil
+}
+
+// parsePoint returns the point at ?lat= and ?long=, or nil if neither is
+// given. ?lon=, the name Nominatim uses, is accepted in place of ?long=.
+func parsePoint(c *gin.Context) (*services.GeoPoint, error) {
+	longValue := c.Query("long")
+	if longValue == "" {
+		longValue = c.Query("lon")
+	}
+	if c.Query("lat") == "" && longValue == "" {
+		return nil, nil
+	}
+	lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	long, longErr := strconv.ParseFloat(longValue, 64)
+	if latErr != nil || longErr != nil || math.IsNaN(lat) || math.IsInf(lat, 0) || math.IsNaN(long) || math.IsInf(long, 0) {
+		return nil, services.ValidationError("lat and long must be finite numbers")
+	}
+	return &services.GeoPoint{Lat: lat, Lon: long}, nil
+}
+
+func splitQuery(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// placeKey returns the place a request names by :osmID, or by ?osmID= on the
+// older routes, qualified by ?osmType= if the client gives it.
+func placeKey(c *gin.Context) string {
+	osmID := c.Param("osmID")
+	if osmID == "" {
+		osmID = c.Query("osmID")
+	}
+	return services.PlaceKey(c.Query("osmType"), osmID)
+}
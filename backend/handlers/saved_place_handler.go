@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FindSavedPlaces returns the user's saved places inside ?bbox=, or within
+// ?radius= metres of ?lat= and ?long=, nearest first. Given both, the bbox
+// wins: lat and long only set the point to sort by, and radius is ignored.
+// ?list=, ?tag=, ?kind=, ?minRating= and ?limit= narrow the result.
+func FindSavedPlaces(c *gin.Context) {
+	filter, err := savedPlaceFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	origin, err := parsePoint(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var places []services.SavedPlace
+	if c.Query("bbox") != "" {
+		box, err := parseBoundingBox(c.Query("bbox"))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		places, err = services.FindSavedPlacesIn(c.Request.Context(), c.Param("id"), box, origin, filter)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	} else {
+		if origin == nil {
+			c.Error(services.ValidationError("give either bbox, or lat, long and radius"))
+			return
+		}
+		radius, err := strconv.ParseFloat(c.Query("radius"), 64)
+		if err != nil {
+			c.Error(services.ValidationError("radius must be a number of metres"))
+			return
+		}
+		places, err = services.FindSavedPlacesNear(c.Request.Context(), c.Param("id"), *origin, radius, filter)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"places": places,
+	})
+}
+
+func savedPlaceFilter(c *gin.Context) (services.SavedPlaceFilter, error) {
+	filter := services.SavedPlaceFilter{
+		Kinds:   splitQuery(c.Query("kind")),
+		ListIDs: splitQuery(c.Query("list")),
+		Tags:    splitQuery(c.Query("tag")),
+	}
+	if value := c.Query("minRating"); value != "" {
+		rating, err := strconv.ParseInt(value, 10, 8)
+		if err != nil {
+			return filter, services.ValidationError("minRating must be a whole number")
+		}
+		minRating := int8(rating)
+		filter.MinRating = &minRating
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return filter, services.ValidationError("limit must be a positive whole number")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
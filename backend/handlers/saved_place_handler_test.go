@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSavedPlaceRoutes(t *testing.T) {
+	router, apiKey := testServer(t)
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users", `{"id":"u1"}`, nil); status != http.StatusCreated {
+		t.Fatalf("POST /api/users: status %d, want 201", status)
+	}
+	list := `{"list_name":"Lunch","places":[{"osm_id":"1","osm_type":"node","lat":52.5,"long":13.4}]}`
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users/u1/lists", list, nil); status != http.StatusCreated {
+		t.Fatalf("POST lists: status %d, want 201", status)
+	}
+
+	for _, query := range []string{
+		"lat=52.5&long=13.4&radius=1000",
+		"lat=52.5&lon=13.4&radius=1000",
+		"bbox=52.4,13.3,52.6,13.5&lat=52.5&long=13.4",
+	} {
+		var found struct {
+			Places []struct {
+				OsmID string `json:"osm_id"`
+			} `json:"places"`
+		}
+		if status := serve(t, router, apiKey, http.MethodGet, "/api/users/u1/places?"+query, "", &found); status != http.StatusOK || len(found.Places) != 1 || found.Places[0].OsmID != "1" {
+			t.Errorf("GET ?%s: status %d, body %+v; want 200 with place 1", query, status, found)
+		}
+	}
+
+	for _, query := range []string{
+		"",
+		"lat=52.5&radius=1000",
+		"lat=NaN&long=13.4&radius=1000",
+		"lat=52.5&long=Inf&radius=1000",
+		"lat=52.5&long=13.4&radius=NaN",
+		"lat=52.5&long=13.4&radius=Inf",
+		"bbox=52.4,13.3,52.6,NaN",
+	} {
+		var invalid errorBody
+		if status := serve(t, router, apiKey, http.MethodGet, "/api/users/u1/places?"+query, "", &invalid); status != http.StatusUnprocessableEntity || invalid.Code != "validation_failed" {
+			t.Errorf("GET ?%s: status %d, body %+v; want 422 validation_failed", query, status, invalid)
+		}
+	}
+}
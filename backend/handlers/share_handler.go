@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type shareListRequest struct {
+	Visibility string `json:"visibility" binding:"required"`
+}
+
+// ShareList sets a list's visibility to private or unlisted and returns
+// the list with its share token.
+func ShareList(c *gin.Context) {
+	var req shareListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	list, err := services.ShareList(c.Request.Context(), c.Param("id"), c.Param("listID"), req.Visibility)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func RegenerateShareToken(c *gin.Context) {
+	list, err := services.RegenerateShareToken(c.Request.Context(), c.Param("id"), c.Param("listID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// RevokeShare makes a list private again.
+func RevokeShare(c *gin.Context) {
+	_, err := services.ShareList(c.Request.Context(), c.Param("id"), c.Param("listID"), services.VisibilityPrivate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedList serves a shared list to anyone holding its share token.
+func GetSharedList(c *gin.Context) {
+	list, err := services.GetSharedList(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Revoking a token has to take effect right away.
+	c.Header("Cache-Control", "no-cache")
+	c.JSON(http.StatusOK, list)
+}
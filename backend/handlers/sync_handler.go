@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/data"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type syncRequest struct {
+	Token     string         `json:"token"`
+	Mutations []syncMutation `json:"mutations"`
+}
+
+type syncMutation struct {
+	ID          string      `json:"id"`
+	Op          string      `json:"op"`
+	ListID      string      `json:"list_id"`
+	OsmID       string      `json:"osm_id"`
+	OsmType     string      `json:"osm_type"`
+	ListName    *string     `json:"list_name"`
+	Description *string     `json:"description"`
+	Colour      *string     `json:"colour"`
+	Icon        *string     `json:"icon"`
+	Place       *data.Place `json:"place"`
+	Note        *string     `json:"note"`
+	Position    *int        `json:"position"`
+	Visit       *data.Visit `json:"visit"`
+	Rating      *int8       `json:"rating"`
+	Tags        []string    `json:"tags"`
+}
+
+// Sync applies the mutations a client queued while offline and returns
+// what changed since the client's token. An empty token returns everything.
+func Sync(c *gin.Context) {
+	var req syncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	mutations := make([]services.Mutation, len(req.Mutations))
+	for i, m := range req.Mutations {
+		mutations[i] = services.Mutation{
+			ID:          m.ID,
+			Op:          m.Op,
+			ListID:      m.ListID,
+			OsmID:       m.OsmID,
+			OsmType:     m.OsmType,
+			ListName:    m.ListName,
+			Description: m.Description,
+			Colour:      m.Colour,
+			Icon:        m.Icon,
+			Place:       m.Place,
+			Note:        m.Note,
+			Position:    m.Position,
+			Visit:       m.Visit,
+			Rating:      m.Rating,
+			Tags:        m.Tags,
+		}
+	}
+
+	result, err := services.Sync(c.Request.Context(), c.Param("id"), req.Token, mutations, utils.Actor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetChanges returns what changed since ?token= without applying any
+// mutations.
+func GetChanges(c *gin.Context) {
+	result, err := services.Sync(c.Request.Context(), c.Param("id"), c.Query("token"), nil, utils.Actor(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"backend/data"
 	"backend/services"
+	"backend/utils"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
@@ -65,7 +67,7 @@ func handleUserCreated(c *gin.Context, clerkUser data.ClerkUserData) error {
 	user := convertClerkUserToUser(clerkUser)
 	_, err := services.CreateUser(c.Request.Context(), &user)
 	if err != nil {
-		if err.Error() == "user already exists" {
+		if errors.Is(err, services.ErrUserAlreadyExists) {
 			return nil
 		}
 		return err
@@ -74,7 +76,11 @@ func handleUserCreated(c *gin.Context, clerkUser data.ClerkUserData) error {
 }
 
 func handleUserDeleted(c *gin.Context, userID string) error {
-	return services.DeleteUserByID(c.Request.Context(), userID)
+	err := services.DeleteUserByID(c.Request.Context(), userID)
+	if errors.Is(err, services.ErrUserNotFound) {
+		return nil
+	}
+	return err
 }
 
 func convertClerkUserToUser(clerkUser data.ClerkUserData) data.User {
@@ -89,16 +95,16 @@ func convertClerkUserToUser(clerkUser data.ClerkUserData) data.User {
 func CreateUser(c *gin.Context) {
 	var newUser data.User
 	if err := c.ShouldBindJSON(&newUser); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	if !utils.CanAccessUser(c, newUser.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot create a user other than yourself", "code": "forbidden"})
 		return
 	}
 	docId, err := services.CreateUser(c.Request.Context(), &newUser)
 	if err != nil {
-		if err.Error() == "user already exists" {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -112,7 +118,7 @@ func CreateUser(c *gin.Context) {
 func GetUser(c *gin.Context) {
 	docId, err := services.GetUserByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting user: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -124,7 +130,7 @@ func GetUser(c *gin.Context) {
 func DeleteUser(c *gin.Context) {
 	err := services.DeleteUserByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting user: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -135,17 +141,13 @@ func VisitPlace(c *gin.Context) {
 	userId := c.Param("id")
 	var newLocation data.UserPlace
 	if err := c.ShouldBindJSON(&newLocation); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	place, err := services.VisitPlace(c.Request.Context(), userId, newLocation)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
 		return
 	}
 
@@ -155,10 +157,17 @@ func VisitPlace(c *gin.Context) {
 	})
 }
 
+// GetVisitedPlace returns the visited place given by ?osmID=, or all visited
+// places if no osmID is given.
 func GetVisitedPlace(c *gin.Context) {
-	place, err := services.GetVisitedPlace(c.Request.Context(), c.Param("id"), c.Query("osmID"))
+	if c.Query("osmID") == "" {
+		ListUserPlaces(services.VisitedPlaces)(c)
+		return
+	}
+
+	place, err := services.GetVisitedPlace(c.Request.Context(), c.Param("id"), placeKey(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting list: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -171,17 +180,13 @@ func WatchPlace(c *gin.Context) {
 	userId := c.Param("id")
 	var newLocation data.UserPlace
 	if err := c.ShouldBindJSON(&newLocation); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	place, err := services.WatchPlace(c.Request.Context(), userId, newLocation)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
 		return
 	}
 
@@ -191,10 +196,17 @@ func WatchPlace(c *gin.Context) {
 	})
 }
 
+// GetWatchedPlace returns the watched place given by ?osmID=, or all watched
+// places if no osmID is given.
 func GetWatchedPlace(c *gin.Context) {
-	place, err := services.GetWatchedPlace(c.Request.Context(), c.Param("id"), c.Query("osmID"))
+	if c.Query("osmID") == "" {
+		ListUserPlaces(services.WatchedPlaces)(c)
+		return
+	}
+
+	place, err := services.GetWatchedPlace(c.Request.Context(), c.Param("id"), placeKey(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting list: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -203,41 +215,151 @@ func GetWatchedPlace(c *gin.Context) {
 	})
 }
 
-/*
-func CreateRating(c *gin.Context) {
-	userID := c.Param("id")
-	var newRating data.Rating
-	if err := c.ShouldBindJSON(&newRating); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+type userPlaceRequest struct {
+	Tags      []string   `json:"tags"`
+	VisitedAt *time.Time `json:"visitedAt"`
+}
+
+// ListUserPlaces returns all of the user's places of the given kind.
+func ListUserPlaces(kind services.PlaceKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		places, err := services.GetUserPlaces(c.Request.Context(), c.Param("id"), kind)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"places": places,
+		})
+	}
+}
+
+// GetUserPlace returns the place of the given kind named by :osmID.
+func GetUserPlace(kind services.PlaceKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		place, err := services.GetUserPlace(c.Request.Context(), c.Param("id"), kind, placeKey(c))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"place": place,
+		})
+	}
+}
+
+// ReplaceUserPlace overwrites the tags and visit date of a place.
+func ReplaceUserPlace(kind services.PlaceKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req userPlaceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err).SetType(gin.ErrorTypeBind)
+			return
+		}
+
+		place, err := services.ReplaceUserPlace(c.Request.Context(), c.Param("id"), kind, placeKey(c), req.Tags, req.VisitedAt)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Place updated successfully",
+			"place":   place,
+		})
+	}
+}
+
+// PatchUserPlace changes only the fields present in the request body.
+func PatchUserPlace(kind services.PlaceKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req userPlaceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err).SetType(gin.ErrorTypeBind)
+			return
+		}
+
+		patch := services.UserPlacePatch{Tags: req.Tags, VisitedAt: req.VisitedAt}
+		place, err := services.PatchUserPlace(c.Request.Context(), c.Param("id"), kind, placeKey(c), patch)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Place updated successfully",
+			"place":   place,
+		})
+	}
+}
+
+func DeleteUserPlace(kind services.PlaceKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := services.DeleteUserPlace(c.Request.Context(), c.Param("id"), kind, placeKey(c))
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type ratingRequest struct {
+	Rating *int8 `json:"rating" binding:"required"`
+}
+
+func SetRating(c *gin.Context) {
+	var req ratingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	osmID, err := services.CreateRating(c.Request.Context(), userID, newRating)
+	place, err := services.SetRating(c.Request.Context(), c.Param("id"), placeKey(c), *req.Rating)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Rating created successfully",
-		"rating":  newRating,
-		"osmID":   osmID,
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rating saved successfully",
+		"place":   place,
 	})
 }
 
 func GetRatings(c *gin.Context) {
-	docId, err := services.GetRating(c.Request.Context(), c.Param("id"), c.Query("osmID"))
+	places, err := services.GetRatedPlaces(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"places": places,
+	})
+}
+
+func GetRating(c *gin.Context) {
+	place, err := services.GetRating(c.Request.Context(), c.Param("id"), placeKey(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting list: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"rating": docId,
+		"place": place,
 	})
 }
-*/
+
+func DeleteRating(c *gin.Context) {
+	_, err := services.ClearRating(c.Request.Context(), c.Param("id"), placeKey(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUserRoutes(t *testing.T) {
+	router, apiKey := testServer(t)
+
+	var missing errorBody
+	if status := serve(t, router, apiKey, http.MethodGet, "/api/users/u1", "", &missing); status != http.StatusNotFound || missing.Code != "user_not_found" {
+		t.Errorf("GET unknown user: status %d, body %+v; want 404 user_not_found", status, missing)
+	}
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users", `{"id":"u1"}`, nil); status != http.StatusCreated {
+		t.Fatalf("POST /api/users: status %d, want 201", status)
+	}
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users", `{"id":"u1"}`, nil); status != http.StatusConflict {
+		t.Errorf("POST /api/users twice: status %d, want 409", status)
+	}
+
+	var got struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	if status := serve(t, router, apiKey, http.MethodGet, "/api/users/u1", "", &got); status != http.StatusOK || got.User.ID != "u1" {
+		t.Errorf("GET user: status %d, body %+v; want 200 with user u1", status, got)
+	}
+	if status := serve(t, router, apiKey, http.MethodDelete, "/api/users/u1", "", nil); status != http.StatusNoContent {
+		t.Errorf("DELETE user: status %d, want 204", status)
+	}
+	if status := serve(t, router, "", http.MethodGet, "/api/users/u1", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("GET user without credentials: status %d, want 401", status)
+	}
+}
+
+func TestRatingRoutes(t *testing.T) {
+	router, apiKey := testServer(t)
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users", `{"id":"u1"}`, nil); status != http.StatusCreated {
+		t.Fatalf("POST /api/users: status %d, want 201", status)
+	}
+
+	var missing errorBody
+	if status := serve(t, router, apiKey, http.MethodPut, "/api/users/u1/ratings/1", `{"rating":3}`, &missing); status != http.StatusNotFound || missing.Code != "place_not_found" {
+		t.Errorf("PUT rating of an unvisited place: status %d, body %+v; want 404 place_not_found", status, missing)
+	}
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users/u1/visit", `{"osmID":"1","osmType":"node"}`, nil); status != http.StatusCreated {
+		t.Fatalf("POST visit: status %d, want 201", status)
+	}
+
+	for body, want := range map[string]int{
+		`{"rating":2.5}`: http.StatusBadRequest,
+		`{"rating":"4"}`: http.StatusBadRequest,
+		`{"rating":300}`: http.StatusBadRequest,
+		`{}`:             http.StatusBadRequest,
+		`{"rating":0}`:   http.StatusUnprocessableEntity,
+		`{"rating":6}`:   http.StatusUnprocessableEntity,
+	} {
+		if status := serve(t, router, apiKey, http.MethodPut, "/api/users/u1/ratings/1", body, nil); status != want {
+			t.Errorf("PUT rating %s: status %d, want %d", body, status, want)
+		}
+	}
+
+	if status := serve(t, router, apiKey, http.MethodPut, "/api/users/u1/ratings/1", `{"rating":4}`, nil); status != http.StatusOK {
+		t.Errorf("PUT rating 4: status %d, want 200", status)
+	}
+	var rated struct {
+		Place struct {
+			Rating int `json:"rating"`
+		} `json:"place"`
+	}
+	if status := serve(t, router, apiKey, http.MethodGet, "/api/users/u1/ratings/1", "", &rated); status != http.StatusOK || rated.Place.Rating != 4 {
+		t.Errorf("GET rating: status %d, body %+v; want 200 with rating 4", status, rated)
+	}
+	if status := serve(t, router, apiKey, http.MethodDelete, "/api/users/u1/ratings/1", "", nil); status != http.StatusNoContent {
+		t.Errorf("DELETE rating: status %d, want 204", status)
+	}
+	if status := serve(t, router, apiKey, http.MethodGet, "/api/users/u1/ratings/1", "", nil); status != http.StatusNotFound {
+		t.Errorf("GET deleted rating: status %d, want 404", status)
+	}
+}
+
+func TestUserPlaceRoutes(t *testing.T) {
+	router, apiKey := testServer(t)
+	if status := serve(t, router, apiKey, http.MethodPost, "/api/users", `{"id":"u1"}`, nil); status != http.StatusCreated {
+		t.Fatalf("POST /api/users: status %d, want 201", status)
+	}
+
+	type placeBody struct {
+		Place struct {
+			OsmID     string   `json:"osmID"`
+			Tags      []string `json:"tags"`
+			VisitedAt *string  `json:"visitedAt"`
+		} `json:"place"`
+	}
+	for _, kind := range []string{"visit", "watch"} {
+		path := "/api/users/u1/" + kind
+		for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			var missing errorBody
+			if status := serve(t, router, apiKey, method, path+"/1", `{"tags":["lunch"]}`, &missing); status != http.StatusNotFound || missing.Code != "place_not_found" {
+				t.Errorf("%s unknown %s: status %d, body %+v; want 404 place_not_found", method, kind, status, missing)
+			}
+		}
+
+		if status := serve(t, router, apiKey, http.MethodPost, path, `{"osmID":"1","osmType":"node","tags":["old"]}`, nil); status != http.StatusCreated {
+			t.Fatalf("POST %s: status %d, want 201", kind, status)
+		}
+		var replaced placeBody
+		if status := serve(t, router, apiKey, http.MethodPut, path+"/1", `{"tags":["lunch","cheap"]}`, &replaced); status != http.StatusOK || len(replaced.Place.Tags) != 2 {
+			t.Errorf("PUT %s: status %d, body %+v; want 200 with both tags", kind, status, replaced)
+		}
+		var patched placeBody
+		if status := serve(t, router, apiKey, http.MethodPatch, path+"/1", `{}`, &patched); status != http.StatusOK || len(patched.Place.Tags) != 2 {
+			t.Errorf("PATCH %s without fields: status %d, body %+v; want 200 with the tags kept", kind, status, patched)
+		}
+		if status := serve(t, router, apiKey, http.MethodPatch, path+"/1", `{"tags":"lunch"}`, nil); status != http.StatusBadRequest {
+			t.Errorf("PATCH %s with invalid tags: status %d, want 400", kind, status)
+		}
+	}
+
+	// The visit date of a visited place follows its visits, while a watched
+	// place's can be set.
+	visitedAt := `{"visitedAt":"2025-03-01T12:00:00Z"}`
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		var readOnly errorBody
+		if status := serve(t, router, apiKey, method, "/api/users/u1/visit/1", visitedAt, &readOnly); status != http.StatusUnprocessableEntity || readOnly.Code != "validation_failed" {
+			t.Errorf("%s visit with visitedAt: status %d, body %+v; want 422 validation_failed", method, status, readOnly)
+		}
+		var watched placeBody
+		if status := serve(t, router, apiKey, method, "/api/users/u1/watch/1", visitedAt, &watched); status != http.StatusOK || watched.Place.VisitedAt == nil {
+			t.Errorf("%s watch with visitedAt: status %d, body %+v; want 200 with the date", method, status, watched)
+		}
+	}
+
+	for _, kind := range []string{"visit", "watch"} {
+		path := "/api/users/u1/" + kind + "/1"
+		if status := serve(t, router, apiKey, http.MethodDelete, path, "", nil); status != http.StatusNoContent {
+			t.Errorf("DELETE %s: status %d, want 204", kind, status)
+		}
+		if status := serve(t, router, apiKey, http.MethodGet, path, "", nil); status != http.StatusNotFound {
+			t.Errorf("GET deleted %s: status %d, want 404", kind, status)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"backend/data"
+	"backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type visitRequest struct {
+	VisitedAt *time.Time `json:"visitedAt"`
+	Rating    *int8      `json:"rating"`
+	Notes     *string    `json:"notes"`
+	Tags      []string   `json:"tags"`
+}
+
+func GetVisits(c *gin.Context) {
+	visits, err := services.GetVisits(c.Request.Context(), c.Param("id"), placeKey(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"visits": visits,
+	})
+}
+
+func AddVisit(c *gin.Context) {
+	var req visitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	visit := data.Visit{Rating: req.Rating, Tags: req.Tags}
+	if req.VisitedAt != nil {
+		visit.VisitedAt = *req.VisitedAt
+	}
+	if req.Notes != nil {
+		visit.Notes = *req.Notes
+	}
+
+	place, added, err := services.RecordVisit(c.Request.Context(), c.Param("id"), placeKey(c), visit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Visit recorded successfully",
+		"place":   place,
+		"visit":   added,
+	})
+}
+
+func UpdateVisit(c *gin.Context) {
+	var req visitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	patch := services.VisitPatch{VisitedAt: req.VisitedAt, Rating: req.Rating, Notes: req.Notes, Tags: req.Tags}
+	place, visit, err := services.UpdateVisit(c.Request.Context(), c.Param("id"), placeKey(c), c.Param("visitID"), patch)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Visit updated successfully",
+		"place":   place,
+		"visit":   visit,
+	})
+}
+
+func DeleteVisit(c *gin.Context) {
+	_, err := services.DeleteVisit(c.Request.Context(), c.Param("id"), placeKey(c), c.Param("visitID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"github.com/gin-contrib/cors"
 	"log"
 	"os"
 	"time"
 
+	"backend/nominatim"
+	"backend/overpass"
+	"backend/placestore"
 	"backend/routes"
+	"backend/services"
+	"backend/storage"
 	"backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -19,23 +25,53 @@ func main() {
 		log.Println("No .env file found, relying on system environment variables")
 	}
 
-	utils.InitFirebase()
-	defer utils.CloseFirestoreClient()
+	closeStorage := setupStorage()
+	defer closeStorage()
+
+	// ADMIN_API_KEY bootstraps access to the /api/admin endpoints
+	services.SetBootstrapAdminKey(os.Getenv("ADMIN_API_KEY"))
+
+	// API_KEY is the shared key the frontend sent before it had Clerk
+	// tokens. It keeps read and write access to every user while
+	// ALLOW_LEGACY_API_KEY is "true", so the frontend keeps working until it
+	// sends "Authorization: Bearer <Clerk session token>" with every request.
+	// Requests that send a token are held to its user even if they also send
+	// API_KEY. Once no client relies on API_KEY alone, unset
+	// ALLOW_LEGACY_API_KEY and remove VITE_BACKEND_API_KEY from the frontend;
+	// scripts move to keys created through /api/admin/keys.
+	if os.Getenv("ALLOW_LEGACY_API_KEY") == "true" {
+		legacyKey := os.Getenv("API_KEY")
+		if legacyKey == "" {
+			log.Fatalf("ALLOW_LEGACY_API_KEY is set but API_KEY is not")
+		}
+		log.Println("Accepting the legacy shared API_KEY; unset ALLOW_LEGACY_API_KEY once clients send Clerk tokens")
+		services.SetLegacyAPIKey(legacyKey)
+	}
+
+	closePlaces := setupPlaces()
+	defer closePlaces()
+
+	// Reverse geocoding goes to the Nominatim server at NOMINATIM_URL, or
+	// the public instance if it is not set, identified by
+	// NOMINATIM_USER_AGENT if set
+	services.SetReverseGeocoder(nominatim.NewClient(os.Getenv("NOMINATIM_URL"), os.Getenv("NOMINATIM_USER_AGENT")))
 
 	// Initialize Gin router
 	router := gin.Default()
 
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "https://zeckhardt.github.io"}, // Frontend dev URL
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "x-api-key"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "x-api-key"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	// Setup all routes
-	routes.SetupRoutes(router)
+	if err := routes.SetupRoutes(router); err != nil {
+		log.Fatalf("Error setting up routes: %v", err)
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -47,3 +83,80 @@ func main() {
 		log.Fatalf("Error starting server: %v", err)
 	}
 }
+
+// setupPlaces picks where imports match saved places against OSM data, the
+// place catalog fetches place details and the map searches for places: the
+// place file at PLACE_STORE, built by cmd/osmimport, or else the Overpass
+// server at OVERPASS_URL, or the public instance if that is not set. It
+// returns a function that releases the place file.
+func setupPlaces() func() {
+	if path := os.Getenv("PLACE_STORE"); path != "" {
+		store, err := placestore.Open(context.Background(), path)
+		if err != nil {
+			log.Fatalf("error opening place store: %v", err)
+		}
+		log.Printf("Using places from %s", path)
+		services.SetPlaceMatcher(store)
+		services.SetPlaceLookup(store)
+		services.SetLocalPlaceSearcher(store)
+		return func() {
+			if err := store.Close(); err != nil {
+				log.Printf("Error closing place store: %v", err)
+			}
+		}
+	}
+
+	overpassClient := overpass.NewClient(os.Getenv("OVERPASS_URL"))
+	services.SetPlaceMatcher(overpassClient)
+	services.SetPlaceLookup(overpassClient)
+
+	// Map searches are cached for OVERPASS_CACHE_TTL (such as "30m"), or an
+	// hour if it is not set
+	mapCacheTTL := services.DefaultMapCacheTTL
+	if value := os.Getenv("OVERPASS_CACHE_TTL"); value != "" {
+		var err error
+		if mapCacheTTL, err = time.ParseDuration(value); err != nil || mapCacheTTL <= 0 {
+			log.Fatalf("Invalid OVERPASS_CACHE_TTL %q", value)
+		}
+	}
+	services.SetPlaceSearcher(overpassClient, mapCacheTTL)
+	return func() {}
+}
+
+// setupStorage picks the storage backend from STORAGE_BACKEND and returns a
+// function that releases it. Firestore is the default; the SQL backends read
+// their connection string from DATABASE_URL.
+func setupStorage() func() {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "memory":
+		log.Println("Using in-memory storage, data will not persist across restarts")
+		services.SetRepository(storage.NewMemoryRepository())
+		return func() {}
+	case storage.DialectSQLite, storage.DialectPostgres:
+		dsn := os.Getenv("DATABASE_URL")
+		if dsn == "" && backend == storage.DialectSQLite {
+			dsn = "eatfinder.db"
+		}
+		if dsn == "" {
+			log.Fatalf("DATABASE_URL environment variable is not set")
+		}
+		repo, err := storage.OpenSQL(context.Background(), backend, dsn)
+		if err != nil {
+			log.Fatalf("error opening %s database: %v", backend, err)
+		}
+		log.Printf("Using %s storage", backend)
+		services.SetRepository(repo)
+		return func() {
+			if err := repo.Close(); err != nil {
+				log.Printf("Error closing database: %v", err)
+			}
+		}
+	case "", "firestore":
+		utils.InitFirebase()
+		services.SetRepository(storage.NewFirestoreRepository(utils.FirestoreClient))
+		return utils.CloseFirestoreClient
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %q", backend)
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+// Package nominatim reverse geocodes points on a Nominatim server.
+package nominatim
+
+import (
+	"backend/data"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the public instance the frontend used to query directly.
+// Its usage policy asks for an identifying User-Agent and at most one
+// request per second.
+const DefaultURL = "https://nominatim.openstreetmap.org"
+
+// DefaultUserAgent identifies the backend to the server.
+const DefaultUserAgent = "EatFinder backend (+https://zeckhardt.github.io/EatFinder)"
+
+// reverseZoom asks for the address down to the neighbourhood.
+const reverseZoom = 14
+
+// Client queries a Nominatim server. It implements services.ReverseGeocoder.
+type Client struct {
+	url        string
+	userAgent  string
+	httpClient *http.Client
+}
+
+type reverseResponse struct {
+	Error       string            `json:"error"`
+	DisplayName string            `json:"display_name"`
+	Address     map[string]string `json:"address"`
+}
+
+// NewClient returns a client for the server at serverURL, or for DefaultURL
+// if serverURL is empty, that sends userAgent, or DefaultUserAgent if
+// userAgent is empty.
+func NewClient(serverURL string, userAgent string) *Client {
+	if serverURL == "" {
+		serverURL = DefaultURL
+	}
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &Client{url: strings.TrimSuffix(serverURL, "/"), userAgent: userAgent, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (c *Client) ReverseGeocode(ctx context.Context, lat float64, long float64) (data.Location, error) {
+	query := url.Values{
+		"format":         {"jsonv2"},
+		"lat":            {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"lon":            {strconv.FormatFloat(long, 'f', -1, 64)},
+		"zoom":           {strconv.Itoa(reverseZoom)},
+		"addressdetails": {"1"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/reverse?"+query.Encode(), nil)
+	if err != nil {
+		return data.Location{}, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept-Language", "en")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return data.Location{}, fmt.Errorf("nominatim: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return data.Location{}, fmt.Errorf("nominatim: unexpected status %s", resp.Status)
+	}
+
+	var body reverseResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return data.Location{}, fmt.Errorf("nominatim: invalid response: %w", err)
+	}
+	if body.Error != "" {
+		// Nothing there, such as at sea.
+		return data.Location{}, nil
+	}
+	return data.Location{
+		City:          firstOf(body.Address, "city", "town", "village", "municipality"),
+		Neighbourhood: firstOf(body.Address, "neighbourhood", "suburb", "quarter", "city_district"),
+		Country:       body.Address["country"],
+		CountryCode:   body.Address["country_code"],
+		DisplayName:   body.DisplayName,
+	}, nil
+}
+
+// firstOf returns the first of the given address parts that is set.
+func firstOf(address map[string]string, parts ...string) string {
+	for _, part := range parts {
+		if value := address[part]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
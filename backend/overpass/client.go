@@ -0,0 +1,198 @@
+// Package overpass looks up eating and drinking places on an Overpass API
+// server, either near a location or by OSM type and ID.
+package overpass
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the public instance the frontend map queries as well.
+const DefaultURL = "https://overpass-api.de/api/interpreter"
+
+// eateryFilter selects the places the map shows.
+var eateryFilter = `["amenity"~"^(` + strings.Join(services.EateryAmenities, "|") + `)$"]`
+
+const (
+	// pointsPerQuery bounds the size of a single query.
+	pointsPerQuery = 25
+	userAgent      = "EatFinder backend"
+)
+
+// Client queries an Overpass API server. It implements
+// services.PlaceMatcher, services.PlaceLookup and services.PlaceSearcher.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+type overpassResponse struct {
+	Elements []overpassElement `json:"elements"`
+}
+
+type overpassElement struct {
+	Type   string  `json:"type"`
+	ID     int64   `json:"id"`
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+	Center *struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"center"`
+	Tags map[string]string `json:"tags"`
+}
+
+// NewClient returns a client for the interpreter endpoint at serverURL, or
+// for DefaultURL if serverURL is empty.
+func NewClient(serverURL string) *Client {
+	if serverURL == "" {
+		serverURL = DefaultURL
+	}
+	return &Client{url: serverURL, httpClient: &http.Client{Timeout: 90 * time.Second}}
+}
+
+func (c *Client) NearbyPlaces(ctx context.Context, points []services.GeoPoint, radius float64) ([][]data.PlaceMatch, error) {
+	nearby := make([][]data.PlaceMatch, len(points))
+	for start := 0; start < len(points); start += pointsPerQuery {
+		end := min(start+pointsPerQuery, len(points))
+		elements, err := c.query(ctx, nearbyQuery(points[start:end], radius))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := start; i < end; i++ {
+			nearby[i] = []data.PlaceMatch{}
+			for _, element := range elements {
+				lat, lon := element.location()
+				if services.Distance(points[i], services.GeoPoint{Lat: lat, Lon: lon}) > radius {
+					continue
+				}
+				nearby[i] = append(nearby[i], data.PlaceMatch{
+					OsmID:   strconv.FormatInt(element.ID, 10),
+					OsmType: element.Type,
+					Name:    element.Tags["name"],
+					Long:    lon,
+					Lat:     lat,
+				})
+			}
+		}
+	}
+	return nearby, nil
+}
+
+func (c *Client) LookupPlaces(ctx context.Context, keys []string) ([]data.CatalogPlace, error) {
+	idsByType := map[string][]string{}
+	for _, key := range keys {
+		// IDs go into the query as they are, so anything but a number is
+		// left out, like a place that is not in OSM.
+		if osmType, osmID := services.SplitPlaceKey(key); osmType != "" && services.ValidOsmID(osmID) {
+			idsByType[osmType] = append(idsByType[osmType], osmID)
+		}
+	}
+	if len(idsByType) == 0 {
+		return []data.CatalogPlace{}, nil
+	}
+
+	elements, err := c.query(ctx, lookupQuery(idsByType))
+	if err != nil {
+		return nil, err
+	}
+	return catalogPlaces(elements), nil
+}
+
+func (c *Client) PlacesInBox(ctx context.Context, box services.BoundingBox) ([]data.CatalogPlace, error) {
+	elements, err := c.query(ctx, boxQuery(box))
+	if err != nil {
+		return nil, err
+	}
+	return catalogPlaces(elements), nil
+}
+
+func (c *Client) query(ctx context.Context, query string) ([]overpassElement, error) {
+	form := url.Values{"data": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("overpass: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("overpass: unexpected status %s", resp.Status)
+	}
+
+	var body overpassResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("overpass: invalid response: %w", err)
+	}
+	return body.Elements, nil
+}
+
+// nearbyQuery selects the eateries around any of points. Only named ones
+// are selected, as those are the only ones that can be matched. Ways and
+// relations are returned with their centre.
+func nearbyQuery(points []services.GeoPoint, radius float64) string {
+	var b strings.Builder
+	b.WriteString("[out:json][timeout:60];\n(\n")
+	for _, point := range points {
+		fmt.Fprintf(&b, "  nwr%s[\"name\"](around:%s,%s,%s);\n", eateryFilter,
+			strconv.FormatFloat(radius, 'f', -1, 64),
+			strconv.FormatFloat(point.Lat, 'f', -1, 64),
+			strconv.FormatFloat(point.Lon, 'f', -1, 64))
+	}
+	b.WriteString(");\nout center tags;\n")
+	return b.String()
+}
+
+// lookupQuery selects elements by type and ID. Ways and relations are
+// returned with their centre.
+func lookupQuery(idsByType map[string][]string) string {
+	var b strings.Builder
+	b.WriteString("[out:json][timeout:60];\n(\n")
+	for _, osmType := range []string{"node", "way", "relation"} {
+		if ids := idsByType[osmType]; len(ids) > 0 {
+			fmt.Fprintf(&b, "  %s(id:%s);\n", osmType, strings.Join(ids, ","))
+		}
+	}
+	b.WriteString(");\nout center tags;\n")
+	return b.String()
+}
+
+// boxQuery selects the eateries inside box, named or not. Ways and
+// relations are returned with their centre.
+func boxQuery(box services.BoundingBox) string {
+	return fmt.Sprintf("[out:json][timeout:60];\nnwr%s(%s,%s,%s,%s);\nout center tags;\n", eateryFilter,
+		strconv.FormatFloat(box.South, 'f', -1, 64),
+		strconv.FormatFloat(box.West, 'f', -1, 64),
+		strconv.FormatFloat(box.North, 'f', -1, 64),
+		strconv.FormatFloat(box.East, 'f', -1, 64))
+}
+
+func catalogPlaces(elements []overpassElement) []data.CatalogPlace {
+	places := make([]data.CatalogPlace, 0, len(elements))
+	for _, element := range elements {
+		lat, lon := element.location()
+		places = append(places, services.NewCatalogPlace(element.Type, strconv.FormatInt(element.ID, 10), lat, lon, element.Tags))
+	}
+	return places
+}
+
+func (e overpassElement) location() (float64, float64) {
+	if e.Center != nil {
+		return e.Center.Lat, e.Center.Lon
+	}
+	return e.Lat, e.Lon
+}
@@ -0,0 +1,118 @@
+package overpass_test
+
+import (
+	"backend/overpass"
+	"backend/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// overpassServer answers every query with response and records the last
+// query it received.
+func overpassServer(t *testing.T, status int, response string) (*httptest.Server, *string) {
+	t.Helper()
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		query = r.PostFormValue("data")
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &query
+}
+
+const elements = `{"elements": [
+	{"type": "node", "id": 1, "lat": 52.5, "lon": 13.4, "tags": {"name": "Cafe", "amenity": "cafe", "cuisine": "coffee_shop"}},
+	{"type": "way", "id": 2, "center": {"lat": 52.6, "lon": 13.5}, "tags": {"name": "Pub", "amenity": "pub"}}
+]}`
+
+func TestPlacesInBox(t *testing.T) {
+	server, query := overpassServer(t, http.StatusOK, elements)
+	client := overpass.NewClient(server.URL)
+
+	places, err := client.PlacesInBox(t.Context(), services.BoundingBox{South: 52.4, West: 13.3, North: 52.7, East: 13.6})
+	if err != nil {
+		t.Fatalf("PlacesInBox: %v", err)
+	}
+	if !strings.Contains(*query, "(52.4,13.3,52.7,13.6)") || !strings.Contains(*query, `"amenity"~`) {
+		t.Errorf("query = %q, want the eateries inside the box", *query)
+	}
+	if len(places) != 2 {
+		t.Fatalf("PlacesInBox = %+v, want 2 places", places)
+	}
+	if places[0].Key != "node/1" || places[0].Name != "Cafe" || places[0].Cuisine != "coffee_shop" || places[0].Lat != 52.5 {
+		t.Errorf("PlacesInBox[0] = %+v, want the cafe", places[0])
+	}
+	if places[1].Key != "way/2" || places[1].Lat != 52.6 || places[1].Long != 13.5 {
+		t.Errorf("PlacesInBox[1] = %+v, want the pub at the centre of its way", places[1])
+	}
+}
+
+func TestLookupPlaces(t *testing.T) {
+	server, query := overpassServer(t, http.StatusOK, elements)
+	client := overpass.NewClient(server.URL)
+
+	places, err := client.LookupPlaces(t.Context(), []string{"node/1", "way/2", "3"})
+	if err != nil {
+		t.Fatalf("LookupPlaces: %v", err)
+	}
+	if !strings.Contains(*query, "node(id:1);") || !strings.Contains(*query, "way(id:2);") || strings.Contains(*query, "id:3") {
+		t.Errorf("query = %q, want node 1 and way 2 only", *query)
+	}
+	if len(places) != 2 {
+		t.Errorf("LookupPlaces = %+v, want 2 places", places)
+	}
+
+	*query = ""
+	places, err = client.LookupPlaces(t.Context(), []string{"3"})
+	if err != nil || len(places) != 0 || *query != "" {
+		t.Errorf("LookupPlaces of bare IDs = %+v, %v with query %q, want nothing and no query", places, err, *query)
+	}
+
+	// A bad ID is left out instead of failing the batch or changing the query.
+	*query = ""
+	if _, err := client.LookupPlaces(t.Context(), []string{"node/1", "node/1);out;node(2", "way/x"}); err != nil {
+		t.Fatalf("LookupPlaces with bad IDs: %v", err)
+	}
+	if !strings.Contains(*query, "node(id:1);") || strings.Contains(*query, "out;node") || strings.Contains(*query, "way(") {
+		t.Errorf("query = %q, want node 1 only", *query)
+	}
+}
+
+func TestNearbyPlaces(t *testing.T) {
+	server, _ := overpassServer(t, http.StatusOK, elements)
+	client := overpass.NewClient(server.URL)
+
+	points := []services.GeoPoint{{Lat: 52.5, Lon: 13.4}, {Lat: 0, Lon: 0}}
+	nearby, err := client.NearbyPlaces(t.Context(), points, 100)
+	if err != nil {
+		t.Fatalf("NearbyPlaces: %v", err)
+	}
+	if len(nearby) != 2 || len(nearby[0]) != 1 || nearby[0][0].OsmID != "1" || len(nearby[1]) != 0 {
+		t.Errorf("NearbyPlaces = %+v, want the cafe near the first point only", nearby)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	responses := map[string]struct {
+		status int
+		body   string
+	}{
+		"server error":     {http.StatusTooManyRequests, "rate limited"},
+		"invalid response": {http.StatusOK, "<html>"},
+	}
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			server, _ := overpassServer(t, response.status, response.body)
+			client := overpass.NewClient(server.URL)
+			if _, err := client.PlacesInBox(t.Context(), services.BoundingBox{South: 0, West: 0, North: 1, East: 1}); err == nil {
+				t.Error("PlacesInBox: got no error")
+			}
+		})
+	}
+}
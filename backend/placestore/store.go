@@ -0,0 +1,192 @@
+// Package placestore keeps eating and drinking places from an OSM extract
+// in a local SQLite file, so that place search and lookup work offline or
+// self-hosted instead of asking the public Overpass API. The file is built
+// by cmd/osmimport.
+package placestore
+
+import (
+	"backend/data"
+	"backend/geo"
+	"backend/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE places (
+    osm_type TEXT NOT NULL,
+    osm_id   TEXT NOT NULL,
+    lat      REAL NOT NULL,
+    lng      REAL NOT NULL,
+    geohash  TEXT NOT NULL,
+    name     TEXT NOT NULL,
+    tags     TEXT NOT NULL,
+    PRIMARY KEY (osm_type, osm_id)
+);
+CREATE INDEX places_geohash ON places (geohash);
+`
+
+const (
+	// geohashLength is the length of the stored geohashes. Box queries
+	// look up the cells covering the box by geohash prefix.
+	geohashLength = 9
+	// maxQueryCells bounds the cells a box query looks up; larger boxes use
+	// shorter, coarser cells.
+	maxQueryCells = 32
+	// lookupBatch bounds the IDs bound to a single query.
+	lookupBatch = 500
+	// maxBoxPlaces bounds the places a box query returns, more than a map
+	// search of the largest area the services allow should find.
+	maxBoxPlaces = 10000
+)
+
+// Store reads places from a file built by a Writer. It implements
+// services.PlaceMatcher, services.PlaceLookup and services.PlaceSearcher.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the place file at path for reading.
+func Open(ctx context.Context, path string) (*Store, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM places`).Scan(&count); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("placestore: %s is not a place file: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) NearbyPlaces(ctx context.Context, points []services.GeoPoint, radius float64) ([][]data.PlaceMatch, error) {
+	nearby := make([][]data.PlaceMatch, len(points))
+	for i, point := range points {
+		south, west, north, east := geo.BoxAround(point.Lat, point.Lon, radius)
+		places, err := s.PlacesInBox(ctx, services.BoundingBox{South: south, West: west, North: north, East: east})
+		if err != nil {
+			return nil, err
+		}
+		nearby[i] = []data.PlaceMatch{}
+		for _, place := range places {
+			// Only named places can be matched.
+			if place.Name == "" || services.Distance(point, services.GeoPoint{Lat: place.Lat, Lon: place.Long}) > radius {
+				continue
+			}
+			nearby[i] = append(nearby[i], data.PlaceMatch{
+				OsmID:   place.OsmID,
+				OsmType: place.OsmType,
+				Name:    place.Name,
+				Long:    place.Long,
+				Lat:     place.Lat,
+			})
+		}
+	}
+	return nearby, nil
+}
+
+func (s *Store) LookupPlaces(ctx context.Context, keys []string) ([]data.CatalogPlace, error) {
+	idsByType := map[string][]any{}
+	for _, key := range keys {
+		if osmType, osmID := services.SplitPlaceKey(key); osmType != "" {
+			idsByType[osmType] = append(idsByType[osmType], osmID)
+		}
+	}
+
+	places := []data.CatalogPlace{}
+	for osmType, ids := range idsByType {
+		for start := 0; start < len(ids); start += lookupBatch {
+			batch := ids[start:min(start+lookupBatch, len(ids))]
+			found, err := s.query(ctx, `SELECT osm_type, osm_id, lat, lng, tags FROM places WHERE osm_type = ? AND osm_id IN (?`+strings.Repeat(`, ?`, len(batch)-1)+`)`,
+				append([]any{osmType}, batch...)...)
+			if err != nil {
+				return nil, err
+			}
+			places = append(places, found...)
+		}
+	}
+	return places, nil
+}
+
+// PlacesInBox returns the places inside box, at most maxBoxPlaces of them,
+// so callers are expected to bound the box. A box whose west edge lies east
+// of its east edge crosses the antimeridian.
+func (s *Store) PlacesInBox(ctx context.Context, box services.BoundingBox) ([]data.CatalogPlace, error) {
+	if box.West <= box.East {
+		return s.placesInBox(ctx, box, maxBoxPlaces)
+	}
+	west := box
+	west.East = 180
+	places, err := s.placesInBox(ctx, west, maxBoxPlaces)
+	if err != nil || len(places) == maxBoxPlaces {
+		return places, err
+	}
+	east := box
+	east.West = -180
+	more, err := s.placesInBox(ctx, east, maxBoxPlaces-len(places))
+	if err != nil {
+		return nil, err
+	}
+	return append(places, more...), nil
+}
+
+// placesInBox returns at most limit places inside box, which must not cross
+// the antimeridian.
+func (s *Store) placesInBox(ctx context.Context, box services.BoundingBox, limit int) ([]data.CatalogPlace, error) {
+	var cells []string
+	for precision := 6; precision > 0 && cells == nil; precision-- {
+		cells = geo.Cover(box.South, box.West, box.North, box.East, precision, maxQueryCells)
+	}
+
+	query := `SELECT osm_type, osm_id, lat, lng, tags FROM places WHERE lat BETWEEN ? AND ? AND lng BETWEEN ? AND ?`
+	args := []any{box.South, box.North, box.West, box.East}
+	if cells != nil {
+		ranges := make([]string, len(cells))
+		for i, cell := range cells {
+			// "{" sorts right after "z", the last geohash character.
+			ranges[i] = `(geohash >= ? AND geohash < ?)`
+			args = append(args, cell, cell+"{")
+		}
+		query += ` AND (` + strings.Join(ranges, ` OR `) + `)`
+	}
+	query += ` LIMIT ?`
+	args = append(args, limit)
+	return s.query(ctx, query, args...)
+}
+
+func (s *Store) query(ctx context.Context, query string, args ...any) ([]data.CatalogPlace, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	places := []data.CatalogPlace{}
+	for rows.Next() {
+		var osmType, osmID, tagsJSON string
+		var lat, long float64
+		if err := rows.Scan(&osmType, &osmID, &lat, &long, &tagsJSON); err != nil {
+			return nil, err
+		}
+		var tags map[string]string
+		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+			return nil, err
+		}
+		places = append(places, services.NewCatalogPlace(osmType, osmID, lat, long, tags))
+	}
+	return places, rows.Err()
+}
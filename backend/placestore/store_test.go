@@ -0,0 +1,105 @@
+package placestore
+
+import (
+	"backend/services"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// placeFile writes places, keyed by "type/id", to a new place file and
+// opens it.
+func placeFile(t *testing.T, places map[string][2]float64) *Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "places.db")
+	w, err := Create(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for key, point := range places {
+		osmType, osmID := services.SplitPlaceKey(key)
+		tags := map[string]string{"amenity": "cafe", "name": "Cafe " + osmID}
+		if err := w.Add(t.Context(), osmType, osmID, point[0], point[1], tags); err != nil {
+			t.Fatalf("Add %s: %v", key, err)
+		}
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	s, err := Open(t.Context(), path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := placeFile(t, map[string][2]float64{
+		"node/1":     {52.5, 13.4},
+		"way/2":      {52.51, 13.41},
+		"relation/3": {48.1, 11.6},
+		"node/4":     {0, 179.95},
+		"node/5":     {0, -179.95},
+	})
+
+	found, err := s.LookupPlaces(t.Context(), []string{"node/1", "way/2", "way/1", "3", "node/9"})
+	if err != nil {
+		t.Fatalf("LookupPlaces: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("LookupPlaces = %+v, want node 1 and way 2", found)
+	}
+	for _, place := range found {
+		if place.Key == "node/1" && (place.Name != "Cafe 1" || place.Lat != 52.5 || place.Long != 13.4) {
+			t.Errorf("LookupPlaces node 1 = %+v, want the cafe with its tags and position", place)
+		}
+	}
+
+	inBerlin, err := s.PlacesInBox(t.Context(), services.BoundingBox{South: 52.4, West: 13.3, North: 52.6, East: 13.5})
+	if err != nil {
+		t.Fatalf("PlacesInBox: %v", err)
+	}
+	if len(inBerlin) != 2 {
+		t.Errorf("PlacesInBox around Berlin = %+v, want node 1 and way 2", inBerlin)
+	}
+
+	across, err := s.PlacesInBox(t.Context(), services.BoundingBox{South: -1, West: 179.9, North: 1, East: -179.9})
+	if err != nil {
+		t.Fatalf("PlacesInBox across the antimeridian: %v", err)
+	}
+	if len(across) != 2 {
+		t.Errorf("PlacesInBox across the antimeridian = %+v, want nodes 4 and 5", across)
+	}
+
+	nearby, err := s.NearbyPlaces(t.Context(), []services.GeoPoint{{Lat: 52.5, Lon: 13.4}, {Lat: 10, Lon: 10}}, 100)
+	if err != nil {
+		t.Fatalf("NearbyPlaces: %v", err)
+	}
+	if len(nearby) != 2 || len(nearby[0]) != 1 || nearby[0][0].OsmID != "1" || len(nearby[1]) != 0 {
+		t.Errorf("NearbyPlaces = %+v, want node 1 near the first point only", nearby)
+	}
+}
+
+func TestPlacesInBoxLimit(t *testing.T) {
+	places := make(map[string][2]float64, maxBoxPlaces+1)
+	for i := 1; i <= maxBoxPlaces+1; i++ {
+		places["node/"+strconv.Itoa(i)] = [2]float64{52.5 + float64(i)*1e-6, 13.4}
+	}
+	s := placeFile(t, places)
+
+	found, err := s.PlacesInBox(t.Context(), services.BoundingBox{South: 52.4, West: 13.3, North: 52.6, East: 13.5})
+	if err != nil {
+		t.Fatalf("PlacesInBox: %v", err)
+	}
+	if len(found) != maxBoxPlaces {
+		t.Errorf("PlacesInBox returned %d places, want %d", len(found), maxBoxPlaces)
+	}
+}
+
+func TestOpenRejectsOtherFiles(t *testing.T) {
+	if _, err := Open(t.Context(), filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("Open of a missing file: got no error")
+	}
+}
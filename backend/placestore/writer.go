@@ -0,0 +1,111 @@
+package placestore
+
+import (
+	"backend/geo"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+)
+
+// writeBatch is how many places are written per transaction.
+const writeBatch = 5000
+
+// Writer builds a place file. It writes to a temporary file next to the
+// target and only replaces the target on Commit, so a server reading the
+// old file is not disturbed by a failed or running import.
+type Writer struct {
+	db      *sql.DB
+	path    string
+	tmpPath string
+	tx      *sql.Tx
+	stmt    *sql.Stmt
+	pending int
+	count   int
+}
+
+// Create starts a new place file that will replace the one at path.
+func Create(ctx context.Context, path string) (*Writer, error) {
+	tmpPath := path + ".tmp"
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Writer{db: db, path: path, tmpPath: tmpPath}, nil
+}
+
+// Add writes a place. Adding a place twice keeps the last one.
+func (w *Writer) Add(ctx context.Context, osmType string, osmID string, lat float64, long float64, tags map[string]string) error {
+	if w.tx == nil {
+		tx, err := w.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		stmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO places (osm_type, osm_id, lat, lng, geohash, name, tags) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		w.tx, w.stmt = tx, stmt
+	}
+
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := w.stmt.ExecContext(ctx, osmType, osmID, lat, long, geo.Encode(lat, long, geohashLength), tags["name"], string(tagsJSON)); err != nil {
+		return err
+	}
+	w.count++
+	if w.pending++; w.pending == writeBatch {
+		return w.flush()
+	}
+	return nil
+}
+
+// Count returns the number of places added so far.
+func (w *Writer) Count() int {
+	return w.count
+}
+
+func (w *Writer) flush() error {
+	if w.tx == nil {
+		return nil
+	}
+	w.stmt.Close()
+	err := w.tx.Commit()
+	w.tx, w.stmt, w.pending = nil, nil, 0
+	return err
+}
+
+// Commit finishes the file and moves it into place.
+func (w *Writer) Commit() error {
+	if err := w.flush(); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.db.Close(); err != nil {
+		os.Remove(w.tmpPath)
+		return err
+	}
+	return os.Rename(w.tmpPath, w.path)
+}
+
+// Abort discards the file, leaving the one at the target path alone.
+func (w *Writer) Abort() {
+	if w.tx != nil {
+		w.stmt.Close()
+		w.tx.Rollback()
+		w.tx = nil
+	}
+	w.db.Close()
+	os.Remove(w.tmpPath)
+}
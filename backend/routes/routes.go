@@ -2,13 +2,21 @@ package routes
 
 import (
 	"backend/handlers"
+	"backend/services"
 	"backend/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine) {
+// SetupRoutes registers all routes on router. It returns an error if the
+// authentication middleware cannot be set up.
+func SetupRoutes(router *gin.Engine) error {
+	jwtMiddleware, err := utils.JWTMiddleware()
+	if err != nil {
+		return err
+	}
+
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "This is an API server use /api/ endpoints."})
 	})
@@ -20,35 +28,102 @@ func SetupRoutes(router *gin.Engine) {
 	}
 
 	apiGroup := router.Group("/api")
+	apiGroup.Use(handlers.ErrorHandler())
 	{
+		// Shared lists can be read without signing in; the share token is
+		// the only credential.
+		public := apiGroup.Group("/public")
+		{
+			public.GET("/lists/:token", handlers.GetSharedList)
+		}
 
 		authenticated := apiGroup.Group("")
-		authenticated.Use(utils.APIKeyMiddleware())
+		authenticated.Use(utils.APIKeyMiddleware(), jwtMiddleware, utils.AuthorizeUser())
 		{
 			authenticated.GET("/protected", func(c *gin.Context) {
-				c.JSON(200, gin.H{"message": "You accessed a protected route within /api using an API Key!"})
+				c.JSON(200, gin.H{"message": "You accessed a protected route within /api as " + utils.AuthSubject(c)})
 			})
 
 			authenticated.POST("/users", handlers.CreateUser)
 			authenticated.GET("/users/:id", handlers.GetUser)
 			authenticated.DELETE("/users/:id", handlers.DeleteUser)
+			authenticated.GET("/users/:id/events", handlers.StreamEvents)
+			authenticated.GET("/users/:id/sync", handlers.GetChanges)
+			authenticated.POST("/users/:id/sync", handlers.Sync)
 
 			authenticated.POST("/users/:id/lists", handlers.CreateList)
-			authenticated.GET("/users/:id/lists", handlers.GetList)
+			authenticated.GET("/users/:id/lists", handlers.GetLists)
 			authenticated.DELETE("/users/:id/lists", handlers.DeleteList)
 
-			authenticated.POST("/users/:id/lists/:listName", handlers.AddToList)
-			authenticated.DELETE("/users/:id/lists/:listName", handlers.RemoveFromList)
+			// :listID also accepts a list name on the place routes.
+			authenticated.GET("/users/:id/lists/:listID", handlers.GetListByID)
+			authenticated.PATCH("/users/:id/lists/:listID", handlers.UpdateList)
+			authenticated.DELETE("/users/:id/lists/:listID", handlers.DeleteListByID)
+			authenticated.POST("/users/:id/lists/:listID", handlers.AddToList)
+			authenticated.POST("/users/:id/lists/:listID/places", handlers.AddToList)
+			authenticated.PATCH("/users/:id/lists/:listID/places/:osmID", handlers.UpdateListPlace)
+			authenticated.DELETE("/users/:id/lists/:listID/places/:osmID", handlers.RemoveFromList)
+			authenticated.POST("/users/:id/lists/:listID/places/:osmID/move", handlers.MoveListPlace)
+			authenticated.POST("/users/:id/lists/:listID/places/:osmID/copy", handlers.CopyListPlace)
+			authenticated.PUT("/users/:id/lists/:listID/order", handlers.ReorderList)
+			authenticated.PUT("/users/:id/lists/:listID/share", handlers.ShareList)
+			authenticated.DELETE("/users/:id/lists/:listID/share", handlers.RevokeShare)
+			authenticated.POST("/users/:id/lists/:listID/share/regenerate", handlers.RegenerateShareToken)
+			authenticated.POST("/users/:id/lists/:listID/members", handlers.InviteMember)
+			authenticated.GET("/users/:id/lists/:listID/members", handlers.GetListMembers)
+			authenticated.PATCH("/users/:id/lists/:listID/members/:memberID", handlers.UpdateMemberRole)
+			authenticated.DELETE("/users/:id/lists/:listID/members/:memberID", handlers.RemoveMember)
+			authenticated.GET("/users/:id/invitations", handlers.GetInvitations)
+			authenticated.POST("/users/:id/invitations/:listID/accept", handlers.AcceptInvitation)
+			authenticated.POST("/users/:id/invitations/:listID/decline", handlers.DeclineInvitation)
+			authenticated.GET("/users/:id/lists/:listID/export", handlers.ExportList)
+			authenticated.GET("/users/:id/export", handlers.ExportAccount)
+			authenticated.POST("/users/:id/import", handlers.ImportPlaces)
+			authenticated.POST("/users/:id/imports/takeout", handlers.StartTakeoutImport)
+			authenticated.GET("/users/:id/imports", handlers.GetImports)
+			authenticated.GET("/users/:id/imports/:importID", handlers.GetImport)
+			authenticated.PATCH("/users/:id/imports/:importID/entries/:entryID", handlers.ReviewImportEntry)
+			authenticated.POST("/users/:id/imports/:importID/commit", handlers.CommitImport)
+			authenticated.DELETE("/users/:id/imports/:importID", handlers.DeleteImport)
 
 			authenticated.POST("/users/:id/visit", handlers.VisitPlace)
 			authenticated.GET("/users/:id/visit", handlers.GetVisitedPlace)
+			authenticated.GET("/users/:id/visit/:osmID", handlers.GetUserPlace(services.VisitedPlaces))
+			authenticated.PUT("/users/:id/visit/:osmID", handlers.ReplaceUserPlace(services.VisitedPlaces))
+			authenticated.PATCH("/users/:id/visit/:osmID", handlers.PatchUserPlace(services.VisitedPlaces))
+			authenticated.DELETE("/users/:id/visit/:osmID", handlers.DeleteUserPlace(services.VisitedPlaces))
+			authenticated.GET("/users/:id/visit/:osmID/visits", handlers.GetVisits)
+			authenticated.POST("/users/:id/visit/:osmID/visits", handlers.AddVisit)
+			authenticated.PATCH("/users/:id/visit/:osmID/visits/:visitID", handlers.UpdateVisit)
+			authenticated.DELETE("/users/:id/visit/:osmID/visits/:visitID", handlers.DeleteVisit)
 
 			authenticated.POST("/users/:id/watch", handlers.WatchPlace)
 			authenticated.GET("/users/:id/watch", handlers.GetWatchedPlace)
-			/*
-				authenticated.POST("/users/:id/ratings", handlers.CreateRating)
-				authenticated.GET("/users/:id/ratings", handlers.GetRatings)
-			*/
+			authenticated.GET("/users/:id/watch/:osmID", handlers.GetUserPlace(services.WatchedPlaces))
+			authenticated.PUT("/users/:id/watch/:osmID", handlers.ReplaceUserPlace(services.WatchedPlaces))
+			authenticated.PATCH("/users/:id/watch/:osmID", handlers.PatchUserPlace(services.WatchedPlaces))
+			authenticated.DELETE("/users/:id/watch/:osmID", handlers.DeleteUserPlace(services.WatchedPlaces))
+
+			authenticated.GET("/places", handlers.SearchPlaces)
+			authenticated.GET("/places/:osmType/:osmID", handlers.GetCatalogPlace)
+			authenticated.GET("/geocode/reverse", handlers.ReverseGeocode)
+
+			authenticated.GET("/users/:id/places", handlers.FindSavedPlaces)
+
+			authenticated.GET("/users/:id/ratings", handlers.GetRatings)
+			authenticated.GET("/users/:id/ratings/:osmID", handlers.GetRating)
+			authenticated.PUT("/users/:id/ratings/:osmID", handlers.SetRating)
+			authenticated.DELETE("/users/:id/ratings/:osmID", handlers.DeleteRating)
+
+			admin := authenticated.Group("/admin")
+			admin.Use(utils.RequireScope(services.ScopeAdmin))
+			{
+				admin.POST("/keys", handlers.CreateAPIKey)
+				admin.GET("/keys", handlers.GetAPIKeys)
+				admin.POST("/keys/:keyID/rotate", handlers.RotateAPIKey)
+				admin.DELETE("/keys/:keyID", handlers.DeleteAPIKey)
+			}
 		}
 	}
+	return nil
 }
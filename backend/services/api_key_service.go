@@ -0,0 +1,202 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// ScopeRead allows read-only access to every user.
+	ScopeRead = "read"
+	// ScopeWrite allows reading and changing every user.
+	ScopeWrite = "write"
+	// ScopeAdmin allows everything, including managing API keys.
+	ScopeAdmin = "admin"
+
+	// LegacyAPIKeyID is the ID of the key returned for the legacy shared key.
+	LegacyAPIKeyID = "legacy"
+
+	apiKeyPrefix = "efk_"
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	bootstrapKeyHash  []byte
+	legacyKeyHash     []byte
+	validAPIKeyScopes = map[string]bool{ScopeRead: true, ScopeWrite: true, ScopeAdmin: true}
+)
+
+// SetBootstrapAdminKey registers a key, normally from the ADMIN_API_KEY
+// environment variable, that is accepted with admin scope without being
+// stored. It exists so the first stored keys can be created.
+func SetBootstrapAdminKey(key string) {
+	if key == "" {
+		bootstrapKeyHash = nil
+		return
+	}
+	sum := sha256.Sum256([]byte(key))
+	bootstrapKeyHash = sum[:]
+}
+
+// SetLegacyAPIKey registers the single shared key, normally from the API_KEY
+// environment variable, that clients sent before there were user tokens and
+// stored keys. It is accepted with the read and write scopes, so it should
+// only be set while clients move to tokens or stored keys. An empty key
+// turns it off.
+func SetLegacyAPIKey(key string) {
+	if key == "" {
+		legacyKeyHash = nil
+		return
+	}
+	sum := sha256.Sum256([]byte(key))
+	legacyKeyHash = sum[:]
+}
+
+// CreateAPIKey stores a new key and returns it together with its secret,
+// which cannot be recovered later.
+func CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*data.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", ValidationError("API key name is required")
+	}
+	if err := validateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ValidationError("expiresAt must be in the future")
+	}
+
+	id, err := randomString(8)
+	if err != nil {
+		return nil, "", err
+	}
+	hash, plaintext, err := newAPIKeySecret(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := data.APIKey{
+		ID:        id,
+		Name:      name,
+		Hash:      hash,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return &key, plaintext, nil
+}
+
+func ListAPIKeys(ctx context.Context) ([]data.APIKey, error) {
+	return repo.ListAPIKeys(ctx)
+}
+
+// RotateAPIKey replaces the key's secret. The old secret stops working
+// immediately.
+func RotateAPIKey(ctx context.Context, keyID string) (*data.APIKey, string, error) {
+	hash, plaintext, err := newAPIKeySecret(keyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := repo.UpdateAPIKey(ctx, keyID, func(key *data.APIKey) error {
+		now := time.Now()
+		key.Hash = hash
+		key.RotatedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+func DeleteAPIKey(ctx context.Context, keyID string) error {
+	return repo.DeleteAPIKey(ctx, keyID)
+}
+
+// AuthenticateAPIKey checks a presented key and returns the stored key it
+// belongs to. Hashes are compared in constant time.
+func AuthenticateAPIKey(ctx context.Context, presented string) (*data.APIKey, error) {
+	presentedHash := sha256.Sum256([]byte(presented))
+	if bootstrapKeyHash != nil && subtle.ConstantTimeCompare(presentedHash[:], bootstrapKeyHash) == 1 {
+		return &data.APIKey{ID: "bootstrap", Name: "ADMIN_API_KEY", Scopes: []string{ScopeAdmin}}, nil
+	}
+	if legacyKeyHash != nil && subtle.ConstantTimeCompare(presentedHash[:], legacyKeyHash) == 1 {
+		return &data.APIKey{ID: LegacyAPIKeyID, Name: "API_KEY", Scopes: []string{ScopeRead, ScopeWrite}}, nil
+	}
+
+	id, _, ok := parseAPIKey(presented)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := repo.GetAPIKey(ctx, id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	storedHash, err := hex.DecodeString(key.Hash)
+	if err != nil || subtle.ConstantTimeCompare(presentedHash[:], storedHash) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+// newAPIKeySecret returns the stored hash and the plaintext of a new key of
+// the form efk_<id>.<secret>.
+func newAPIKeySecret(id string) (string, string, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	plaintext := apiKeyPrefix + id + "." + secret
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:]), plaintext, nil
+}
+
+func parseAPIKey(presented string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(presented, apiKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	id, secret, ok := strings.Cut(rest, ".")
+	if !ok || id == "" || secret == "" {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ValidationError("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !validAPIKeyScopes[scope] {
+			return ValidationError("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// catalogMaxAge is how long a catalog entry is used before the place is
+	// looked up again.
+	catalogMaxAge = 7 * 24 * time.Hour
+	// lookupBatchSize bounds how many places are looked up at once.
+	lookupBatchSize = 100
+	// catalogRefreshTimeout bounds a background lookup of missing entries.
+	catalogRefreshTimeout = 2 * time.Minute
+	// catalogRetryDelay is how long places whose lookup failed are left
+	// alone before reads try again.
+	catalogRetryDelay = time.Minute
+)
+
+// osmElementTypes are the OSM types that a catalog key may name.
+var osmElementTypes = map[string]bool{"node": true, "way": true, "relation": true}
+
+// PlaceLookup fetches OSM places by catalog key, such as from an Overpass
+// server.
+type PlaceLookup interface {
+	// LookupPlaces returns the places with the given keys. Keys of places
+	// that are not in OSM are left out.
+	LookupPlaces(ctx context.Context, keys []string) ([]data.CatalogPlace, error)
+}
+
+var placeLookup PlaceLookup
+
+// SetPlaceLookup sets where the place catalog fetches places it does not
+// hold yet. Without one, places get no details until the catalog is filled
+// some other way.
+func SetPlaceLookup(l PlaceLookup) {
+	placeLookup = l
+}
+
+// PlaceKey returns the catalog key of an OSM place, such as "way/123", or
+// just osmID if osmType is not an OSM type. Wherever the services take an
+// OSM ID they also take a key, which tells apart a node and a way with the
+// same ID; a bare ID matches a place of any type.
+func PlaceKey(osmType string, osmID string) string {
+	if !osmElementTypes[osmType] {
+		return osmID
+	}
+	return osmType + "/" + osmID
+}
+
+// SplitPlaceKey returns the OSM type and ID of a key from PlaceKey. The type
+// is empty if the key is a bare OSM ID.
+func SplitPlaceKey(key string) (string, string) {
+	osmType, osmID, ok := strings.Cut(key, "/")
+	if !ok || !osmElementTypes[osmType] {
+		return "", key
+	}
+	return osmType, osmID
+}
+
+// ValidOsmID reports whether osmID is an OSM element ID, a positive number.
+// IDs end up in Overpass queries, so anything else must not reach a lookup.
+func ValidOsmID(osmID string) bool {
+	id, err := strconv.ParseUint(osmID, 10, 64)
+	return err == nil && id > 0
+}
+
+// validateOsmID checks the OSM ID of a place a user stores.
+func validateOsmID(osmID string) error {
+	if osmID == "" {
+		return ValidationError("missing OsmID in place")
+	}
+	if !ValidOsmID(osmID) {
+		return ValidationError("invalid OSM ID %q", osmID)
+	}
+	return nil
+}
+
+// knownOsmType returns osmType if it is an OSM type, and "" otherwise.
+func knownOsmType(osmType string) string {
+	if !osmElementTypes[osmType] {
+		return ""
+	}
+	return osmType
+}
+
+// SamePlace reports whether two places are the same OSM place. A place
+// without a known type, such as one recorded before types were kept, is
+// taken to be any place with its ID.
+func SamePlace(typeA string, idA string, typeB string, idB string) bool {
+	if idA != idB {
+		return false
+	}
+	return typeA == typeB || !osmElementTypes[typeA] || !osmElementTypes[typeB]
+}
+
+// NewCatalogPlace builds a catalog entry from the tags of an OSM element.
+func NewCatalogPlace(osmType string, osmID string, lat float64, long float64, tags map[string]string) data.CatalogPlace {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return data.CatalogPlace{
+		Key:          PlaceKey(osmType, osmID),
+		OsmType:      osmType,
+		OsmID:        osmID,
+		Name:         tags["name"],
+		Lat:          lat,
+		Long:         long,
+		Cuisine:      tags["cuisine"],
+		Address:      osmAddress(tags),
+		OpeningHours: tags["opening_hours"],
+		Website:      firstTag(tags, "website", "contact:website"),
+		Phone:        firstTag(tags, "phone", "contact:phone"),
+		Tags:         tags,
+	}
+}
+
+// GetCatalogPlace returns the catalog entry of an OSM place, looking the
+// place up first if the catalog does not hold it or holds a stale entry.
+func GetCatalogPlace(ctx context.Context, osmType string, osmID string) (*data.CatalogPlace, error) {
+	if !osmElementTypes[osmType] {
+		return nil, ValidationError("OSM type must be node, way or relation")
+	}
+	if !ValidOsmID(osmID) {
+		return nil, ValidationError("invalid OSM ID %q", osmID)
+	}
+	key := PlaceKey(osmType, osmID)
+
+	var cached *data.CatalogPlace
+	places, err := repo.GetCatalogPlaces(ctx, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	if len(places) > 0 {
+		cached = &places[0]
+		if time.Since(cached.FetchedAt) < catalogMaxAge || placeLookup == nil {
+			return foundPlace(cached)
+		}
+	}
+	if placeLookup == nil {
+		return nil, ErrPlaceNotFound
+	}
+
+	fetched, err := fetchCatalogPlaces(ctx, []string{key})
+	if err != nil {
+		if cached != nil {
+			log.Printf("Error refreshing catalog entry %s, using the cached one: %v", key, err)
+			return foundPlace(cached)
+		}
+		log.Printf("Error looking up place %s: %v", key, err)
+		return nil, ErrPlaceLookupFailed
+	}
+	return foundPlace(&fetched[0])
+}
+
+func foundPlace(place *data.CatalogPlace) (*data.CatalogPlace, error) {
+	if place.Missing {
+		return nil, ErrPlaceNotFound
+	}
+	return place, nil
+}
+
+// fetchCatalogPlaces looks up places and stores them in the catalog. Places
+// that OSM does not have are stored as missing, so they are not looked up
+// again on every read. The entries are returned in the order of keys.
+func fetchCatalogPlaces(ctx context.Context, keys []string) ([]data.CatalogPlace, error) {
+	fetched := make([]data.CatalogPlace, 0, len(keys))
+	for start := 0; start < len(keys); start += lookupBatchSize {
+		batch := keys[start:min(start+lookupBatchSize, len(keys))]
+		places, err := placeLookup.LookupPlaces(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		found := make(map[string]data.CatalogPlace, len(places))
+		for _, place := range places {
+			found[place.Key] = place
+		}
+		now := time.Now()
+		entries := make([]data.CatalogPlace, len(batch))
+		for i, key := range batch {
+			entry, ok := found[key]
+			if !ok {
+				osmType, osmID := SplitPlaceKey(key)
+				entry = data.CatalogPlace{Key: key, OsmType: osmType, OsmID: osmID, Tags: map[string]string{}, Missing: true}
+			}
+			entry.FetchedAt = now
+			entries[i] = entry
+		}
+		if err := repo.SaveCatalogPlaces(ctx, entries); err != nil {
+			return nil, err
+		}
+		fetched = append(fetched, entries...)
+	}
+	return fetched, nil
+}
+
+// catalogRefresher looks up places for the catalog in the background, so
+// reads do not wait for the lookup.
+type catalogRefresher struct {
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+var catalogRefresh = &catalogRefresher{pending: make(map[string]bool)}
+
+// refresh looks up the places with the given keys, skipping those that are
+// already being looked up or whose lookup failed lately.
+func (r *catalogRefresher) refresh(keys []string) {
+	if placeLookup == nil || len(keys) == 0 {
+		return
+	}
+	r.mu.Lock()
+	var queued []string
+	for _, key := range keys {
+		if !r.pending[key] {
+			r.pending[key] = true
+			queued = append(queued, key)
+		}
+	}
+	r.mu.Unlock()
+	if len(queued) == 0 {
+		return
+	}
+
+	release := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, key := range queued {
+			delete(r.pending, key)
+		}
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), catalogRefreshTimeout)
+		defer cancel()
+		if _, err := fetchCatalogPlaces(ctx, queued); err != nil {
+			log.Printf("Error looking up %d places for the catalog: %v", len(queued), err)
+			time.AfterFunc(catalogRetryDelay, release)
+			return
+		}
+		release()
+	}()
+}
+
+// catalogDetails returns the catalog entries of the places with the given
+// keys, and starts a lookup of those the catalog does not hold or holds a
+// stale entry for. Keys that are bare OSM IDs are skipped. Details are a
+// convenience, so a failing catalog is only logged.
+func catalogDetails(ctx context.Context, keys []string) map[string]*data.CatalogPlace {
+	wanted := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if osmType, osmID := SplitPlaceKey(key); osmType != "" && ValidOsmID(osmID) && !seen[key] {
+			seen[key] = true
+			wanted = append(wanted, key)
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	places, err := repo.GetCatalogPlaces(ctx, wanted)
+	if err != nil {
+		log.Printf("Error reading %d catalog entries: %v", len(wanted), err)
+		return nil
+	}
+	details := make(map[string]*data.CatalogPlace, len(places))
+	var stale []string
+	for i := range places {
+		delete(seen, places[i].Key)
+		if time.Since(places[i].FetchedAt) >= catalogMaxAge {
+			stale = append(stale, places[i].Key)
+		}
+		if !places[i].Missing {
+			details[places[i].Key] = &places[i]
+		}
+	}
+	for _, key := range wanted {
+		if seen[key] {
+			stale = append(stale, key)
+		}
+	}
+	catalogRefresh.refresh(stale)
+	return details
+}
+
+// fillListDetails fills in the catalog details of the entries of lists.
+func fillListDetails(ctx context.Context, lists []data.List) {
+	var entries []*data.Place
+	for i := range lists {
+		entries = append(entries, listEntries(lists[i].Places)...)
+	}
+	fillPlaceDetails(ctx, entries...)
+}
+
+// fillPlaceDetails fills in the catalog details of list entries.
+func fillPlaceDetails(ctx context.Context, places ...*data.Place) {
+	keys := make([]string, len(places))
+	for i, place := range places {
+		keys[i] = PlaceKey(place.OsmType, place.OsmID)
+	}
+	details := catalogDetails(ctx, keys)
+	for i, place := range places {
+		place.Details = details[keys[i]]
+	}
+}
+
+func listEntries(places []data.Place) []*data.Place {
+	entries := make([]*data.Place, len(places))
+	for i := range places {
+		entries[i] = &places[i]
+	}
+	return entries
+}
+
+// fillUserPlaceDetails fills in the catalog details of visited or watched
+// places.
+func fillUserPlaceDetails(ctx context.Context, places []data.UserPlace) {
+	keys := make([]string, len(places))
+	for i, place := range places {
+		keys[i] = PlaceKey(place.OsmType, place.OsmID)
+	}
+	details := catalogDetails(ctx, keys)
+	for i := range places {
+		places[i].Details = details[keys[i]]
+	}
+}
+
+// userPlaceDetails returns the catalog details of a visited or watched place.
+func userPlaceDetails(ctx context.Context, place *data.UserPlace) *data.CatalogPlace {
+	key := PlaceKey(place.OsmType, place.OsmID)
+	return catalogDetails(ctx, []string{key})[key]
+}
+
+// osmAddress formats the addr:* tags of a place as a single line.
+func osmAddress(tags map[string]string) string {
+	if full := tags["addr:full"]; full != "" {
+		return full
+	}
+	street := strings.TrimSpace(tags["addr:street"] + " " + tags["addr:housenumber"])
+	city := strings.TrimSpace(tags["addr:postcode"] + " " + tags["addr:city"])
+	switch {
+	case street == "":
+		return city
+	case city == "":
+		return street
+	}
+	return street + ", " + city
+}
+
+func firstTag(tags map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := tags[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package services
+
+import "fmt"
+
+// ErrorKind classifies domain errors so callers can react to them without
+// comparing messages.
+type ErrorKind int
+
+const (
+	KindNotFound ErrorKind = iota + 1
+	KindAlreadyExists
+	KindValidation
+	KindConflict
+	KindForbidden
+	// KindUnavailable errors come from a service the server depends on, such
+	// as an OSM server, being unreachable.
+	KindUnavailable
+	// KindRateLimited errors ask the client to slow down.
+	KindRateLimited
+)
+
+// Error is a domain error returned by the services and repositories. Code is
+// a stable, machine-readable identifier such as "user_not_found".
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrUserNotFound        = &Error{Kind: KindNotFound, Code: "user_not_found", Message: "user not found"}
+	ErrUserAlreadyExists   = &Error{Kind: KindAlreadyExists, Code: "user_already_exists", Message: "user already exists"}
+	ErrListNotFound        = &Error{Kind: KindNotFound, Code: "list_not_found", Message: "list not found"}
+	ErrListNameTaken       = &Error{Kind: KindAlreadyExists, Code: "list_name_taken", Message: "a list with this name already exists"}
+	ErrPlaceNotFound       = &Error{Kind: KindNotFound, Code: "place_not_found", Message: "place not found"}
+	ErrPlaceExists         = &Error{Kind: KindAlreadyExists, Code: "place_already_exists", Message: "place already added"}
+	ErrVisitNotFound       = &Error{Kind: KindNotFound, Code: "visit_not_found", Message: "visit not found"}
+	ErrListAccessDenied    = &Error{Kind: KindForbidden, Code: "list_access_denied", Message: "your role on this list does not allow this"}
+	ErrMemberNotFound      = &Error{Kind: KindNotFound, Code: "member_not_found", Message: "list member not found"}
+	ErrMemberExists        = &Error{Kind: KindAlreadyExists, Code: "member_already_invited", Message: "user is already invited to this list"}
+	ErrInvitationNotFound  = &Error{Kind: KindNotFound, Code: "invitation_not_found", Message: "invitation not found"}
+	ErrImportNotFound      = &Error{Kind: KindNotFound, Code: "import_not_found", Message: "import not found"}
+	ErrImportEntryNotFound = &Error{Kind: KindNotFound, Code: "import_entry_not_found", Message: "import entry not found"}
+	ErrAPIKeyNotFound      = &Error{Kind: KindNotFound, Code: "api_key_not_found", Message: "API key not found"}
+	ErrAPIKeyExists        = &Error{Kind: KindAlreadyExists, Code: "api_key_already_exists", Message: "API key already exists"}
+	ErrRateLimited         = &Error{Kind: KindRateLimited, Code: "rate_limited", Message: "too many requests, please slow down"}
+	ErrPlaceLookupFailed   = &Error{Kind: KindUnavailable, Code: "place_lookup_failed", Message: "place details could not be fetched from OSM, please retry later"}
+	ErrPlacesUnavailable   = &Error{Kind: KindUnavailable, Code: "places_unavailable", Message: "places could not be fetched from OSM, please retry later"}
+	ErrLocationNotFound    = &Error{Kind: KindNotFound, Code: "location_not_found", Message: "location not found"}
+	ErrGeocoderBusy        = &Error{Kind: KindRateLimited, Code: "geocoder_busy", Message: "too many location lookups, please retry later"}
+	ErrGeocodingFailed     = &Error{Kind: KindUnavailable, Code: "geocoding_failed", Message: "location could not be looked up, please retry later"}
+	// ErrConflict is returned when an update keeps colliding with concurrent
+	// writes to the same document and the backend gives up retrying.
+	ErrConflict = &Error{Kind: KindConflict, Code: "conflict", Message: "data was modified concurrently, please retry"}
+)
+
+// ValidationError reports input that is well-formed but not acceptable.
+func ValidationError(format string, args ...any) error {
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Event types. The data of list events is the list as the receiving
+	// user sees it, or a ListRef once it is deleted or no longer shared with
+	// the user. The data of visit and watch events is the place, or a
+	// PlaceRef once it is deleted.
+	EventListCreated       = "list.created"
+	EventListUpdated       = "list.updated"
+	EventListDeleted       = "list.deleted"
+	EventVisitUpdated      = "visit.updated"
+	EventVisitDeleted      = "visit.deleted"
+	EventWatchUpdated      = "watch.updated"
+	EventWatchDeleted      = "watch.deleted"
+	EventInvitationCreated = "invitation.created"
+	// EventReset tells a resuming client that events were missed, such as
+	// after a restart or a long disconnect, and it has to reload its data.
+	EventReset = "reset"
+
+	// maxBufferedEvents is how many recent events are kept per user for
+	// clients that reconnect.
+	maxBufferedEvents = 200
+	// eventRetention is how long the events of a user without subscribers
+	// are kept for a client to resume.
+	eventRetention = 10 * time.Minute
+	// subscriberBuffer is how many events a subscriber may fall behind
+	// before it is dropped and has to resume.
+	subscriberBuffer = 64
+)
+
+// Event is a change to a user's data. IDs increase over time, across users
+// and restarts, so a client can resume from the last ID it saw.
+type Event struct {
+	ID   uint64
+	Type string
+	Data any
+}
+
+// ListRef identifies a list in events about deleted lists.
+type ListRef struct {
+	OwnerID string `json:"owner_id"`
+	ListID  string `json:"id"`
+}
+
+// PlaceRef identifies a visited or watched place in events about deleted
+// places.
+type PlaceRef struct {
+	OsmID   string `json:"osmID"`
+	OsmType string `json:"osmType,omitempty"`
+}
+
+// eventBroker fans out events to the subscribers of each user. It lives in
+// memory, so subscribers only hear about changes made through the same
+// server instance.
+type eventBroker struct {
+	mu        sync.Mutex
+	firstID   uint64
+	nextID    uint64
+	users     map[string]*userEvents
+	lastPrune time.Time
+}
+
+type userEvents struct {
+	// events holds the most recent events, oldest first. dropped is the ID
+	// of the newest event that no longer fits.
+	events      []Event
+	dropped     uint64
+	subscribers map[chan Event]struct{}
+	lastActive  time.Time
+}
+
+var events = newEventBroker()
+
+func newEventBroker() *eventBroker {
+	// Starting from the clock keeps IDs increasing across restarts.
+	first := uint64(time.Now().UnixMicro())
+	return &eventBroker{firstID: first, nextID: first, users: make(map[string]*userEvents)}
+}
+
+// SubscribeEvents streams the changes to a user's data. With lastEventID
+// set, the events after it that are still buffered are returned first, or a
+// reset event if some were missed. The channel is closed when the
+// subscriber falls too far behind; cancel must be called once done.
+func SubscribeEvents(ctx context.Context, userID string, lastEventID string) ([]Event, <-chan Event, func(), error) {
+	if _, err := repo.GetLists(ctx, userID); err != nil {
+		return nil, nil, nil, err
+	}
+	backlog, ch, cancel := events.subscribe(userID, lastEventID)
+	return backlog, ch, cancel, nil
+}
+
+func (b *eventBroker) subscribe(userID string, lastEventID string) ([]Event, <-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	user := b.user(userID)
+	ch := make(chan Event, subscriberBuffer)
+	user.subscribers[ch] = struct{}{}
+	user.lastActive = time.Now()
+
+	var backlog []Event
+	if lastEventID != "" {
+		lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil || lastID+1 < b.firstID || lastID >= b.nextID || lastID < user.dropped {
+			backlog = []Event{{ID: b.nextID - 1, Type: EventReset}}
+		} else {
+			for _, event := range user.events {
+				if event.ID > lastID {
+					backlog = append(backlog, event)
+				}
+			}
+		}
+	}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := user.subscribers[ch]; ok {
+			delete(user.subscribers, ch)
+			close(ch)
+		}
+		user.lastActive = time.Now()
+	}
+	return backlog, ch, cancel
+}
+
+// publish sends an event to the user's subscribers. Events for users who
+// have not subscribed lately are dropped, as there is no one to resume them,
+// but still take an ID, so that a client resuming from before them is reset.
+func (b *eventBroker) publish(userID string, eventType string, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prune()
+	event := Event{ID: b.nextID, Type: eventType, Data: data}
+	b.nextID++
+	user, ok := b.users[userID]
+	if !ok {
+		return
+	}
+
+	if len(user.events) == maxBufferedEvents {
+		user.dropped = user.events[0].ID
+		user.events = append(user.events[:0:0], user.events[1:]...)
+	}
+	user.events = append(user.events, event)
+
+	for ch := range user.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Too slow; closing the channel ends the stream, and the client
+			// resumes from the last event it got.
+			delete(user.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// user returns the user's entry, creating it if needed. A new entry holds
+// none of the events before it, so a client resuming from before it is
+// reset. It must be called with b.mu held.
+func (b *eventBroker) user(userID string) *userEvents {
+	user, ok := b.users[userID]
+	if !ok {
+		user = &userEvents{dropped: b.nextID - 1, subscribers: make(map[chan Event]struct{})}
+		b.users[userID] = user
+	}
+	return user
+}
+
+// prune forgets users who have had no subscribers for eventRetention. It
+// must be called with b.mu held.
+func (b *eventBroker) prune() {
+	now := time.Now()
+	if now.Sub(b.lastPrune) < time.Minute {
+		return
+	}
+	b.lastPrune = now
+	for userID, user := range b.users {
+		if len(user.subscribers) == 0 && now.Sub(user.lastActive) > eventRetention {
+			delete(b.users, userID)
+		}
+	}
+}
+
+// The publish functions below are called after every successful change to
+// a list or place. Besides publishing an event they record the change for
+// sync, and return an error if it could not be recorded for every user it
+// concerns.
+
+// publishList tells the owner and the accepted members of a list about a
+// change to it, each with their own role.
+func publishList(ctx context.Context, ownerID string, eventType string, list *data.List) error {
+	fillPlaceDetails(ctx, listEntries(list.Places)...)
+	owned := *list
+	owned.OwnerID, owned.Role = ownerID, RoleOwner
+	owned.Places = append([]data.Place{}, list.Places...)
+	numberPlaces(&owned)
+	events.publish(ownerID, eventType, owned)
+	recordErr := recordChange(ctx, ownerID, ChangeList, list.ID, false)
+
+	members, err := repo.GetListMembers(ctx, ownerID, list.ID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.Status != MemberAccepted {
+			continue
+		}
+		if err := publishSharedList(ctx, member.UserID, ownerID, member.Role, *list, eventType); err != nil && recordErr == nil {
+			recordErr = err
+		}
+	}
+	return recordErr
+}
+
+// publishSharedList tells a member about a list shared with them.
+func publishSharedList(ctx context.Context, userID string, ownerID string, role string, list data.List, eventType string) error {
+	setListRole(&list, ownerID, role)
+	list.Places = append([]data.Place{}, list.Places...)
+	numberPlaces(&list)
+	events.publish(userID, eventType, list)
+	return recordChange(ctx, userID, ChangeList, list.ID, false)
+}
+
+// publishListDeleted tells the owner and the given members that a list is
+// gone. The members have to be read before the list is deleted, as they are
+// deleted with it.
+func publishListDeleted(ctx context.Context, ownerID string, listID string, members []data.ListMember) error {
+	recordErr := publishListRemoved(ctx, ownerID, ownerID, listID)
+	for _, member := range members {
+		if member.Status != MemberAccepted {
+			continue
+		}
+		if err := publishListRemoved(ctx, member.UserID, ownerID, listID); err != nil && recordErr == nil {
+			recordErr = err
+		}
+	}
+	return recordErr
+}
+
+// publishListRemoved tells a user that a list is gone or no longer shared
+// with them.
+func publishListRemoved(ctx context.Context, userID string, ownerID string, listID string) error {
+	events.publish(userID, EventListDeleted, ListRef{OwnerID: ownerID, ListID: listID})
+	return recordChange(ctx, userID, ChangeList, listID, true)
+}
+
+func publishUserPlace(ctx context.Context, userID string, kind PlaceKind, place *data.UserPlace) error {
+	eventType := EventWatchUpdated
+	if kind == VisitedPlaces {
+		eventType = EventVisitUpdated
+	}
+	place.Details = userPlaceDetails(ctx, place)
+	events.publish(userID, eventType, *place)
+	return recordChange(ctx, userID, changeKind(kind), PlaceKey(place.OsmType, place.OsmID), false)
+}
+
+func publishUserPlaceDeleted(ctx context.Context, userID string, kind PlaceKind, osmType string, osmID string) error {
+	eventType := EventWatchDeleted
+	if kind == VisitedPlaces {
+		eventType = EventVisitDeleted
+	}
+	events.publish(userID, eventType, PlaceRef{OsmID: osmID, OsmType: osmType})
+	return recordChange(ctx, userID, changeKind(kind), PlaceKey(osmType, osmID), true)
+}
+
+// updateList wraps repo.UpdateList and publishes the updated list.
+func updateList(ctx context.Context, ownerID string, listID string, update func(list *data.List) error) (*data.List, error) {
+	list, err := repo.UpdateList(ctx, ownerID, listID, update)
+	if err != nil {
+		return nil, err
+	}
+	if err := publishList(ctx, ownerID, EventListUpdated, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEventBrokerResume(t *testing.T) {
+	b := newEventBroker()
+	_, ch, cancel := b.subscribe("u1", "")
+	b.publish("u1", EventListUpdated, nil)
+	b.publish("u1", EventListUpdated, nil)
+	first, second := <-ch, <-ch
+	cancel()
+
+	backlog, _, cancel := b.subscribe("u1", strconv.FormatUint(first.ID, 10))
+	cancel()
+	if len(backlog) != 1 || backlog[0].ID != second.ID {
+		t.Errorf("backlog after %d = %+v, want event %d", first.ID, backlog, second.ID)
+	}
+	backlog, _, cancel = b.subscribe("u1", strconv.FormatUint(second.ID, 10))
+	cancel()
+	if len(backlog) != 0 {
+		t.Errorf("backlog after the last event = %+v, want none", backlog)
+	}
+}
+
+func TestEventBrokerResumeAfterPrune(t *testing.T) {
+	b := newEventBroker()
+	_, ch, cancel := b.subscribe("u1", "")
+	b.publish("u1", EventListUpdated, nil)
+	seen := <-ch
+	cancel()
+
+	// The user's entry expires, and a change made meanwhile reaches no one.
+	b.users["u1"].lastActive = time.Now().Add(-eventRetention - time.Minute)
+	b.lastPrune = time.Time{}
+	b.publish("u1", EventListUpdated, nil)
+	if _, ok := b.users["u1"]; ok {
+		t.Fatal("user entry was not pruned")
+	}
+
+	backlog, _, cancel := b.subscribe("u1", strconv.FormatUint(seen.ID, 10))
+	defer cancel()
+	if len(backlog) != 1 || backlog[0].Type != EventReset {
+		t.Errorf("backlog after the entry was pruned = %+v, want a reset", backlog)
+	}
+}
+
+func TestEventBrokerResumeOfNewEntry(t *testing.T) {
+	b := newEventBroker()
+	b.publish("u2", EventListUpdated, nil)
+
+	// Nothing happened since the client's last event, so there is nothing
+	// to reload.
+	backlog, _, cancel := b.subscribe("u1", strconv.FormatUint(b.nextID-1, 10))
+	cancel()
+	if len(backlog) != 0 {
+		t.Errorf("backlog of an up to date client = %+v, want none", backlog)
+	}
+
+	backlog, _, cancel = b.subscribe("u3", strconv.FormatUint(b.nextID-2, 10))
+	cancel()
+	if len(backlog) != 1 || backlog[0].Type != EventReset {
+		t.Errorf("backlog of a client resuming from before the entry = %+v, want a reset", backlog)
+	}
+}
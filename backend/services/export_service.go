@@ -0,0 +1,114 @@
+package services
+
+import (
+	"backend/data"
+	"backend/formats"
+	"context"
+)
+
+const (
+	visitedCollection = "visited"
+	watchedCollection = "watched"
+)
+
+// ExportList returns the places of a list as features, together with the
+// list name. Places the user has visited carry their rating, tags and visit
+// dates.
+func ExportList(ctx context.Context, userID string, listRef string) (string, []formats.Feature, error) {
+	_, list, err := resolveListAccess(ctx, userID, listRef, RoleViewer)
+	if err != nil {
+		return "", nil, err
+	}
+	visited, err := GetUserPlaces(ctx, userID, VisitedPlaces)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return list.ListName, listFeatures(*list, visited), nil
+}
+
+// ExportAccount returns every list entry, visited place and watched place of
+// the user as features. Visited and watched places take their coordinates
+// from a list holding the same place, if there is one.
+func ExportAccount(ctx context.Context, userID string) ([]formats.Feature, error) {
+	user, err := GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make(map[string]data.Place)
+	features := []formats.Feature{}
+	for _, list := range user.Lists {
+		// Lists shared with the user belong to their owner's account.
+		if list.OwnerID != userID {
+			continue
+		}
+		for _, place := range list.Places {
+			if _, ok := locations[place.OsmID]; !ok {
+				locations[place.OsmID] = place
+			}
+		}
+		features = append(features, listFeatures(list, user.VisitedPlaces)...)
+	}
+	for _, place := range user.VisitedPlaces {
+		features = append(features, userPlaceFeature(visitedCollection, place, locations))
+	}
+	for _, place := range user.WatchedPlaces {
+		features = append(features, userPlaceFeature(watchedCollection, place, locations))
+	}
+	return features, nil
+}
+
+func listFeatures(list data.List, visited []data.UserPlace) []formats.Feature {
+	features := make([]formats.Feature, len(list.Places))
+	for i, place := range list.Places {
+		feature := formats.Feature{
+			Collection:  list.ListName,
+			Name:        osmName(place.OsmType, place.OsmID),
+			OsmID:       place.OsmID,
+			OsmType:     place.OsmType,
+			HasLocation: true,
+			Lat:         place.Lat,
+			Lon:         place.Long,
+			Note:        place.Note,
+			AddedAt:     place.AddedAt,
+		}
+		if userPlace := findUserPlaceById(visited, PlaceKey(place.OsmType, place.OsmID)); userPlace != nil {
+			addUserPlaceDetails(&feature, *userPlace)
+		}
+		features[i] = feature
+	}
+	return features
+}
+
+func userPlaceFeature(collection string, place data.UserPlace, locations map[string]data.Place) formats.Feature {
+	feature := formats.Feature{Collection: collection, OsmID: place.OsmID}
+	if location, ok := locations[place.OsmID]; ok {
+		feature.OsmType = location.OsmType
+		feature.HasLocation = true
+		feature.Lat = location.Lat
+		feature.Lon = location.Long
+	}
+	feature.Name = osmName(feature.OsmType, place.OsmID)
+	addUserPlaceDetails(&feature, place)
+	return feature
+}
+
+func addUserPlaceDetails(feature *formats.Feature, place data.UserPlace) {
+	feature.Tags = place.Tags
+	feature.Rating = place.Rating
+	feature.VisitCount = place.VisitCount
+	feature.LastVisitedAt = place.LastVisitedAt
+	for _, visit := range place.Visits {
+		feature.VisitDates = append(feature.VisitDates, visit.VisitedAt)
+	}
+}
+
+// osmName names a place after its OSM element, such as "node/123", as
+// places carry no name of their own.
+func osmName(osmType string, osmID string) string {
+	if osmType == "" {
+		return osmID
+	}
+	return osmType + "/" + osmID
+}
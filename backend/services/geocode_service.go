@@ -0,0 +1,277 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// locationMaxAge is how long a cached location is used before the point
+	// is looked up again. Places rarely change city.
+	locationMaxAge = 90 * 24 * time.Hour
+	// geocodeEvery spaces out the requests to the geocoder across all
+	// users, as the public Nominatim instance allows one per second.
+	geocodeEvery = time.Second
+	// geocodeWait bounds how long a request waits for its turn at the
+	// geocoder before it gives up.
+	geocodeWait = 5 * time.Second
+	// locateBatchSize bounds the places located in one background pass over
+	// a list, so that lists take turns and progress is saved along the way.
+	locateBatchSize = 50
+	// locateTimeout bounds a background pass over a list.
+	locateTimeout = 5 * time.Minute
+)
+
+// ReverseGeocoder finds what is at a point, such as on a Nominatim server.
+type ReverseGeocoder interface {
+	// ReverseGeocode returns the location at a point. Where nothing is
+	// found, such as at sea, it returns an empty location.
+	ReverseGeocode(ctx context.Context, lat float64, long float64) (data.Location, error)
+}
+
+var (
+	reverseGeocoder ReverseGeocoder
+	geocodeLimiter  = rate.NewLimiter(rate.Every(geocodeEvery), 1)
+)
+
+// SetReverseGeocoder sets where locations that are not cached are looked
+// up. Without one, only cached locations are found and saved places are
+// not located.
+func SetReverseGeocoder(g ReverseGeocoder) {
+	reverseGeocoder = g
+}
+
+// ReverseGeocode returns the location at a point. Locations are cached by
+// rounded coordinates, so nearby points share an entry. When the geocoder
+// fails or is busy, a stale entry is returned if there is one.
+func ReverseGeocode(ctx context.Context, lat float64, long float64) (*data.Location, error) {
+	if !finite(lat, long) || lat < -90 || lat > 90 || long < -180 || long > 180 {
+		return nil, ValidationError("lat must lie within -90 to 90 and long within -180 to 180")
+	}
+	return locate(ctx, lat, long, geocodeWait)
+}
+
+// locate returns the cached location at a point, looking it up first if the
+// cache holds none or a stale one. It waits at most wait for its turn at
+// the geocoder.
+func locate(ctx context.Context, lat float64, long float64, wait time.Duration) (*data.Location, error) {
+	key, lat, long := locationKey(lat, long)
+	cached, err := repo.GetLocation(ctx, key)
+	if err != nil && !errors.Is(err, ErrLocationNotFound) {
+		return nil, err
+	}
+	if cached != nil && (time.Since(cached.FetchedAt) < locationMaxAge || reverseGeocoder == nil) {
+		return cached, nil
+	}
+	if reverseGeocoder == nil {
+		return nil, ErrLocationNotFound
+	}
+
+	fetched, err := fetchLocation(ctx, key, lat, long, wait)
+	if err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return fetched, nil
+}
+
+func fetchLocation(ctx context.Context, key string, lat float64, long float64, wait time.Duration) (*data.Location, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	if err := geocodeLimiter.Wait(waitCtx); err != nil {
+		return nil, ErrGeocoderBusy
+	}
+
+	location, err := reverseGeocoder.ReverseGeocode(ctx, lat, long)
+	if err != nil {
+		log.Printf("Error reverse geocoding %s: %v", key, err)
+		return nil, ErrGeocodingFailed
+	}
+	location.Key, location.Lat, location.Long, location.FetchedAt = key, lat, long, time.Now()
+	if err := repo.SaveLocation(ctx, location); err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// locationKey rounds a point to three decimals, about 100 m, and returns
+// its cache key along with the rounded coordinates.
+func locationKey(lat float64, long float64) (string, float64, float64) {
+	lat, long = roundCoordinate(lat), roundCoordinate(long)
+	return strconv.FormatFloat(lat, 'f', 3, 64) + "," + strconv.FormatFloat(long, 'f', 3, 64), lat, long
+}
+
+func roundCoordinate(value float64) float64 {
+	rounded := math.Round(value*1000) / 1000
+	if rounded == 0 {
+		// Keeps "-0.000" out of keys.
+		return 0
+	}
+	return rounded
+}
+
+// placeLocator fills in where saved list places are in the background, so
+// saving a place does not wait for the geocoder. Lists are located one at a
+// time, which keeps a large import from crowding out interactive lookups.
+type placeLocator struct {
+	mu      sync.Mutex
+	queue   []ListRef
+	queued  map[ListRef]bool
+	running bool
+}
+
+var placeLocations = &placeLocator{queued: make(map[ListRef]bool)}
+
+// errNothingLocated leaves a list unchanged when its places were edited
+// while they were being located.
+var errNothingLocated = errors.New("no places to locate")
+
+// locate queues a list whose places may lack a location.
+func (l *placeLocator) locate(ownerID string, listID string) {
+	if reverseGeocoder == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ref := ListRef{OwnerID: ownerID, ListID: listID}
+	if l.queued[ref] {
+		return
+	}
+	l.queued[ref] = true
+	l.queue = append(l.queue, ref)
+	if !l.running {
+		l.running = true
+		go l.run()
+	}
+}
+
+func (l *placeLocator) run() {
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			l.running = false
+			l.mu.Unlock()
+			return
+		}
+		ref := l.queue[0]
+		l.queue = l.queue[1:]
+		delete(l.queued, ref)
+		l.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), locateTimeout)
+		more, err := locateListPlaces(ctx, ref.OwnerID, ref.ListID)
+		cancel()
+		if err != nil {
+			log.Printf("Error locating the places of list %s of user %s: %v", ref.ListID, ref.OwnerID, err)
+			continue
+		}
+		if more {
+			l.locate(ref.OwnerID, ref.ListID)
+		}
+	}
+}
+
+// locateListPlaces fills in the location of up to locateBatchSize places of
+// a list that have none, and reports whether more are left. Places where
+// nothing is found do not count towards the batch, and as their empty
+// location is cached they cost no lookup the next time.
+func locateListPlaces(ctx context.Context, ownerID string, listID string) (bool, error) {
+	list, err := repo.GetList(ctx, ownerID, listID)
+	if errors.Is(err, ErrListNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	found := make(map[string]*data.Location)
+	located, more := 0, false
+	for _, place := range list.Places {
+		if !needsLocation(place) {
+			continue
+		}
+		key, _, _ := locationKey(place.Lat, place.Long)
+		if _, ok := found[key]; ok {
+			continue
+		}
+		if located == locateBatchSize {
+			more = true
+			break
+		}
+		location, err := locate(ctx, place.Lat, place.Long, locateTimeout)
+		if err != nil {
+			if located == 0 {
+				return false, err
+			}
+			// Save what was found, the rest waits for the next change.
+			break
+		}
+		found[key] = location
+		if locationFound(location) {
+			located++
+		}
+	}
+	if located == 0 {
+		return false, nil
+	}
+
+	_, err = updateList(ctx, ownerID, listID, func(list *data.List) error {
+		changed := false
+		for i := range list.Places {
+			place := &list.Places[i]
+			if !needsLocation(*place) {
+				continue
+			}
+			key, _, _ := locationKey(place.Lat, place.Long)
+			if location := found[key]; location != nil && locationFound(location) {
+				place.City, place.Neighbourhood, place.Country = location.City, location.Neighbourhood, location.Country
+				changed = true
+			}
+		}
+		if !changed {
+			return errNothingLocated
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errNothingLocated) && !errors.Is(err, ErrListNotFound) {
+		return false, err
+	}
+	return more, nil
+}
+
+// needsLocation reports whether a list place has coordinates but no
+// location yet.
+func needsLocation(place data.Place) bool {
+	if place.City != "" || place.Neighbourhood != "" || place.Country != "" {
+		return false
+	}
+	if place.Lat == 0 && place.Long == 0 {
+		return false
+	}
+	return place.Lat >= -90 && place.Lat <= 90 && place.Long >= -180 && place.Long <= 180
+}
+
+func locationFound(location *data.Location) bool {
+	return location.City != "" || location.Neighbourhood != "" || location.Country != ""
+}
+
+// locateNewPlaces queues a list for locating if any of places needs it.
+func locateNewPlaces(ownerID string, listID string, places []data.Place) {
+	for _, place := range places {
+		if needsLocation(place) {
+			placeLocations.locate(ownerID, listID)
+			return
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package services_test
+
+import (
+	"backend/services"
+	"testing"
+)
+
+func TestReverseGeocodeRejectsInvalidPoints(t *testing.T) {
+	ctx := newUser(t, "u1")
+	rejectsAll(t, invalidPoints, func(point services.GeoPoint) error {
+		_, err := services.ReverseGeocode(ctx, point.Lat, point.Lon)
+		return err
+	})
+}
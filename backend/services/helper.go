@@ -2,35 +2,18 @@ package services
 
 import (
 	"backend/data"
-	"backend/utils"
-	"context"
-	"errors"
-
-	"cloud.google.com/go/firestore"
+	"math"
 )
 
-func getUserByID(ctx context.Context, userID string) (*data.User, *firestore.DocumentSnapshot, error) {
-	query := utils.FirestoreClient.Collection("users").Where("ID", "==", userID).Limit(1)
-	docs, err := query.Documents(ctx).GetAll()
-	if err != nil {
-		return nil, nil, err
-	}
-	if len(docs) == 0 {
-		return nil, nil, errors.New("user not found")
-	}
-
-	docSnap := docs[0]
-	var user data.User
-	if err := docSnap.DataTo(&user); err != nil {
-		return nil, nil, err
+// finite reports whether none of values is NaN or infinite. Range checks
+// need it first, as every comparison with NaN is false.
+func finite(values ...float64) bool {
+	for _, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return false
+		}
 	}
-
-	return &user, docSnap, nil
-}
-
-func saveUser(ctx context.Context, user *data.User, docSnap *firestore.DocumentSnapshot) error {
-	_, err := utils.FirestoreClient.Collection("users").Doc(docSnap.Ref.ID).Set(ctx, user)
-	return err
+	return true
 }
 
 func findListByName(lists []data.List, listName string) (int, *data.List) {
@@ -42,31 +25,44 @@ func findListByName(lists []data.List, listName string) (int, *data.List) {
 	return -1, nil
 }
 
-func findVisitById(visitedPlaces []data.UserPlace, osmID string) *data.UserPlace {
-	for i, place := range visitedPlaces {
-		if place.OsmID == osmID {
-			return &visitedPlaces[i]
+// findUserPlaceById finds the place named by key, an OSM ID or a key from
+// PlaceKey.
+func findUserPlaceById(places []data.UserPlace, key string) *data.UserPlace {
+	osmType, osmID := SplitPlaceKey(key)
+	for i, place := range places {
+		if SamePlace(place.OsmType, place.OsmID, osmType, osmID) {
+			return &places[i]
 		}
 	}
 	return nil
 }
 
-func findWatchedById(watchedPlaces []data.UserPlace, osmID string) *data.UserPlace {
-	for i, place := range watchedPlaces {
-		if place.OsmID == osmID {
-			return &watchedPlaces[i]
+func findVisitIndex(visits []data.Visit, visitID string) int {
+	for i, visit := range visits {
+		if visit.ID == visitID {
+			return i
 		}
 	}
-	return nil
+	return -1
 }
 
-/*
-func findRatingById(ratings []data.Rating, osmID string) (int, *data.Rating) {
-	for i, rating := range ratings {
-		if rating.OsmID == osmID {
-			return i, &ratings[i]
+// findListPlace returns the index of the entry named by key, an OSM ID or a
+// key from PlaceKey, or -1.
+func findListPlace(places []data.Place, key string) int {
+	osmType, osmID := SplitPlaceKey(key)
+	for i, place := range places {
+		if SamePlace(place.OsmType, place.OsmID, osmType, osmID) {
+			return i
 		}
 	}
-	return -1, nil
+	return -1
+}
+
+func findImportEntry(entries []data.ImportEntry, entryID int) int {
+	for i, entry := range entries {
+		if entry.ID == entryID {
+			return i
+		}
+	}
+	return -1
 }
-*/
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"backend/data"
+	"backend/formats"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxImportFeatures caps how many places a single import may hold.
+const MaxImportFeatures = 2000
+
+// ImportResult reports what an import did. Rows are numbered as in the
+// imported file.
+type ImportResult struct {
+	ListID      string             `json:"listId"`
+	ListName    string             `json:"listName"`
+	ListCreated bool               `json:"listCreated"`
+	Added       int                `json:"added"`
+	Duplicates  []int              `json:"duplicates"`
+	Errors      []formats.RowError `json:"errors"`
+}
+
+// ImportPlaces adds features read from a file to the list called listName,
+// creating it if the user has no such list. Features without an OSM
+// element reference cannot be matched to a place and are reported as row
+// errors, as are places already in the list or earlier in the file.
+func ImportPlaces(ctx context.Context, userID string, listName string, features []formats.Feature, rowErrors []formats.RowError, addedBy string) (*ImportResult, error) {
+	listName = strings.TrimSpace(listName)
+	if listName == "" {
+		return nil, ValidationError("list name is required")
+	}
+	if len(features) > MaxImportFeatures {
+		return nil, ValidationError("a file may hold at most %d places", MaxImportFeatures)
+	}
+
+	result := &ImportResult{ListName: listName, Duplicates: []int{}, Errors: append([]formats.RowError{}, rowErrors...)}
+	now := time.Now()
+	var places []data.Place
+	var rows []int
+	for _, feature := range features {
+		if feature.OsmID == "" {
+			result.Errors = append(result.Errors, formats.RowError{Row: feature.Row, Message: "no OSM element reference to match the place with"})
+			continue
+		}
+		if !ValidOsmID(feature.OsmID) {
+			result.Errors = append(result.Errors, formats.RowError{Row: feature.Row, Message: fmt.Sprintf("invalid OSM ID %q", feature.OsmID)})
+			continue
+		}
+		place := data.Place{
+			OsmID:   feature.OsmID,
+			OsmType: feature.OsmType,
+			Long:    feature.Lon,
+			Lat:     feature.Lat,
+			Note:    importNote(feature),
+			AddedAt: &now,
+			AddedBy: addedBy,
+		}
+		places = append(places, place)
+		rows = append(rows, feature.Row)
+	}
+
+	if err := appendImported(ctx, userID, result, places, rows); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// appendImported adds places to the list named in result, creating the
+// list if needed, and records which rows were added, already present or
+// invalid. rows gives the row of each place. Places go through the same
+// checks and writes as AppendPlace, but in a single write per list.
+func appendImported(ctx context.Context, userID string, result *ImportResult, places []data.Place, rows []int) error {
+	var valid []data.Place
+	var validRows []int
+	for i, place := range places {
+		if err := validateListPlace(place); err != nil {
+			result.Errors = append(result.Errors, formats.RowError{Row: rows[i], Message: err.Error()})
+			continue
+		}
+		valid = append(valid, place)
+		validRows = append(validRows, rows[i])
+	}
+
+	list, err := GetListByName(ctx, userID, result.ListName)
+	if errors.Is(err, ErrListNotFound) {
+		var listID string
+		listID, err = CreateList(ctx, userID, data.List{ListName: result.ListName})
+		if errors.Is(err, ErrListNameTaken) {
+			// Created concurrently, so extend it like any other list.
+			list, err = GetListByName(ctx, userID, result.ListName)
+		} else if err == nil {
+			list = &data.List{ID: listID, ListName: result.ListName}
+			result.ListCreated = true
+		}
+	}
+	if err != nil {
+		return err
+	}
+	result.ListID = list.ID
+
+	_, added, err := appendListPlaces(ctx, userID, list.ID, valid)
+	if err != nil {
+		return err
+	}
+	for i := range valid {
+		if added[i] {
+			result.Added++
+		} else {
+			result.Duplicates = append(result.Duplicates, validRows[i])
+		}
+	}
+	return nil
+}
+
+// importNote keeps the feature's note, or its name if that says more than
+// the OSM reference it was exported as.
+func importNote(feature formats.Feature) string {
+	if feature.Note != "" {
+		return feature.Note
+	}
+	if feature.Name == osmName(feature.OsmType, feature.OsmID) {
+		return ""
+	}
+	return feature.Name
+}
@@ -0,0 +1,68 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/formats"
+	"backend/services"
+	"strings"
+	"testing"
+)
+
+func TestImportPlaces(t *testing.T) {
+	ctx := newUser(t, "u1")
+	listID, err := services.CreateList(ctx, "u1", data.List{ListName: "Imported"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	if _, _, err := services.AppendPlace(ctx, "u1", listID, data.Place{OsmID: "1", OsmType: "node"}, "u1"); err != nil {
+		t.Fatalf("AppendPlace: %v", err)
+	}
+
+	features := []formats.Feature{
+		{Row: 2, OsmID: "1", OsmType: "node", Lat: 1, Lon: 1},
+		{Row: 3, OsmID: "2", OsmType: "node", Lat: 2, Lon: 2, Note: "corner table"},
+		{Row: 4, OsmID: "2", OsmType: "node", Lat: 2, Lon: 2},
+		{Row: 5, Name: "No reference", Lat: 3, Lon: 3},
+		{Row: 6, OsmID: "3", OsmType: "way", Lat: 4, Lon: 4, Note: strings.Repeat("x", 1001)},
+	}
+	result, err := services.ImportPlaces(ctx, "u1", "Imported", features, nil, "apikey:import")
+	if err != nil {
+		t.Fatalf("ImportPlaces: %v", err)
+	}
+	if result.ListID != listID || result.ListCreated || result.Added != 1 {
+		t.Errorf("ImportPlaces = %+v, want one place added to the existing list", result)
+	}
+	if len(result.Duplicates) != 2 || result.Duplicates[0] != 2 || result.Duplicates[1] != 4 {
+		t.Errorf("ImportPlaces duplicates = %v, want rows 2 and 4", result.Duplicates)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Row != 5 || result.Errors[1].Row != 6 {
+		t.Errorf("ImportPlaces errors = %+v, want rows 5 and 6", result.Errors)
+	}
+
+	list, err := services.GetList(ctx, "u1", listID)
+	if err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	if len(list.Places) != 2 {
+		t.Fatalf("GetList places = %+v, want 2", list.Places)
+	}
+	imported := list.Places[1]
+	if imported.OsmID != "2" || imported.Note != "corner table" || imported.AddedBy != "apikey:import" || imported.AddedAt == nil {
+		t.Errorf("imported entry = %+v, want place 2 with its note, added by apikey:import", imported)
+	}
+}
+
+func TestImportPlacesCreatesList(t *testing.T) {
+	ctx := newUser(t, "u1")
+
+	result, err := services.ImportPlaces(ctx, "u1", "From my old app", []formats.Feature{{Row: 2, OsmID: "1", OsmType: "node"}}, nil, "u1")
+	if err != nil {
+		t.Fatalf("ImportPlaces: %v", err)
+	}
+	if !result.ListCreated || result.Added != 1 {
+		t.Errorf("ImportPlaces = %+v, want a new list with one place", result)
+	}
+	if _, err := services.GetList(ctx, "u1", result.ListID); err != nil {
+		t.Errorf("GetList of the created list: %v", err)
+	}
+}
@@ -4,103 +4,441 @@ import (
 	"backend/data"
 	"context"
 	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
+const (
+	maxListNameLength        = 100
+	maxListDescriptionLength = 1000
+	maxListIconLength        = 32
+	maxListNoteLength        = 1000
+)
+
+var (
+	listColourPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	// errPlaceInList stops appendListPlaces from writing a list that
+	// already holds all of the places.
+	errPlaceInList = errors.New("place already in list")
+)
+
+// ListPlacePatch holds the list entry fields a partial update changes. Nil
+// fields are left as they are.
+type ListPlacePatch struct {
+	Note     *string
+	Position *int
+}
+
+// ListPatch holds the list fields a partial update changes. Nil fields are
+// left as they are.
+type ListPatch struct {
+	ListName    *string
+	Description *string
+	Colour      *string
+	Icon        *string
+}
+
 func CreateList(ctx context.Context, userID string, list data.List) (string, error) {
-	user, docSnap, err := getUserByID(ctx, userID)
-	if err != nil {
+	list.ListName = strings.TrimSpace(list.ListName)
+	if err := validateList(list); err != nil {
 		return "", err
 	}
-
 	if list.ID == "" {
 		list.ID = uuid.New().String()
 	}
+	if list.Places == nil {
+		list.Places = []data.Place{}
+	}
+	// Lists start out private and are shared through ShareList.
+	list.Visibility = VisibilityPrivate
+	list.ShareToken = ""
 
-	user.Lists = append(user.Lists, list)
-
-	if err := saveUser(ctx, user, docSnap); err != nil {
+	if err := repo.CreateList(ctx, userID, list); err != nil {
+		return "", err
+	}
+	locateNewPlaces(userID, list.ID, list.Places)
+	if err := publishList(ctx, userID, EventListCreated, &list); err != nil {
 		return "", err
 	}
 
 	return list.ID, nil
 }
 
+// GetLists returns all of the user's lists in creation order, followed by
+// the lists other users have shared with the user.
+func GetLists(ctx context.Context, userID string) ([]data.List, error) {
+	lists, err := repo.GetLists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	numberLists(lists)
+	if lists, err = withSharedLists(ctx, userID, lists); err != nil {
+		return nil, err
+	}
+	fillListDetails(ctx, lists)
+	return lists, nil
+}
+
+// GetList returns a list the user owns or is a member of.
+func GetList(ctx context.Context, userID string, listRef string) (*data.List, error) {
+	_, list, err := resolveListAccess(ctx, userID, listRef, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	numberPlaces(list)
+	fillPlaceDetails(ctx, listEntries(list.Places)...)
+	return list, nil
+}
+
+// UpdateListDetails renames a list or changes its description, colour or
+// icon. Places are left alone.
+func UpdateListDetails(ctx context.Context, userID string, listID string, patch ListPatch) (*data.List, error) {
+	list, err := updateList(ctx, userID, listID, func(list *data.List) error {
+		if patch.ListName != nil {
+			list.ListName = strings.TrimSpace(*patch.ListName)
+		}
+		if patch.Description != nil {
+			list.Description = *patch.Description
+		}
+		if patch.Colour != nil {
+			list.Colour = *patch.Colour
+		}
+		if patch.Icon != nil {
+			list.Icon = *patch.Icon
+		}
+		return validateList(*list)
+	})
+	if err != nil {
+		return nil, ownerOnly(ctx, userID, listID, err)
+	}
+	numberPlaces(list)
+	fillPlaceDetails(ctx, listEntries(list.Places)...)
+	return list, nil
+}
+
+func DeleteList(ctx context.Context, userID string, listID string) error {
+	return ownerOnly(ctx, userID, listID, deleteList(ctx, userID, listID))
+}
+
+// deleteList deletes a list and publishes its deletion to the owner and
+// the members.
+func deleteList(ctx context.Context, ownerID string, listID string) error {
+	members, err := repo.GetListMembers(ctx, ownerID, listID)
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteList(ctx, ownerID, listID); err != nil {
+		return err
+	}
+	return publishListDeleted(ctx, ownerID, listID, members)
+}
+
+// resolveList finds a list by ID, falling back to its name for callers that
+// still address lists by name.
+func resolveList(ctx context.Context, userID string, listRef string) (*data.List, error) {
+	list, err := repo.GetList(ctx, userID, listRef)
+	if errors.Is(err, ErrListNotFound) {
+		return GetListByName(ctx, userID, listRef)
+	}
+	return list, err
+}
+
+func validateList(list data.List) error {
+	if list.ListName == "" {
+		return ValidationError("list name is required")
+	}
+	if utf8.RuneCountInString(list.ListName) > maxListNameLength {
+		return ValidationError("list name must be at most %d characters", maxListNameLength)
+	}
+	if utf8.RuneCountInString(list.Description) > maxListDescriptionLength {
+		return ValidationError("description must be at most %d characters", maxListDescriptionLength)
+	}
+	if list.Colour != "" && !listColourPattern.MatchString(list.Colour) {
+		return ValidationError("colour must be a hex colour such as #ff8800")
+	}
+	if utf8.RuneCountInString(list.Icon) > maxListIconLength {
+		return ValidationError("icon must be at most %d characters", maxListIconLength)
+	}
+	return nil
+}
+
 func GetListByName(ctx context.Context, userID string, listName string) (*data.List, error) {
-	user, _, err := getUserByID(ctx, userID)
+	lists, err := repo.GetLists(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	_, list := findListByName(user.Lists, listName)
+	_, list := findListByName(lists, listName)
 	if list == nil {
-		return nil, errors.New("list not found")
+		return nil, ErrListNotFound
 	}
+	numberPlaces(list)
 
 	return list, nil
 }
 
 func DeleteListByName(ctx context.Context, userID string, listName string) error {
-	user, docSnap, err := getUserByID(ctx, userID)
+	lists, err := repo.GetLists(ctx, userID)
 	if err != nil {
 		return err
 	}
 
-	var postDelete []data.List
-	for _, list := range user.Lists {
+	for _, list := range lists {
 		if list.ListName != listName {
-			postDelete = append(postDelete, list)
+			continue
+		}
+		if err := deleteList(ctx, userID, list.ID); err != nil && !errors.Is(err, ErrListNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendPlace adds a place to the end of a list. If the list already holds
+// the place, the existing entry is returned unchanged and added is false.
+func AppendPlace(ctx context.Context, userID string, listRef string, place data.Place, addedBy string) (*data.Place, bool, error) {
+	if err := validateListPlace(place); err != nil {
+		return nil, false, err
+	}
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, RoleEditor)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	place.AddedAt = &now
+	place.AddedBy = addedBy
+	entries, added, err := appendListPlaces(ctx, ownerID, list.ID, []data.Place{place})
+	if err != nil {
+		return nil, false, err
+	}
+	fillPlaceDetails(ctx, &entries[0])
+	return &entries[0], added[0], nil
+}
+
+// appendListPlaces adds places to the end of a list in a single write,
+// skipping those the list already holds, including earlier ones of places.
+// It returns the list entry of each place, with its position, and whether
+// it was added. The places are stored as given, so callers fill in AddedAt
+// and AddedBy.
+func appendListPlaces(ctx context.Context, ownerID string, listID string, places []data.Place) ([]data.Place, []bool, error) {
+	entries := make([]data.Place, len(places))
+	added := make([]bool, len(places))
+	_, err := updateList(ctx, ownerID, listID, func(list *data.List) error {
+		anyAdded := false
+		for i, place := range places {
+			if j := findListPlace(list.Places, PlaceKey(place.OsmType, place.OsmID)); j >= 0 {
+				entries[i], added[i] = list.Places[j], false
+				entries[i].Position = j
+				continue
+			}
+			place.Position, place.Details = 0, nil
+			list.Places = append(list.Places, place)
+			entries[i], added[i] = place, true
+			entries[i].Position = len(list.Places) - 1
+			anyAdded = true
 		}
+		if !anyAdded {
+			return errPlaceInList
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPlaceInList) {
+		return nil, nil, err
 	}
 
-	user.Lists = postDelete
-	return saveUser(ctx, user, docSnap)
+	var newEntries []data.Place
+	for i, entry := range entries {
+		if added[i] {
+			newEntries = append(newEntries, entry)
+		}
+	}
+	locateNewPlaces(ownerID, listID, newEntries)
+	return entries, added, nil
 }
 
-func AppendPlace(ctx context.Context, userID string, listName string, place data.Place) (*data.Place, error) {
-	user, docSnap, err := getUserByID(ctx, userID)
+// RemovePlace removes every entry of the place from a list.
+func RemovePlace(ctx context.Context, userID string, listRef string, placeKey string) error {
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, RoleEditor)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	listIndex, _ := findListByName(user.Lists, listName)
-	if listIndex == -1 {
-		return nil, errors.New("list not found")
+	_, err = updateList(ctx, ownerID, list.ID, func(list *data.List) error {
+		osmType, osmID := SplitPlaceKey(placeKey)
+		newPlaces := make([]data.Place, 0, len(list.Places))
+		for _, place := range list.Places {
+			if !SamePlace(place.OsmType, place.OsmID, osmType, osmID) {
+				newPlaces = append(newPlaces, place)
+			}
+		}
+		if len(newPlaces) == len(list.Places) {
+			return ErrPlaceNotFound
+		}
+		list.Places = newPlaces
+		return nil
+	})
+	return err
+}
+
+// UpdateListPlace changes the note of a list entry or moves it to another
+// position in the list.
+func UpdateListPlace(ctx context.Context, userID string, listRef string, placeKey string, patch ListPlacePatch) (*data.Place, error) {
+	if patch.Note != nil {
+		if err := validateListNote(*patch.Note); err != nil {
+			return nil, err
+		}
+	}
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, RoleEditor)
+	if err != nil {
+		return nil, err
 	}
 
-	user.Lists[listIndex].Places = append(user.Lists[listIndex].Places, place)
+	var entry data.Place
+	_, err = updateList(ctx, ownerID, list.ID, func(list *data.List) error {
+		i := findListPlace(list.Places, placeKey)
+		if i < 0 {
+			return ErrPlaceNotFound
+		}
+		if patch.Note != nil {
+			list.Places[i].Note = *patch.Note
+		}
+		entry = list.Places[i]
+		entry.Position = i
+		if patch.Position == nil || *patch.Position == i {
+			return nil
+		}
+
+		position := *patch.Position
+		if position < 0 || position >= len(list.Places) {
+			return ValidationError("position must be between 0 and %d", len(list.Places)-1)
+		}
+		places := append(list.Places[:i:i], list.Places[i+1:]...)
+		places = append(places[:position], append([]data.Place{entry}, places[position:]...)...)
+		list.Places = places
+		entry.Position = position
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fillPlaceDetails(ctx, &entry)
+	return &entry, nil
+}
 
-	if err := saveUser(ctx, user, docSnap); err != nil {
+// ReorderList puts the entries of a list in the order of placeKeys, OSM IDs
+// or keys from PlaceKey, which have to name every entry exactly once.
+func ReorderList(ctx context.Context, userID string, listRef string, placeKeys []string) (*data.List, error) {
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, RoleEditor)
+	if err != nil {
 		return nil, err
 	}
 
-	return &place, nil
+	updated, err := updateList(ctx, ownerID, list.ID, func(list *data.List) error {
+		if len(placeKeys) != len(list.Places) {
+			return ValidationError("order must name all %d places of the list", len(list.Places))
+		}
+		remaining := append([]data.Place{}, list.Places...)
+		ordered := make([]data.Place, 0, len(placeKeys))
+		for _, key := range placeKeys {
+			i := findListPlace(remaining, key)
+			if i < 0 {
+				return ValidationError("place %q is not in the list or named twice", key)
+			}
+			ordered = append(ordered, remaining[i])
+			remaining = append(remaining[:i], remaining[i+1:]...)
+		}
+		list.Places = ordered
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	setListRole(updated, list.OwnerID, list.Role)
+	numberPlaces(updated)
+	fillPlaceDetails(ctx, listEntries(updated.Places)...)
+	return updated, nil
 }
 
-func RemovePlace(ctx context.Context, userID string, listName string, osmID string) error {
-	user, docSnap, err := getUserByID(ctx, userID)
+// TransferListPlace copies a list entry to another list, keeping its note,
+// and removes it from the source list unless keepSource is set. A moved
+// entry also keeps when and by whom it was added, while a copy is added by
+// addedBy now. The entry is added to the target before it is removed from
+// the source, so a failure in between leaves it in both lists rather than
+// in neither. Copying only needs read access to the source list.
+func TransferListPlace(ctx context.Context, userID string, listRef string, placeKey string, targetRef string, keepSource bool, addedBy string) (*data.Place, error) {
+	sourceRole := RoleEditor
+	if keepSource {
+		sourceRole = RoleViewer
+	}
+	sourceOwner, source, err := resolveListAccess(ctx, userID, listRef, sourceRole)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	targetOwner, target, err := resolveListAccess(ctx, userID, targetRef, RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if sourceOwner == targetOwner && source.ID == target.ID {
+		return nil, ValidationError("source and target list are the same")
+	}
+	i := findListPlace(source.Places, placeKey)
+	if i < 0 {
+		return nil, ErrPlaceNotFound
+	}
+
+	place := source.Places[i]
+	if keepSource {
+		now := time.Now()
+		place.AddedAt = &now
+		place.AddedBy = addedBy
+	}
+	entries, _, err := appendListPlaces(ctx, targetOwner, target.ID, []data.Place{place})
+	if err != nil {
+		return nil, err
 	}
+	entry := &entries[0]
+	fillPlaceDetails(ctx, entry)
+	if keepSource {
+		return entry, nil
+	}
+	if err := RemovePlace(ctx, userID, source.ID, placeKey); err != nil && !errors.Is(err, ErrPlaceNotFound) {
+		return nil, err
+	}
+	return entry, nil
+}
 
-	listIndex, _ := findListByName(user.Lists, listName)
-	if listIndex == -1 {
-		return errors.New("list not found")
+// numberPlaces fills in the Position of every entry of a list.
+func numberPlaces(list *data.List) {
+	for i := range list.Places {
+		list.Places[i].Position = i
 	}
+}
 
-	places := user.Lists[listIndex].Places
-	newPlaces := make([]data.Place, 0, len(places))
-	for _, place := range places {
-		if place.OsmID != osmID {
-			newPlaces = append(newPlaces, place)
-		}
+func numberLists(lists []data.List) {
+	for i := range lists {
+		numberPlaces(&lists[i])
 	}
-	user.Lists[listIndex].Places = newPlaces
+}
 
-	if err := saveUser(ctx, user, docSnap); err != nil {
-		return err
+// validateListPlace checks a place before it is added to a list.
+func validateListPlace(place data.Place) error {
+	if place.OsmID == "" {
+		return ValidationError("missing osm_id in place")
 	}
+	if !ValidOsmID(place.OsmID) {
+		return ValidationError("invalid osm_id %q in place", place.OsmID)
+	}
+	return validateListNote(place.Note)
+}
 
-	return saveUser(ctx, user, docSnap)
+func validateListNote(note string) error {
+	if utf8.RuneCountInString(note) > maxListNoteLength {
+		return ValidationError("note must be at most %d characters", maxListNoteLength)
+	}
+	return nil
 }
@@ -0,0 +1,157 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"errors"
+	"testing"
+)
+
+func TestCreateList(t *testing.T) {
+	ctx := newUser(t, "u1")
+
+	listID, err := services.CreateList(ctx, "u1", data.List{ListName: "  Lunch  "})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	list, err := services.GetList(ctx, "u1", listID)
+	if err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	if list.ListName != "Lunch" || list.Visibility != services.VisibilityPrivate || list.Places == nil {
+		t.Errorf("GetList = %+v, want a private, empty list named Lunch", list)
+	}
+
+	if _, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch"}); !errors.Is(err, services.ErrListNameTaken) {
+		t.Errorf("CreateList with a taken name: got %v, want ErrListNameTaken", err)
+	}
+	if _, err := services.CreateList(ctx, "u1", data.List{ListName: " "}); errorKind(err) != services.KindValidation {
+		t.Errorf("CreateList without a name: got %v, want a validation error", err)
+	}
+	if _, err := services.CreateList(ctx, "nobody", data.List{ListName: "Lunch"}); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("CreateList for an unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestAppendPlace(t *testing.T) {
+	ctx := newUser(t, "u1")
+	listID, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+
+	place := data.Place{OsmID: "1", OsmType: "node", Lat: 52.5, Long: 13.4, Note: "try the soup"}
+	entry, added, err := services.AppendPlace(ctx, "u1", listID, place, "u1")
+	if err != nil {
+		t.Fatalf("AppendPlace: %v", err)
+	}
+	if !added || entry.AddedAt == nil || entry.AddedBy != "u1" || entry.Position != 0 {
+		t.Errorf("AppendPlace = %+v, added %v; want a new entry at 0 added by u1", entry, added)
+	}
+
+	again, added, err := services.AppendPlace(ctx, "u1", listID, data.Place{OsmID: "1", OsmType: "node"}, "u2")
+	if err != nil {
+		t.Fatalf("AppendPlace twice: %v", err)
+	}
+	if added || again.Note != "try the soup" || again.AddedBy != "u1" {
+		t.Errorf("AppendPlace twice = %+v, added %v; want the existing entry", again, added)
+	}
+
+	// Lists can still be addressed by name.
+	if _, _, err := services.AppendPlace(ctx, "u1", "Lunch", data.Place{OsmID: "2", OsmType: "way"}, "u1"); err != nil {
+		t.Fatalf("AppendPlace by list name: %v", err)
+	}
+	list, err := services.GetList(ctx, "u1", listID)
+	if err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	if len(list.Places) != 2 || list.Places[1].OsmID != "2" || list.Places[1].Position != 1 {
+		t.Errorf("GetList places = %+v, want places 1 and 2 in order", list.Places)
+	}
+
+	if _, _, err := services.AppendPlace(ctx, "u1", listID, data.Place{}, "u1"); errorKind(err) != services.KindValidation {
+		t.Errorf("AppendPlace without an OSM ID: got %v, want a validation error", err)
+	}
+	if _, _, err := services.AppendPlace(ctx, "u1", listID, data.Place{OsmID: "1);way(2", OsmType: "node"}, "u1"); errorKind(err) != services.KindValidation {
+		t.Errorf("AppendPlace with a non-numeric OSM ID: got %v, want a validation error", err)
+	}
+	if _, _, err := services.AppendPlace(ctx, "u1", "Dinner", place, "u1"); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("AppendPlace to an unknown list: got %v, want ErrListNotFound", err)
+	}
+}
+
+func TestRemovePlace(t *testing.T) {
+	ctx := newUser(t, "u1")
+	listID, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch", Places: []data.Place{{OsmID: "1", OsmType: "node"}}})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+
+	if err := services.RemovePlace(ctx, "u1", listID, "node/1"); err != nil {
+		t.Fatalf("RemovePlace: %v", err)
+	}
+	if err := services.RemovePlace(ctx, "u1", listID, "node/1"); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("RemovePlace twice: got %v, want ErrPlaceNotFound", err)
+	}
+}
+
+func TestDeleteList(t *testing.T) {
+	ctx := newUser(t, "u1")
+	listID, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+
+	if err := services.DeleteList(ctx, "u1", listID); err != nil {
+		t.Fatalf("DeleteList: %v", err)
+	}
+	if _, err := services.GetList(ctx, "u1", listID); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("GetList after delete: got %v, want ErrListNotFound", err)
+	}
+	// The name is free again.
+	if _, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch"}); err != nil {
+		t.Errorf("CreateList with the name of a deleted list: %v", err)
+	}
+}
+
+func TestTransferListPlace(t *testing.T) {
+	ctx := newUser(t, "u1")
+	fromID, err := services.CreateList(ctx, "u1", data.List{ListName: "Someday"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	toID, err := services.CreateList(ctx, "u1", data.List{ListName: "Soon"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	original, _, err := services.AppendPlace(ctx, "u1", fromID, data.Place{OsmID: "1", OsmType: "node", Note: "ask for the terrace"}, "u1")
+	if err != nil {
+		t.Fatalf("AppendPlace: %v", err)
+	}
+
+	copied, err := services.TransferListPlace(ctx, "u1", fromID, "node/1", toID, true, "apikey:script")
+	if err != nil {
+		t.Fatalf("TransferListPlace copy: %v", err)
+	}
+	if copied.Note != original.Note || copied.AddedBy != "apikey:script" {
+		t.Errorf("copied entry = %+v, want the note added by apikey:script", copied)
+	}
+	if err := services.RemovePlace(ctx, "u1", toID, "node/1"); err != nil {
+		t.Fatalf("RemovePlace: %v", err)
+	}
+
+	moved, err := services.TransferListPlace(ctx, "u1", fromID, "node/1", toID, false, "apikey:script")
+	if err != nil {
+		t.Fatalf("TransferListPlace move: %v", err)
+	}
+	if moved.Note != original.Note || moved.AddedBy != "u1" || moved.AddedAt == nil || !moved.AddedAt.Equal(*original.AddedAt) {
+		t.Errorf("moved entry = %+v, want the source entry %+v", moved, original)
+	}
+	from, err := services.GetList(ctx, "u1", fromID)
+	if err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	if len(from.Places) != 0 {
+		t.Errorf("source list after move = %+v, want it empty", from.Places)
+	}
+}
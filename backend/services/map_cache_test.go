@@ -0,0 +1,71 @@
+package services
+
+import (
+	"container/list"
+	"testing"
+	"time"
+)
+
+func newMapCache() *mapCache {
+	return &mapCache{ttl: DefaultMapCacheTTL, tiles: make(map[mapTile]*list.Element), recent: list.New(), limiters: make(map[string]*userLimiter)}
+}
+
+func TestMapCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newMapCache()
+	now := time.Now()
+	for x := 0; x < maxCachedTiles; x++ {
+		c.store(mapTile{x: x}, cachedTile{fetchedAt: now})
+	}
+	// Using the oldest tile keeps it over the second oldest.
+	if _, missing := c.lookup([]mapTile{{x: 0}}); len(missing) != 0 {
+		t.Fatalf("lookup of a fresh tile: missing %v", missing)
+	}
+	c.store(mapTile{x: maxCachedTiles}, cachedTile{fetchedAt: now})
+
+	if len(c.tiles) != maxCachedTiles || c.recent.Len() != maxCachedTiles {
+		t.Errorf("cache holds %d tiles in a list of %d, want %d", len(c.tiles), c.recent.Len(), maxCachedTiles)
+	}
+	if _, ok := c.tiles[mapTile{x: 1}]; ok {
+		t.Error("tile 1 is cached, want it evicted as the least recently used")
+	}
+	if _, ok := c.tiles[mapTile{x: 0}]; !ok {
+		t.Error("tile 0 was evicted, want it kept as recently used")
+	}
+}
+
+func TestMapCacheDropsRetiredTiles(t *testing.T) {
+	c := newMapCache()
+	retired := time.Now().Add(-c.ttl - mapStaleRetention - time.Minute)
+	expired := time.Now().Add(-c.ttl - time.Minute)
+	c.store(mapTile{x: 0}, cachedTile{fetchedAt: retired})
+	c.store(mapTile{x: 1}, cachedTile{fetchedAt: expired})
+
+	cached, missing := c.lookup([]mapTile{{x: 0}, {x: 1}})
+	if _, ok := cached[mapTile{x: 0}]; ok || len(missing) != 2 {
+		t.Errorf("lookup = %v, missing %v; want only the expired tile served, and both refetched", cached, missing)
+	}
+	if _, ok := c.tiles[mapTile{x: 0}]; ok || c.recent.Len() != 1 {
+		t.Errorf("cache holds %d tiles, want the retired tile dropped", c.recent.Len())
+	}
+}
+
+func TestMapCachePrunesIdleLimiters(t *testing.T) {
+	c := newMapCache()
+	c.allow("idle")
+	c.limiters["idle"].lastUsed = time.Now().Add(-limiterIdle - time.Minute)
+
+	// The last prune was just now, so the idle limiter stays for a while.
+	c.allow("active")
+	if _, ok := c.limiters["idle"]; !ok {
+		t.Fatal("idle limiter pruned before limiterPruneEvery passed")
+	}
+
+	c.lastPrune = time.Now().Add(-limiterPruneEvery)
+	c.allow("active")
+	if _, ok := c.limiters["idle"]; ok {
+		t.Error("idle limiter kept after limiterPruneEvery passed")
+	}
+	if _, ok := c.limiters["active"]; !ok {
+		t.Error("active limiter pruned")
+	}
+}
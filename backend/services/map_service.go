@@ -0,0 +1,425 @@
+package services
+
+import (
+	"backend/data"
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultMapCacheTTL is how long a cached tile is served before it is
+	// fetched again.
+	DefaultMapCacheTTL = time.Hour
+	// mapTileSize is the edge of a cache tile in degrees, about 2 km.
+	mapTileSize = 0.02
+	// maxMapTiles bounds the area of a single search, about 20 by 20 km.
+	maxMapTiles = 100
+	// maxCachedTiles bounds the memory the tile cache takes.
+	maxCachedTiles = 20000
+	// mapStaleRetention is how long a tile is kept after it expires, to be
+	// served when the upstream server fails.
+	mapStaleRetention = 7 * 24 * time.Hour
+	// mapFetchEvery and mapFetchBurst limit how often a single user's
+	// searches may go to the upstream server. Searches served from the
+	// cache are not limited.
+	mapFetchEvery = 2 * time.Second
+	mapFetchBurst = 5
+	// limiterIdle is how long an unused per-user limiter is kept.
+	limiterIdle = 10 * time.Minute
+	// limiterPruneEvery is how often idle limiters are looked for.
+	limiterPruneEvery = time.Minute
+)
+
+// EateryAmenities are the amenity values of the places the map shows.
+var EateryAmenities = []string{"restaurant", "cafe", "pub", "bar", "fast_food", "biergarten", "ice_cream"}
+
+// BoundingBox is an area in WGS84 degrees.
+type BoundingBox struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// PlaceSearcher finds eating and drinking places in an area, such as on an
+// Overpass server.
+type PlaceSearcher interface {
+	// PlacesInBox returns the places with one of EateryAmenities inside
+	// box, named or not.
+	PlacesInBox(ctx context.Context, box BoundingBox) ([]data.CatalogPlace, error)
+}
+
+// MapFilter narrows a map search. Empty fields match every place; Name
+// matches any part of a place's name, ignoring case.
+type MapFilter struct {
+	Amenities []string
+	Cuisines  []string
+	Name      string
+}
+
+// MapPlaces is the result of a map search. Stale is set when some of the
+// places come from expired cache entries because the upstream server could
+// not be asked.
+type MapPlaces struct {
+	Places []data.CatalogPlace `json:"places"`
+	Stale  bool                `json:"stale"`
+}
+
+type mapTile struct {
+	x int
+	y int
+}
+
+type cachedTile struct {
+	places    []data.CatalogPlace
+	fetchedAt time.Time
+}
+
+// tileEntry is the value of an element of mapCache.recent.
+type tileEntry struct {
+	tile mapTile
+	cachedTile
+}
+
+// mapCache holds the places of recently searched tiles, shared by all
+// users. It lives in memory, so each server instance has its own.
+type mapCache struct {
+	mu       sync.Mutex
+	searcher PlaceSearcher
+	// local searchers are asked directly, without the cache or limits.
+	local bool
+	ttl   time.Duration
+	// tiles maps each cached tile to its element in recent, which holds
+	// the tiles most recently used first.
+	tiles     map[mapTile]*list.Element
+	recent    *list.List
+	limiters  map[string]*userLimiter
+	lastPrune time.Time
+}
+
+type userLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+var mapPlaces = &mapCache{ttl: DefaultMapCacheTTL, tiles: make(map[mapTile]*list.Element), recent: list.New(), limiters: make(map[string]*userLimiter)}
+
+// SetPlaceSearcher sets where map searches get their places and how long
+// they are cached. A ttl of 0 means DefaultMapCacheTTL. Without a searcher,
+// map searches fail.
+func SetPlaceSearcher(s PlaceSearcher, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultMapCacheTTL
+	}
+	mapPlaces.mu.Lock()
+	defer mapPlaces.mu.Unlock()
+	mapPlaces.searcher, mapPlaces.local, mapPlaces.ttl = s, false, ttl
+	mapPlaces.clear()
+}
+
+// SetLocalPlaceSearcher sets a searcher that answers from local data, such
+// as a place file, for map searches. Its searches are neither cached nor
+// rate limited.
+func SetLocalPlaceSearcher(s PlaceSearcher) {
+	mapPlaces.mu.Lock()
+	defer mapPlaces.mu.Unlock()
+	mapPlaces.searcher, mapPlaces.local = s, true
+	mapPlaces.clear()
+}
+
+// SearchPlaces returns the eating and drinking places inside box that pass
+// filter. Unless the searcher is local, places are cached by tile, and only
+// expired or missing tiles are fetched, on behalf of userID. When the fetch
+// fails, or the user searches too often, expired tiles are served instead;
+// without them the search fails.
+func SearchPlaces(ctx context.Context, userID string, box BoundingBox, filter MapFilter) (*MapPlaces, error) {
+	if err := validateBox(box); err != nil {
+		return nil, err
+	}
+	for _, amenity := range filter.Amenities {
+		if !slices.Contains(EateryAmenities, amenity) {
+			return nil, ValidationError("amenity must be one of %s", strings.Join(EateryAmenities, ", "))
+		}
+	}
+	southWest, northEast := tileAt(box.South, box.West), tileAt(box.North, box.East)
+	// Each side is checked on its own first, so the product cannot overflow.
+	columns, rows := northEast.x-southWest.x+1, northEast.y-southWest.y+1
+	if columns > maxMapTiles || rows > maxMapTiles || columns*rows > maxMapTiles {
+		return nil, ValidationError("area is too large, zoom in to search")
+	}
+	if searcher := mapPlaces.localSearcher(); searcher != nil {
+		places, err := searcher.PlacesInBox(ctx, box)
+		if err != nil {
+			log.Printf("Error searching local places: %v", err)
+			return nil, ErrPlacesUnavailable
+		}
+		result := &MapPlaces{Places: []data.CatalogPlace{}}
+		for _, place := range places {
+			if filter.matches(place) {
+				result.Places = append(result.Places, place)
+			}
+		}
+		return result, nil
+	}
+
+	var tiles []mapTile
+	for x := southWest.x; x <= northEast.x; x++ {
+		for y := southWest.y; y <= northEast.y; y++ {
+			tiles = append(tiles, mapTile{x, y})
+		}
+	}
+
+	cached, missing := mapPlaces.lookup(tiles)
+	stale := false
+	if len(missing) > 0 {
+		if err := mapPlaces.refresh(ctx, userID, missing, cached); err != nil {
+			for _, tile := range missing {
+				if _, ok := cached[tile]; !ok {
+					return nil, err
+				}
+			}
+			stale = true
+		}
+	}
+
+	result := &MapPlaces{Places: []data.CatalogPlace{}, Stale: stale}
+	for _, tile := range tiles {
+		for _, place := range cached[tile].places {
+			if inBox(box, place) && filter.matches(place) {
+				result.Places = append(result.Places, place)
+			}
+		}
+	}
+	return result, nil
+}
+
+// localSearcher returns the searcher if it is local, and nil otherwise.
+func (c *mapCache) localSearcher() PlaceSearcher {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.local {
+		return nil
+	}
+	return c.searcher
+}
+
+// clear drops all cached tiles. It must be called with c.mu held.
+func (c *mapCache) clear() {
+	c.tiles = make(map[mapTile]*list.Element)
+	c.recent = list.New()
+}
+
+// lookup returns the cached entries of tiles, expired or not, and the
+// tiles that are expired or not cached. Tiles past their stale retention
+// are dropped instead.
+func (c *mapCache) lookup(tiles []mapTile) (map[mapTile]cachedTile, []mapTile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := make(map[mapTile]cachedTile, len(tiles))
+	var missing []mapTile
+	for _, tile := range tiles {
+		element, ok := c.tiles[tile]
+		if ok && c.retired(element) {
+			c.remove(element)
+			ok = false
+		}
+		if !ok {
+			missing = append(missing, tile)
+			continue
+		}
+		c.recent.MoveToFront(element)
+		entry := element.Value.(*tileEntry).cachedTile
+		cached[tile] = entry
+		if time.Since(entry.fetchedAt) >= c.ttl {
+			missing = append(missing, tile)
+		}
+	}
+	return cached, missing
+}
+
+// refresh fetches tiles on behalf of userID and adds them to cached.
+func (c *mapCache) refresh(ctx context.Context, userID string, tiles []mapTile, cached map[mapTile]cachedTile) error {
+	if !c.allow(userID) {
+		return ErrRateLimited
+	}
+	fetched, err := c.fetch(ctx, tiles)
+	if err != nil {
+		log.Printf("Error fetching %d map tiles: %v", len(tiles), err)
+		return ErrPlacesUnavailable
+	}
+	for tile, entry := range fetched {
+		cached[tile] = entry
+	}
+	return nil
+}
+
+// fetch gets the places of all tiles in the rectangle spanned by tiles with
+// a single query and caches them. The places are added to the place catalog
+// as well.
+func (c *mapCache) fetch(ctx context.Context, tiles []mapTile) (map[mapTile]cachedTile, error) {
+	c.mu.Lock()
+	searcher := c.searcher
+	c.mu.Unlock()
+	if searcher == nil {
+		return nil, errors.New("no place searcher configured")
+	}
+
+	minX, minY, maxX, maxY := tiles[0].x, tiles[0].y, tiles[0].x, tiles[0].y
+	for _, tile := range tiles[1:] {
+		minX, maxX = min(minX, tile.x), max(maxX, tile.x)
+		minY, maxY = min(minY, tile.y), max(maxY, tile.y)
+	}
+	box := BoundingBox{
+		South: float64(minY) * mapTileSize,
+		West:  float64(minX) * mapTileSize,
+		North: float64(maxY+1) * mapTileSize,
+		East:  float64(maxX+1) * mapTileSize,
+	}
+	places, err := searcher.PlacesInBox(ctx, box)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	fetched := make(map[mapTile]cachedTile)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			fetched[mapTile{x, y}] = cachedTile{places: []data.CatalogPlace{}, fetchedAt: now}
+		}
+	}
+	for i := range places {
+		places[i].FetchedAt = now
+		tile := tileAt(places[i].Lat, places[i].Long)
+		if entry, ok := fetched[tile]; ok {
+			entry.places = append(entry.places, places[i])
+			fetched[tile] = entry
+		}
+	}
+
+	c.mu.Lock()
+	for tile, entry := range fetched {
+		c.store(tile, entry)
+	}
+	c.mu.Unlock()
+
+	if err := repo.SaveCatalogPlaces(ctx, places); err != nil {
+		log.Printf("Error adding %d places to the catalog: %v", len(places), err)
+	}
+	return fetched, nil
+}
+
+// store caches a tile as the most recently used one. The least recently
+// used tiles are dropped while the cache is too large or they are past
+// their stale retention. It must be called with c.mu held.
+func (c *mapCache) store(tile mapTile, entry cachedTile) {
+	if element, ok := c.tiles[tile]; ok {
+		element.Value.(*tileEntry).cachedTile = entry
+		c.recent.MoveToFront(element)
+	} else {
+		c.tiles[tile] = c.recent.PushFront(&tileEntry{tile: tile, cachedTile: entry})
+	}
+	for oldest := c.recent.Back(); oldest != nil && (c.recent.Len() > maxCachedTiles || c.retired(oldest)); oldest = c.recent.Back() {
+		c.remove(oldest)
+	}
+}
+
+// retired reports whether the tile of element is past its stale retention.
+func (c *mapCache) retired(element *list.Element) bool {
+	return time.Since(element.Value.(*tileEntry).fetchedAt) > c.ttl+mapStaleRetention
+}
+
+func (c *mapCache) remove(element *list.Element) {
+	c.recent.Remove(element)
+	delete(c.tiles, element.Value.(*tileEntry).tile)
+}
+
+// allow reports whether the user may make a request to the upstream server
+// now, and takes the request from the user's allowance if so.
+func (c *mapCache) allow(userID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.pruneLimiters(now)
+	limiter, ok := c.limiters[userID]
+	if !ok {
+		limiter = &userLimiter{limiter: rate.NewLimiter(rate.Every(mapFetchEvery), mapFetchBurst)}
+		c.limiters[userID] = limiter
+	}
+	limiter.lastUsed = now
+	return limiter.limiter.AllowN(now, 1)
+}
+
+// pruneLimiters forgets the limiters of users who have not searched for
+// limiterIdle, at most once every limiterPruneEvery. It must be called with
+// c.mu held.
+func (c *mapCache) pruneLimiters(now time.Time) {
+	if now.Sub(c.lastPrune) < limiterPruneEvery {
+		return
+	}
+	c.lastPrune = now
+	for id, limiter := range c.limiters {
+		if now.Sub(limiter.lastUsed) > limiterIdle {
+			delete(c.limiters, id)
+		}
+	}
+}
+
+func (f MapFilter) matches(place data.CatalogPlace) bool {
+	if len(f.Amenities) > 0 && !slices.Contains(f.Amenities, place.Tags["amenity"]) {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(strings.ToLower(place.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if len(f.Cuisines) == 0 {
+		return true
+	}
+	for _, cuisine := range strings.Split(place.Cuisine, ";") {
+		cuisine = strings.TrimSpace(cuisine)
+		for _, wanted := range f.Cuisines {
+			if strings.EqualFold(cuisine, wanted) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validateBox(box BoundingBox) error {
+	if !finite(box.South, box.West, box.North, box.East) {
+		return ValidationError("bounding box must be made of finite numbers")
+	}
+	if box.South < -90 || box.North > 90 || box.West < -180 || box.East > 180 {
+		return ValidationError("bounding box must lie within latitudes -90 to 90 and longitudes -180 to 180")
+	}
+	if box.South >= box.North || box.West >= box.East {
+		return ValidationError("bounding box must be south,west,north,east with south below north and west below east")
+	}
+	return nil
+}
+
+func validatePoint(point GeoPoint) error {
+	if !finite(point.Lat, point.Lon) || point.Lat < -90 || point.Lat > 90 || point.Lon < -180 || point.Lon > 180 {
+		return ValidationError("lat must lie within -90 to 90 and long within -180 to 180")
+	}
+	return nil
+}
+
+func tileAt(lat float64, long float64) mapTile {
+	return mapTile{x: int(math.Floor(long / mapTileSize)), y: int(math.Floor(lat / mapTileSize))}
+}
+
+func inBox(box BoundingBox, place data.CatalogPlace) bool {
+	return place.Lat >= box.South && place.Lat <= box.North && place.Long >= box.West && place.Long <= box.East
+}
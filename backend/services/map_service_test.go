@@ -0,0 +1,159 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// invalidPoints and invalidBoxes are the locations every search that takes
+// a point or a bounding box must reject.
+var (
+	invalidPoints = map[string]services.GeoPoint{
+		"NaN lat":         {Lat: math.NaN(), Lon: 13.4},
+		"infinite long":   {Lat: 52.5, Lon: math.Inf(-1)},
+		"lat beyond 90":   {Lat: 90.5, Lon: 13.4},
+		"long below -180": {Lat: 52.5, Lon: -181},
+	}
+	invalidBoxes = map[string]services.BoundingBox{
+		"NaN west":          {South: 0, West: math.NaN(), North: 0.01, East: 0.01},
+		"NaN north":         {South: 0, West: 0, North: math.NaN(), East: 0.01},
+		"infinite east":     {South: 0, West: 0, North: 0.01, East: math.Inf(1)},
+		"infinite south":    {South: math.Inf(-1), West: 0, North: 0.01, East: 0.01},
+		"outside the world": {South: 0, West: 0, North: 91, East: 0.01},
+		"upside down":       {South: 0.01, West: 0, North: 0, East: 0.01},
+	}
+)
+
+// rejectsAll checks that search fails with a validation error for each of
+// the named inputs.
+func rejectsAll[T any](t *testing.T, inputs map[string]T, search func(T) error) {
+	t.Helper()
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if err := search(input); errorKind(err) != services.KindValidation {
+				t.Errorf("search with %+v: got %v, want a validation error", input, err)
+			}
+		})
+	}
+}
+
+func TestSearchPlacesRejectsInvalidBoxes(t *testing.T) {
+	newUser(t, "u1")
+	search := func(box services.BoundingBox) error {
+		_, err := services.SearchPlaces(t.Context(), "u1", box, services.MapFilter{})
+		return err
+	}
+	rejectsAll(t, invalidBoxes, search)
+	rejectsAll(t, map[string]services.BoundingBox{
+		"too wide":  {South: 0, West: -180, North: 0.01, East: 180},
+		"too large": {South: 0, West: 0, North: 0.5, East: 0.5},
+	}, search)
+}
+
+// fakeSearcher returns places, or err if it is set, and counts how often it
+// was asked.
+type fakeSearcher struct {
+	places []data.CatalogPlace
+	err    error
+	calls  int
+}
+
+func (s *fakeSearcher) PlacesInBox(ctx context.Context, box services.BoundingBox) ([]data.CatalogPlace, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	var places []data.CatalogPlace
+	for _, place := range s.places {
+		if place.Lat >= box.South && place.Lat <= box.North && place.Long >= box.West && place.Long <= box.East {
+			places = append(places, place)
+		}
+	}
+	return places, nil
+}
+
+var cafe = services.NewCatalogPlace("node", "1", 52.505, 13.405, map[string]string{"name": "Cafe", "amenity": "cafe"})
+
+func TestSearchPlacesCachesTiles(t *testing.T) {
+	ctx := newUser(t, "cache-user")
+	searcher := &fakeSearcher{places: []data.CatalogPlace{cafe}}
+	services.SetPlaceSearcher(searcher, 0)
+
+	box := services.BoundingBox{South: 52.5, West: 13.4, North: 52.51, East: 13.41}
+	for range 3 {
+		result, err := services.SearchPlaces(ctx, "cache-user", box, services.MapFilter{})
+		if err != nil {
+			t.Fatalf("SearchPlaces: %v", err)
+		}
+		if len(result.Places) != 1 || result.Places[0].Key != "node/1" || result.Stale {
+			t.Errorf("SearchPlaces = %+v, want the fresh cafe", result)
+		}
+	}
+	if searcher.calls != 1 {
+		t.Errorf("searcher asked %d times, want once", searcher.calls)
+	}
+	if place, err := services.GetCatalogPlace(ctx, "node", "1"); err != nil || place.Name != "Cafe" {
+		t.Errorf("GetCatalogPlace = %+v, %v, want the fetched cafe", place, err)
+	}
+}
+
+func TestSearchPlacesServesStaleTiles(t *testing.T) {
+	ctx := newUser(t, "stale-user")
+	searcher := &fakeSearcher{places: []data.CatalogPlace{cafe}}
+	// Every tile expires at once, so every search asks the searcher.
+	services.SetPlaceSearcher(searcher, time.Nanosecond)
+
+	box := services.BoundingBox{South: 52.5, West: 13.4, North: 52.51, East: 13.41}
+	if _, err := services.SearchPlaces(ctx, "stale-user", box, services.MapFilter{}); err != nil {
+		t.Fatalf("SearchPlaces: %v", err)
+	}
+
+	searcher.err = errors.New("overpass is down")
+	result, err := services.SearchPlaces(ctx, "stale-user", box, services.MapFilter{})
+	if err != nil {
+		t.Fatalf("SearchPlaces with a failing searcher: %v", err)
+	}
+	if len(result.Places) != 1 || !result.Stale {
+		t.Errorf("SearchPlaces with a failing searcher = %+v, want the stale cafe", result)
+	}
+
+	other := services.BoundingBox{South: 10, West: 10, North: 10.01, East: 10.01}
+	if _, err := services.SearchPlaces(ctx, "stale-user", other, services.MapFilter{}); !errors.Is(err, services.ErrPlacesUnavailable) {
+		t.Errorf("SearchPlaces of an uncached area: got %v, want ErrPlacesUnavailable", err)
+	}
+}
+
+func TestSearchPlacesRateLimit(t *testing.T) {
+	ctx := newUser(t, "limited-user")
+	searcher := &fakeSearcher{}
+	services.SetPlaceSearcher(searcher, 0)
+
+	// Each box lies in its own tile, so each search asks the searcher.
+	boxAt := func(i int) services.BoundingBox {
+		lat := float64(i)
+		return services.BoundingBox{South: lat, West: 0, North: lat + 0.01, East: 0.01}
+	}
+	for i := range 5 {
+		if _, err := services.SearchPlaces(ctx, "limited-user", boxAt(i), services.MapFilter{}); err != nil {
+			t.Fatalf("search %d: %v", i+1, err)
+		}
+	}
+	if _, err := services.SearchPlaces(ctx, "limited-user", boxAt(5), services.MapFilter{}); !errors.Is(err, services.ErrRateLimited) {
+		t.Errorf("search 6: got %v, want ErrRateLimited", err)
+	}
+	if searcher.calls != 5 {
+		t.Errorf("searcher asked %d times, want 5", searcher.calls)
+	}
+
+	if _, err := services.SearchPlaces(ctx, "limited-user", boxAt(0), services.MapFilter{}); err != nil {
+		t.Errorf("search of a cached area: %v", err)
+	}
+	if _, err := services.SearchPlaces(ctx, "other-user", boxAt(5), services.MapFilter{}); err != nil {
+		t.Errorf("search by another user: %v", err)
+	}
+}
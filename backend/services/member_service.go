@@ -0,0 +1,311 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// RoleOwner is the role of a list on its owner's account. Owners invite
+	// members as editors, who can change the places of the list, or
+	// viewers, who can only read it.
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+
+	// MemberPending members have been invited but not accepted yet, so the
+	// list does not show up among their lists.
+	MemberPending  = "pending"
+	MemberAccepted = "accepted"
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// Invitation is a pending membership together with the name of the list.
+type Invitation struct {
+	data.ListMember
+	ListName string `json:"listName"`
+}
+
+// InviteMember invites another user to a list as an editor or viewer. Only
+// the owner of the list can invite members.
+func InviteMember(ctx context.Context, userID string, listRef string, memberID string, role string) (*data.ListMember, error) {
+	if err := validateMemberRole(role); err != nil {
+		return nil, err
+	}
+	if memberID == "" {
+		return nil, ValidationError("missing userId of the member")
+	}
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if memberID == ownerID {
+		return nil, ValidationError("the owner of a list cannot be invited to it")
+	}
+	if _, err := repo.GetUser(ctx, memberID); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ValidationError("no user with ID %q", memberID)
+		}
+		return nil, err
+	}
+
+	member := data.ListMember{
+		OwnerID:   ownerID,
+		ListID:    list.ID,
+		UserID:    memberID,
+		Role:      role,
+		Status:    MemberPending,
+		InvitedBy: userID,
+		InvitedAt: time.Now(),
+	}
+	if err := repo.AddListMember(ctx, member); err != nil {
+		return nil, err
+	}
+	events.publish(memberID, EventInvitationCreated, Invitation{ListMember: member, ListName: list.ListName})
+	return &member, nil
+}
+
+// GetListMembers returns the members of a list, including pending ones, to
+// anyone who can read the list.
+func GetListMembers(ctx context.Context, userID string, listRef string) ([]data.ListMember, error) {
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetListMembers(ctx, ownerID, list.ID)
+}
+
+// UpdateMemberRole changes whether a member can edit a list or only view
+// it. Only the owner of the list can change roles.
+func UpdateMemberRole(ctx context.Context, userID string, listRef string, memberID string, role string) (*data.ListMember, error) {
+	if err := validateMemberRole(role); err != nil {
+		return nil, err
+	}
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	member, err := repo.UpdateListMember(ctx, ownerID, list.ID, memberID, func(member *data.ListMember) error {
+		member.Role = role
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if member.Status == MemberAccepted {
+		if err := publishSharedList(ctx, memberID, ownerID, role, *list, EventListUpdated); err != nil {
+			return nil, err
+		}
+	}
+	return member, nil
+}
+
+// RemoveMember takes a member off a list. The owner can remove anyone, and
+// members can leave a list they accepted.
+func RemoveMember(ctx context.Context, userID string, listRef string, memberID string) error {
+	minRole := RoleOwner
+	if memberID == userID {
+		minRole = RoleViewer
+	}
+	ownerID, list, err := resolveListAccess(ctx, userID, listRef, minRole)
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteListMember(ctx, ownerID, list.ID, memberID); err != nil {
+		return err
+	}
+	return publishListRemoved(ctx, memberID, ownerID, list.ID)
+}
+
+// GetInvitations returns the invitations the user has not answered yet,
+// oldest first. Invitations to lists that no longer exist are left out.
+func GetInvitations(ctx context.Context, userID string) ([]Invitation, error) {
+	memberships, err := repo.GetMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []Invitation{}
+	for _, member := range memberships {
+		if member.Status != MemberPending {
+			continue
+		}
+		list, err := repo.GetList(ctx, member.OwnerID, member.ListID)
+		if errors.Is(err, ErrListNotFound) || errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, Invitation{ListMember: member, ListName: list.ListName})
+	}
+	return invitations, nil
+}
+
+// RespondToInvitation accepts or declines the user's invitation to a list.
+// Declining removes the invitation, so the owner can invite the user again.
+func RespondToInvitation(ctx context.Context, userID string, listID string, accept bool) (*data.ListMember, error) {
+	invitation, err := findInvitation(ctx, userID, listID)
+	if err != nil {
+		return nil, err
+	}
+	if !accept {
+		err := repo.DeleteListMember(ctx, invitation.OwnerID, invitation.ListID, userID)
+		if errors.Is(err, ErrMemberNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	member, err := repo.UpdateListMember(ctx, invitation.OwnerID, invitation.ListID, userID, func(member *data.ListMember) error {
+		if member.Status != MemberPending {
+			return ErrInvitationNotFound
+		}
+		now := time.Now()
+		member.Status = MemberAccepted
+		member.AcceptedAt = &now
+		return nil
+	})
+	if errors.Is(err, ErrMemberNotFound) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if list, err := repo.GetList(ctx, member.OwnerID, member.ListID); err == nil {
+		if err := publishSharedList(ctx, userID, member.OwnerID, member.Role, *list, EventListCreated); err != nil {
+			return nil, err
+		}
+	}
+	return member, nil
+}
+
+// resolveListAccess finds a list the user owns or has accepted an
+// invitation to, by ID or by name, and checks that the user's role on it is
+// at least minRole. The user's own lists win over shared lists of the same
+// name. It returns the ID of the list's owner, which the repository needs
+// to address the list.
+func resolveListAccess(ctx context.Context, userID string, listRef string, minRole string) (string, *data.List, error) {
+	list, err := resolveList(ctx, userID, listRef)
+	if err == nil {
+		list.OwnerID, list.Role = userID, RoleOwner
+		return userID, list, nil
+	}
+	if !errors.Is(err, ErrListNotFound) {
+		return "", nil, err
+	}
+
+	member, list, err := findSharedList(ctx, userID, listRef)
+	if err != nil {
+		return "", nil, err
+	}
+	if roleRanks[member.Role] < roleRanks[minRole] {
+		return "", nil, ErrListAccessDenied
+	}
+	return member.OwnerID, list, nil
+}
+
+// ownerOnly turns the ErrListNotFound of an operation on the user's own
+// lists into ErrListAccessDenied when the list is one shared with the user.
+func ownerOnly(ctx context.Context, userID string, listRef string, err error) error {
+	if !errors.Is(err, ErrListNotFound) {
+		return err
+	}
+	if _, _, sharedErr := findSharedList(ctx, userID, listRef); sharedErr == nil {
+		return ErrListAccessDenied
+	}
+	return err
+}
+
+func findSharedList(ctx context.Context, userID string, listRef string) (*data.ListMember, *data.List, error) {
+	lists, members, err := sharedLists(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range lists {
+		if lists[i].ID == listRef {
+			return &members[i], &lists[i], nil
+		}
+	}
+	for i := range lists {
+		if lists[i].ListName == listRef {
+			return &members[i], &lists[i], nil
+		}
+	}
+	return nil, nil, ErrListNotFound
+}
+
+// sharedLists returns the lists of other users that the user has accepted
+// invitations to, with the matching memberships.
+func sharedLists(ctx context.Context, userID string) ([]data.List, []data.ListMember, error) {
+	memberships, err := repo.GetMemberships(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lists := []data.List{}
+	members := []data.ListMember{}
+	for _, member := range memberships {
+		if member.Status != MemberAccepted {
+			continue
+		}
+		list, err := repo.GetList(ctx, member.OwnerID, member.ListID)
+		if errors.Is(err, ErrListNotFound) || errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		setListRole(list, member.OwnerID, member.Role)
+		numberPlaces(list)
+		lists = append(lists, *list)
+		members = append(members, member)
+	}
+	return lists, members, nil
+}
+
+// setListRole fills in whose list it is and the reading user's role on it.
+// Only the owner sees the share token, so members cannot share the list
+// further.
+func setListRole(list *data.List, ownerID string, role string) {
+	list.OwnerID, list.Role = ownerID, role
+	if role != RoleOwner {
+		list.ShareToken = ""
+	}
+}
+
+// withSharedLists marks the user's own lists as owned and appends the lists
+// shared with the user.
+func withSharedLists(ctx context.Context, userID string, lists []data.List) ([]data.List, error) {
+	for i := range lists {
+		lists[i].OwnerID, lists[i].Role = userID, RoleOwner
+	}
+	shared, _, err := sharedLists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return append(lists, shared...), nil
+}
+
+func findInvitation(ctx context.Context, userID string, listID string) (*data.ListMember, error) {
+	memberships, err := repo.GetMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range memberships {
+		if memberships[i].ListID == listID && memberships[i].Status == MemberPending {
+			return &memberships[i], nil
+		}
+	}
+	return nil, ErrInvitationNotFound
+}
+
+func validateMemberRole(role string) error {
+	if role != RoleEditor && role != RoleViewer {
+		return ValidationError("role must be %q or %q", RoleEditor, RoleViewer)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"errors"
+	"testing"
+)
+
+// sharedList creates a list owned by "owner" holding place 1 and invites
+// each of members, given by user ID, with its role. Only the invitations
+// of accepted members are accepted.
+func sharedList(t *testing.T, members map[string]string, accepted ...string) (context.Context, string) {
+	t.Helper()
+	ctx := newUser(t, "owner")
+	listID, err := services.CreateList(ctx, "owner", data.List{ListName: "Team lunch", Places: []data.Place{{OsmID: "1", OsmType: "node"}}})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	for memberID, role := range members {
+		if _, err := services.CreateUser(ctx, &data.User{ID: memberID}); err != nil {
+			t.Fatalf("CreateUser(%s): %v", memberID, err)
+		}
+		if _, err := services.InviteMember(ctx, "owner", listID, memberID, role); err != nil {
+			t.Fatalf("InviteMember(%s): %v", memberID, err)
+		}
+	}
+	for _, memberID := range accepted {
+		if _, err := services.RespondToInvitation(ctx, memberID, listID, true); err != nil {
+			t.Fatalf("RespondToInvitation(%s): %v", memberID, err)
+		}
+	}
+	return ctx, listID
+}
+
+func TestInviteMember(t *testing.T) {
+	ctx, listID := sharedList(t, map[string]string{"member": services.RoleEditor})
+
+	invitations, err := services.GetInvitations(ctx, "member")
+	if err != nil {
+		t.Fatalf("GetInvitations: %v", err)
+	}
+	if len(invitations) != 1 || invitations[0].ListName != "Team lunch" || invitations[0].Status != services.MemberPending {
+		t.Errorf("GetInvitations = %+v, want the pending invitation to Team lunch", invitations)
+	}
+	// A pending member cannot see the list yet.
+	if _, err := services.GetList(ctx, "member", listID); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("GetList before accepting: got %v, want ErrListNotFound", err)
+	}
+
+	if _, err := services.InviteMember(ctx, "owner", listID, "owner", services.RoleViewer); errorKind(err) != services.KindValidation {
+		t.Errorf("InviteMember of the owner: got %v, want a validation error", err)
+	}
+	if _, err := services.InviteMember(ctx, "owner", listID, "nobody", services.RoleViewer); errorKind(err) != services.KindValidation {
+		t.Errorf("InviteMember of an unknown user: got %v, want a validation error", err)
+	}
+	if _, err := services.InviteMember(ctx, "owner", listID, "member", services.RoleOwner); errorKind(err) != services.KindValidation {
+		t.Errorf("InviteMember as owner: got %v, want a validation error", err)
+	}
+}
+
+func TestRespondToInvitation(t *testing.T) {
+	ctx, listID := sharedList(t, map[string]string{"accepting": services.RoleViewer, "declining": services.RoleViewer})
+
+	member, err := services.RespondToInvitation(ctx, "accepting", listID, true)
+	if err != nil {
+		t.Fatalf("RespondToInvitation accept: %v", err)
+	}
+	if member.Status != services.MemberAccepted || member.AcceptedAt == nil {
+		t.Errorf("RespondToInvitation accept = %+v, want an accepted member", member)
+	}
+	if list, err := services.GetList(ctx, "accepting", listID); err != nil || list.OwnerID != "owner" || list.Role != services.RoleViewer {
+		t.Errorf("GetList after accepting = %+v, %v, want the owner's list as a viewer", list, err)
+	}
+	if _, err := services.RespondToInvitation(ctx, "accepting", listID, true); !errors.Is(err, services.ErrInvitationNotFound) {
+		t.Errorf("RespondToInvitation twice: got %v, want ErrInvitationNotFound", err)
+	}
+
+	if _, err := services.RespondToInvitation(ctx, "declining", listID, false); err != nil {
+		t.Fatalf("RespondToInvitation decline: %v", err)
+	}
+	if _, err := services.GetList(ctx, "declining", listID); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("GetList after declining: got %v, want ErrListNotFound", err)
+	}
+	// Declining removes the invitation, so the owner can invite again.
+	if _, err := services.InviteMember(ctx, "owner", listID, "declining", services.RoleEditor); err != nil {
+		t.Errorf("InviteMember after declining: %v", err)
+	}
+}
+
+func TestViewerCannotWrite(t *testing.T) {
+	ctx, listID := sharedList(t, map[string]string{"viewer": services.RoleViewer}, "viewer")
+
+	if _, _, err := services.AppendPlace(ctx, "viewer", listID, data.Place{OsmID: "2", OsmType: "node"}, "viewer"); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("AppendPlace as viewer: got %v, want ErrListAccessDenied", err)
+	}
+	if err := services.RemovePlace(ctx, "viewer", listID, "node/1"); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("RemovePlace as viewer: got %v, want ErrListAccessDenied", err)
+	}
+	if _, err := services.UpdateListPlace(ctx, "viewer", listID, "node/1", services.ListPlacePatch{}); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("UpdateListPlace as viewer: got %v, want ErrListAccessDenied", err)
+	}
+	if _, err := services.ReorderList(ctx, "viewer", listID, []string{"node/1"}); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("ReorderList as viewer: got %v, want ErrListAccessDenied", err)
+	}
+	if members, err := services.GetListMembers(ctx, "viewer", listID); err != nil || len(members) != 1 {
+		t.Errorf("GetListMembers as viewer = %+v, %v, want the one member", members, err)
+	}
+
+	// Once made an editor, the same member can change the places.
+	if _, err := services.UpdateMemberRole(ctx, "owner", listID, "viewer", services.RoleEditor); err != nil {
+		t.Fatalf("UpdateMemberRole: %v", err)
+	}
+	if _, _, err := services.AppendPlace(ctx, "viewer", listID, data.Place{OsmID: "2", OsmType: "node"}, "viewer"); err != nil {
+		t.Errorf("AppendPlace as editor: %v", err)
+	}
+}
+
+func TestNonOwnerCannotManageMembers(t *testing.T) {
+	ctx, listID := sharedList(t, map[string]string{"editor": services.RoleEditor, "viewer": services.RoleViewer}, "editor", "viewer")
+	if _, err := services.CreateUser(ctx, &data.User{ID: "outsider"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if _, err := services.InviteMember(ctx, "editor", listID, "outsider", services.RoleViewer); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("InviteMember as editor: got %v, want ErrListAccessDenied", err)
+	}
+	if _, err := services.UpdateMemberRole(ctx, "editor", listID, "viewer", services.RoleEditor); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("UpdateMemberRole as editor: got %v, want ErrListAccessDenied", err)
+	}
+	if _, err := services.UpdateMemberRole(ctx, "viewer", listID, "viewer", services.RoleEditor); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("UpdateMemberRole of oneself as viewer: got %v, want ErrListAccessDenied", err)
+	}
+	if err := services.RemoveMember(ctx, "editor", listID, "viewer"); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("RemoveMember of another member as editor: got %v, want ErrListAccessDenied", err)
+	}
+	if _, err := services.ShareList(ctx, "editor", listID, services.VisibilityUnlisted); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("ShareList as editor: got %v, want ErrListAccessDenied", err)
+	}
+	if err := services.DeleteList(ctx, "editor", listID); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("DeleteList as editor: got %v, want ErrListAccessDenied", err)
+	}
+	if _, err := services.GetList(ctx, "outsider", listID); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("GetList as outsider: got %v, want ErrListNotFound", err)
+	}
+
+	// Members can leave, and the owner can remove anyone.
+	if err := services.RemoveMember(ctx, "viewer", listID, "viewer"); err != nil {
+		t.Errorf("RemoveMember of oneself: %v", err)
+	}
+	if err := services.RemoveMember(ctx, "owner", listID, "editor"); err != nil {
+		t.Errorf("RemoveMember as owner: %v", err)
+	}
+	if members, err := services.GetListMembers(ctx, "owner", listID); err != nil || len(members) != 0 {
+		t.Errorf("GetListMembers after removing everyone = %+v, %v, want none", members, err)
+	}
+}
+
+func TestDeleteUserRemovesMemberships(t *testing.T) {
+	ctx, listID := sharedList(t, map[string]string{"member": services.RoleEditor}, "member")
+	if _, err := services.CreateUser(ctx, &data.User{ID: "other"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	otherListID, err := services.CreateList(ctx, "other", data.List{ListName: "Other lunch"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	if _, err := services.InviteMember(ctx, "other", otherListID, "member", services.RoleViewer); err != nil {
+		t.Fatalf("InviteMember: %v", err)
+	}
+
+	// A deleted member leaves the lists they were on.
+	if err := services.DeleteUserByID(ctx, "member"); err != nil {
+		t.Fatalf("DeleteUserByID(member): %v", err)
+	}
+	if members, err := services.GetListMembers(ctx, "owner", listID); err != nil || len(members) != 0 {
+		t.Errorf("GetListMembers after deleting the member = %+v, %v, want none", members, err)
+	}
+	if members, err := services.GetListMembers(ctx, "other", otherListID); err != nil || len(members) != 0 {
+		t.Errorf("GetListMembers of the other list = %+v, %v, want none", members, err)
+	}
+
+	// A deleted owner takes the memberships of their lists along.
+	if _, err := services.CreateUser(ctx, &data.User{ID: "member"}); err != nil {
+		t.Fatalf("CreateUser again: %v", err)
+	}
+	if _, err := services.InviteMember(ctx, "owner", listID, "member", services.RoleEditor); err != nil {
+		t.Fatalf("InviteMember again: %v", err)
+	}
+	if err := services.DeleteUserByID(ctx, "owner"); err != nil {
+		t.Fatalf("DeleteUserByID(owner): %v", err)
+	}
+	if invitations, err := services.GetInvitations(ctx, "member"); err != nil || len(invitations) != 0 {
+		t.Errorf("GetInvitations after deleting the owner = %+v, %v, want none", invitations, err)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"backend/data"
+	"backend/geo"
+	"context"
+	"strings"
+	"unicode"
+)
+
+// GeoPoint is a location in WGS84 degrees.
+type GeoPoint struct {
+	Lat float64
+	Lon float64
+}
+
+// PlaceMatcher looks up OSM places, such as from an Overpass server or a
+// local place index.
+type PlaceMatcher interface {
+	// NearbyPlaces returns, for each of points, the eating and drinking
+	// places within radius metres of it. Distances need not be set.
+	NearbyPlaces(ctx context.Context, points []GeoPoint, radius float64) ([][]data.PlaceMatch, error)
+}
+
+var placeMatcher PlaceMatcher
+
+// SetPlaceMatcher sets where imports look up OSM places. Without one,
+// imported entries are left for the user to match by hand.
+func SetPlaceMatcher(m PlaceMatcher) {
+	placeMatcher = m
+}
+
+// Distance returns the great-circle distance between a and b in metres.
+func Distance(a GeoPoint, b GeoPoint) float64 {
+	return geo.Distance(a.Lat, a.Lon, b.Lat, b.Lon)
+}
+
+// namesMatch reports whether two place names plausibly name the same place.
+// Case, punctuation and word order are ignored, and either name may add
+// words such as "Restaurant" or the street to the other.
+func namesMatch(a string, b string) bool {
+	wordsA, wordsB := nameWords(a), nameWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return false
+	}
+	joinedA, joinedB := strings.Join(wordsA, ""), strings.Join(wordsB, "")
+	if joinedA == joinedB {
+		return true
+	}
+	if len(joinedA) >= 4 && len(joinedB) >= 4 && (strings.Contains(joinedA, joinedB) || strings.Contains(joinedB, joinedA)) {
+		return true
+	}
+
+	if len(wordsA) > len(wordsB) {
+		wordsA, wordsB = wordsB, wordsA
+	}
+	inB := make(map[string]bool, len(wordsB))
+	for _, word := range wordsB {
+		inB[word] = true
+	}
+	shared := 0
+	for _, word := range wordsA {
+		if inB[word] {
+			shared++
+		}
+	}
+	return shared*2 >= len(wordsA)+1
+}
+
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
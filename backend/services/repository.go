@@ -0,0 +1,165 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+)
+
+// PlaceKind selects which of a user's place collections an operation targets.
+type PlaceKind string
+
+const (
+	VisitedPlaces PlaceKind = "visits"
+	WatchedPlaces PlaceKind = "watches"
+)
+
+// UserRepository is the storage contract the services are written against.
+// Implementations live in the storage package and are chosen in main.go.
+//
+// Lists, visited places and watched places are stored separately from the
+// user, so every method only reads and writes the data it is about. Methods
+// that take a userID return ErrUserNotFound if the user does not exist.
+type UserRepository interface {
+	// CreateUser stores a new user and returns the ID of the stored document.
+	// Only the user's own fields are stored; lists and places are ignored.
+	// It returns ErrUserAlreadyExists if a user with the same ID is present.
+	CreateUser(ctx context.Context, user *data.User) (string, error)
+	// GetUser returns the user together with all of its lists and places.
+	GetUser(ctx context.Context, userID string) (*data.User, error)
+	// DeleteUser removes the user and everything stored under it, including
+	// its memberships of other users' lists and the members of its own.
+	DeleteUser(ctx context.Context, userID string) error
+
+	// GetLists returns the user's lists in creation order.
+	GetLists(ctx context.Context, userID string) ([]data.List, error)
+	// GetList returns ErrListNotFound if the user has no such list.
+	GetList(ctx context.Context, userID string, listID string) (*data.List, error)
+	// CreateList returns ErrListNameTaken if the user already has a list
+	// with the same name.
+	CreateList(ctx context.Context, userID string, list data.List) error
+	// UpdateList atomically reads the list, passes it to update and stores
+	// the result. If update returns an error nothing is written and that
+	// error is returned unchanged. Backends retry on contention and return
+	// ErrConflict once they run out of attempts. Renaming a list to the name
+	// of another of the user's lists returns ErrListNameTaken.
+	UpdateList(ctx context.Context, userID string, listID string, update func(list *data.List) error) (*data.List, error)
+	// DeleteList removes the list and its members. It returns
+	// ErrListNotFound if the user has no such list.
+	DeleteList(ctx context.Context, userID string, listID string) error
+	// GetSharedList returns the list with the given share token and the ID
+	// of the user it belongs to, or ErrListNotFound if no list has it.
+	GetSharedList(ctx context.Context, shareToken string) (string, *data.List, error)
+
+	// GetUserPlaces returns the user's places of the given kind in the order
+	// they were added.
+	GetUserPlaces(ctx context.Context, userID string, kind PlaceKind) ([]data.UserPlace, error)
+	// AddUserPlace returns ErrPlaceExists if the user already has a place of
+	// the given kind that is the same place by SamePlace.
+	AddUserPlace(ctx context.Context, userID string, kind PlaceKind, place data.UserPlace) error
+	// UpdateUserPlace atomically applies update to the first of the user's
+	// places of the given kind that is the place with the given OSM type and
+	// ID by SamePlace, with the same semantics as UpdateList. It returns
+	// ErrPlaceNotFound if there is no such place.
+	UpdateUserPlace(ctx context.Context, userID string, kind PlaceKind, osmType string, osmID string, update func(place *data.UserPlace) error) (*data.UserPlace, error)
+	// DeleteUserPlace removes every place of the given kind that is the
+	// place with the given OSM type and ID by SamePlace and returns
+	// ErrPlaceNotFound if there was none.
+	DeleteUserPlace(ctx context.Context, userID string, kind PlaceKind, osmType string, osmID string) error
+}
+
+// APIKeyRepository stores the API keys used by server-to-server clients.
+type APIKeyRepository interface {
+	// CreateAPIKey returns ErrAPIKeyExists if the ID is taken.
+	CreateAPIKey(ctx context.Context, key data.APIKey) error
+	// GetAPIKey returns ErrAPIKeyNotFound if no key has the given ID.
+	GetAPIKey(ctx context.Context, keyID string) (*data.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]data.APIKey, error)
+	// UpdateAPIKey atomically applies update to the stored key.
+	UpdateAPIKey(ctx context.Context, keyID string, update func(key *data.APIKey) error) (*data.APIKey, error)
+	DeleteAPIKey(ctx context.Context, keyID string) error
+}
+
+// MemberRepository stores who besides the owner may access a list. A
+// member is identified by the list owner, the list ID and the member's user
+// ID.
+type MemberRepository interface {
+	// AddListMember returns ErrMemberExists if the user is already invited
+	// to or a member of the list.
+	AddListMember(ctx context.Context, member data.ListMember) error
+	// GetListMembers returns the members of a list in invitation order.
+	GetListMembers(ctx context.Context, ownerID string, listID string) ([]data.ListMember, error)
+	// GetMemberships returns the lists the user is invited to or a member of,
+	// in invitation order.
+	GetMemberships(ctx context.Context, userID string) ([]data.ListMember, error)
+	// UpdateListMember atomically applies update to a member, with the same
+	// semantics as UpdateList. It returns ErrMemberNotFound if there is no
+	// such member.
+	UpdateListMember(ctx context.Context, ownerID string, listID string, userID string, update func(member *data.ListMember) error) (*data.ListMember, error)
+	// DeleteListMember returns ErrMemberNotFound if there is no such member.
+	DeleteListMember(ctx context.Context, ownerID string, listID string, userID string) error
+}
+
+// ChangeRepository records which of a user's lists, visited places and
+// watched places changed, for clients that sync only what changed since
+// they last synced. Methods return ErrUserNotFound if the user does not
+// exist.
+type ChangeRepository interface {
+	// RecordChange stores change as the latest change to its entity, giving
+	// it the user's next sequence number, which it returns.
+	RecordChange(ctx context.Context, userID string, change data.Change) (int64, error)
+	// GetChanges returns the latest change to each entity whose sequence
+	// number is above since, in sequence order, together with the user's
+	// latest sequence number.
+	GetChanges(ctx context.Context, userID string, since int64) ([]data.Change, int64, error)
+}
+
+// ImportRepository stores imports that wait for review. Methods return
+// ErrUserNotFound if the user does not exist and ErrImportNotFound if the
+// user has no such import.
+type ImportRepository interface {
+	CreateImport(ctx context.Context, userID string, imp data.Import) error
+	GetImport(ctx context.Context, userID string, importID string) (*data.Import, error)
+	// ListImports returns the user's imports, oldest first.
+	ListImports(ctx context.Context, userID string) ([]data.Import, error)
+	// UpdateImport atomically applies update to the import, with the same
+	// semantics as UpdateList.
+	UpdateImport(ctx context.Context, userID string, importID string, update func(imp *data.Import) error) (*data.Import, error)
+	DeleteImport(ctx context.Context, userID string, importID string) error
+}
+
+// CatalogRepository stores the place catalog shared by all users. Entries
+// are identified by their key, as made by PlaceKey.
+type CatalogRepository interface {
+	// GetCatalogPlaces returns the entries with the given keys in no
+	// particular order. Keys without an entry are left out.
+	GetCatalogPlaces(ctx context.Context, keys []string) ([]data.CatalogPlace, error)
+	// SaveCatalogPlaces creates or replaces the given entries.
+	SaveCatalogPlaces(ctx context.Context, places []data.CatalogPlace) error
+}
+
+// LocationRepository caches reverse geocoding results, shared by all users.
+// Locations are identified by their key, as made by locationKey.
+type LocationRepository interface {
+	// GetLocation returns ErrLocationNotFound if the key has no entry.
+	GetLocation(ctx context.Context, key string) (*data.Location, error)
+	// SaveLocation creates or replaces the entry of location.Key.
+	SaveLocation(ctx context.Context, location data.Location) error
+}
+
+// Repository is everything a storage backend has to provide.
+type Repository interface {
+	UserRepository
+	APIKeyRepository
+	MemberRepository
+	ChangeRepository
+	ImportRepository
+	CatalogRepository
+	LocationRepository
+}
+
+var repo Repository
+
+// SetRepository sets the storage backend used by all services.
+func SetRepository(r Repository) {
+	repo = r
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"backend/data"
+	"backend/geo"
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Saved place kinds, where a user saved a place.
+	SavedInList  = "list"
+	SavedAsVisit = "visit"
+	SavedAsWatch = "watch"
+
+	// geohashLength is the length of the geohash of each place, a few
+	// metres on a side.
+	geohashLength = 9
+
+	// DefaultSavedPlaceResults and MaxSavedPlaceResults bound how many
+	// places a query returns.
+	DefaultSavedPlaceResults = 100
+	MaxSavedPlaceResults     = 500
+	// maxSavedPlaceRadius bounds radius queries, in metres.
+	maxSavedPlaceRadius = 1000 * 1000
+
+	// indexPrecision is the geohash length of the index cells, about 5 km
+	// on a side. Queries needing more than maxIndexCells of them scan all
+	// of the user's places instead.
+	indexPrecision = 5
+	maxIndexCells  = 64
+	// savedIndexMaxAge is how long an index is used. Indexes are dropped on
+	// every change to the user's places, so this only matters for
+	// coordinates that come from the catalog, which change on their own.
+	// Indexes missing some coordinates are rebuilt sooner.
+	savedIndexMaxAge      = 10 * time.Minute
+	incompleteIndexMaxAge = 10 * time.Second
+)
+
+// SavedPlace is a place the user saved in a list, visited or watches,
+// together with each of those records. Distance is in metres from the
+// point the query measured from.
+type SavedPlace struct {
+	Key      string             `json:"key"`
+	OsmType  string             `json:"osm_type"`
+	OsmID    string             `json:"osm_id"`
+	Lat      float64            `json:"lat"`
+	Long     float64            `json:"long"`
+	Geohash  string             `json:"geohash"`
+	Distance float64            `json:"distance"`
+	Lists    []SavedListEntry   `json:"lists"`
+	Visit    *data.UserPlace    `json:"visit,omitempty"`
+	Watch    *data.UserPlace    `json:"watch,omitempty"`
+	Details  *data.CatalogPlace `json:"details,omitempty"`
+}
+
+// SavedListEntry is the entry of a saved place in one of the user's lists.
+type SavedListEntry struct {
+	ListID   string `json:"list_id"`
+	ListName string `json:"list_name"`
+	OwnerID  string `json:"owner_id"`
+	Note     string `json:"note"`
+}
+
+// SavedPlaceFilter narrows a query over saved places. Empty fields match
+// every place; fields with several values match places with any of them.
+type SavedPlaceFilter struct {
+	// Kinds holds SavedInList, SavedAsVisit or SavedAsWatch.
+	Kinds []string
+	// ListIDs keeps places in one of the lists.
+	ListIDs []string
+	// Tags keeps places whose visit or watch has one of the tags.
+	Tags []string
+	// MinRating keeps visited places rated at least this.
+	MinRating *int8
+	// Limit is the number of places returned, DefaultSavedPlaceResults if 0.
+	Limit int
+}
+
+// FindSavedPlacesNear returns the user's saved places within radius metres
+// of a point that pass filter, nearest first.
+func FindSavedPlacesNear(ctx context.Context, userID string, point GeoPoint, radius float64, filter SavedPlaceFilter) ([]SavedPlace, error) {
+	if err := validatePoint(point); err != nil {
+		return nil, err
+	}
+	if !finite(radius) || radius <= 0 || radius > maxSavedPlaceRadius {
+		return nil, ValidationError("radius must be above 0 and at most %d km", maxSavedPlaceRadius/1000)
+	}
+	south, west, north, east := geo.BoxAround(point.Lat, point.Lon, radius)
+	return findSavedPlaces(ctx, userID, BoundingBox{South: south, West: west, North: north, East: east}, point, radius, filter)
+}
+
+// FindSavedPlacesIn returns the user's saved places inside box that pass
+// filter, nearest to origin first, or to the centre of box if origin is
+// nil.
+func FindSavedPlacesIn(ctx context.Context, userID string, box BoundingBox, origin *GeoPoint, filter SavedPlaceFilter) ([]SavedPlace, error) {
+	if err := validateBox(box); err != nil {
+		return nil, err
+	}
+	from := GeoPoint{Lat: (box.South + box.North) / 2, Lon: (box.West + box.East) / 2}
+	if origin != nil {
+		if err := validatePoint(*origin); err != nil {
+			return nil, err
+		}
+		from = *origin
+	}
+	return findSavedPlaces(ctx, userID, box, from, 0, filter)
+}
+
+// findSavedPlaces returns the places inside box, and within radius of from
+// if radius is set, sorted by their distance from from.
+func findSavedPlaces(ctx context.Context, userID string, box BoundingBox, from GeoPoint, radius float64, filter SavedPlaceFilter) ([]SavedPlace, error) {
+	if err := validateSavedPlaceFilter(&filter); err != nil {
+		return nil, err
+	}
+	index, err := savedPlaces.get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []SavedPlace{}
+	for _, i := range index.candidates(box) {
+		place := index.places[i]
+		if place.Lat < box.South || place.Lat > box.North || place.Long < box.West || place.Long > box.East || !filter.matches(place) {
+			continue
+		}
+		place.Distance = math.Round(Distance(from, GeoPoint{Lat: place.Lat, Lon: place.Long}))
+		if radius > 0 && place.Distance > radius {
+			continue
+		}
+		found = append(found, place)
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Distance < found[j].Distance })
+	if len(found) > filter.Limit {
+		found = found[:filter.Limit]
+	}
+	return found, nil
+}
+
+func validateSavedPlaceFilter(filter *SavedPlaceFilter) error {
+	for _, kind := range filter.Kinds {
+		if kind != SavedInList && kind != SavedAsVisit && kind != SavedAsWatch {
+			return ValidationError("kind must be %s, %s or %s", SavedInList, SavedAsVisit, SavedAsWatch)
+		}
+	}
+	if err := validateVisitRating(filter.MinRating); err != nil {
+		return err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultSavedPlaceResults
+	}
+	if filter.Limit < 0 || filter.Limit > MaxSavedPlaceResults {
+		return ValidationError("limit must be between 1 and %d", MaxSavedPlaceResults)
+	}
+	return nil
+}
+
+func (f SavedPlaceFilter) matches(place SavedPlace) bool {
+	if len(f.Kinds) > 0 {
+		saved := (slices.Contains(f.Kinds, SavedInList) && len(place.Lists) > 0) ||
+			(slices.Contains(f.Kinds, SavedAsVisit) && place.Visit != nil) ||
+			(slices.Contains(f.Kinds, SavedAsWatch) && place.Watch != nil)
+		if !saved {
+			return false
+		}
+	}
+	if len(f.ListIDs) > 0 && !slices.ContainsFunc(place.Lists, func(entry SavedListEntry) bool {
+		return slices.Contains(f.ListIDs, entry.ListID)
+	}) {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		var tags []string
+		if place.Visit != nil {
+			tags = append(tags, place.Visit.Tags...)
+		}
+		if place.Watch != nil {
+			tags = append(tags, place.Watch.Tags...)
+		}
+		if !slices.ContainsFunc(f.Tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			return false
+		}
+	}
+	if f.MinRating != nil && (place.Visit == nil || place.Visit.Rating == nil || *place.Visit.Rating < *f.MinRating) {
+		return false
+	}
+	return true
+}
+
+// savedPlaceIndex holds a user's saved places with their geohash cells.
+type savedPlaceIndex struct {
+	places []SavedPlace
+	// cells holds the positions in places of the places in each cell of
+	// indexPrecision.
+	cells   map[string][]int
+	builtAt time.Time
+	maxAge  time.Duration
+}
+
+// candidates returns the positions of the places in the cells covering box,
+// or of all places if box needs too many cells.
+func (x *savedPlaceIndex) candidates(box BoundingBox) []int {
+	cells := geo.Cover(box.South, box.West, box.North, box.East, indexPrecision, maxIndexCells)
+	if cells == nil {
+		all := make([]int, len(x.places))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	var positions []int
+	for _, cell := range cells {
+		positions = append(positions, x.cells[cell]...)
+	}
+	return positions
+}
+
+// savedPlaceCache keeps the index of each user who queried lately. It lives
+// in memory, so each server instance builds its own.
+type savedPlaceCache struct {
+	mu      sync.Mutex
+	indexes map[string]*savedPlaceIndex
+	// generations counts how often each user's index was dropped, so that
+	// an index built from data that changed meanwhile is not kept.
+	generations map[string]uint64
+	lastPrune   time.Time
+}
+
+var savedPlaces = &savedPlaceCache{indexes: make(map[string]*savedPlaceIndex), generations: make(map[string]uint64)}
+
+func (c *savedPlaceCache) get(ctx context.Context, userID string) (*savedPlaceIndex, error) {
+	c.mu.Lock()
+	c.prune()
+	index, ok := c.indexes[userID]
+	generation := c.generations[userID]
+	c.mu.Unlock()
+	if ok && time.Since(index.builtAt) < index.maxAge {
+		return index, nil
+	}
+
+	index, err := buildSavedPlaceIndex(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.generations[userID] == generation {
+		c.indexes[userID] = index
+	}
+	c.mu.Unlock()
+	return index, nil
+}
+
+// forget drops the user's index after a change to their places.
+func (c *savedPlaceCache) forget(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.indexes, userID)
+	c.generations[userID]++
+}
+
+// prune drops expired indexes. It must be called with c.mu held.
+func (c *savedPlaceCache) prune() {
+	now := time.Now()
+	if now.Sub(c.lastPrune) < time.Minute {
+		return
+	}
+	c.lastPrune = now
+	for userID, index := range c.indexes {
+		if now.Sub(index.builtAt) >= index.maxAge {
+			delete(c.indexes, userID)
+		}
+	}
+	for userID := range c.generations {
+		if _, ok := c.indexes[userID]; !ok {
+			delete(c.generations, userID)
+		}
+	}
+}
+
+// buildSavedPlaceIndex gathers the places in the user's lists, including
+// those shared with the user, and the user's visits and watches. A place
+// saved several times becomes one entry. Places are located by their
+// catalog entry, or by the coordinates saved with a list entry; places
+// with neither are left out.
+func buildSavedPlaceIndex(ctx context.Context, userID string) (*savedPlaceIndex, error) {
+	lists, err := GetLists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	visits, err := GetUserPlaces(ctx, userID, VisitedPlaces)
+	if err != nil {
+		return nil, err
+	}
+	watches, err := GetUserPlaces(ctx, userID, WatchedPlaces)
+	if err != nil {
+		return nil, err
+	}
+
+	var places []*SavedPlace
+	byID := make(map[string][]*SavedPlace)
+	entry := func(osmType string, osmID string, details *data.CatalogPlace) *SavedPlace {
+		for _, place := range byID[osmID] {
+			if SamePlace(place.OsmType, place.OsmID, osmType, osmID) {
+				if place.OsmType == "" && knownOsmType(osmType) != "" {
+					place.OsmType, place.Key = osmType, PlaceKey(osmType, osmID)
+				}
+				if place.Details == nil {
+					place.Details = details
+				}
+				return place
+			}
+		}
+		place := &SavedPlace{Key: PlaceKey(osmType, osmID), OsmType: knownOsmType(osmType), OsmID: osmID, Lists: []SavedListEntry{}, Details: details}
+		byID[osmID] = append(byID[osmID], place)
+		places = append(places, place)
+		return place
+	}
+
+	// Coordinates saved with list entries stand in for missing catalog
+	// entries.
+	listCoords := make(map[*SavedPlace]GeoPoint)
+	for _, list := range lists {
+		for _, listPlace := range list.Places {
+			place := entry(listPlace.OsmType, listPlace.OsmID, listPlace.Details)
+			place.Lists = append(place.Lists, SavedListEntry{ListID: list.ID, ListName: list.ListName, OwnerID: list.OwnerID, Note: listPlace.Note})
+			if _, ok := listCoords[place]; !ok && (listPlace.Lat != 0 || listPlace.Long != 0) {
+				listCoords[place] = GeoPoint{Lat: listPlace.Lat, Lon: listPlace.Long}
+			}
+		}
+	}
+	for i := range visits {
+		entry(visits[i].OsmType, visits[i].OsmID, visits[i].Details).Visit = &visits[i]
+	}
+	for i := range watches {
+		entry(watches[i].OsmType, watches[i].OsmID, watches[i].Details).Watch = &watches[i]
+	}
+
+	index := &savedPlaceIndex{places: []SavedPlace{}, cells: make(map[string][]int), builtAt: time.Now(), maxAge: savedIndexMaxAge}
+	for _, place := range places {
+		if place.Details != nil {
+			place.Lat, place.Long = place.Details.Lat, place.Details.Long
+		} else if point, ok := listCoords[place]; ok {
+			place.Lat, place.Long = point.Lat, point.Lon
+		} else {
+			// The catalog may not have looked the place up yet.
+			index.maxAge = incompleteIndexMaxAge
+			continue
+		}
+		place.Geohash = geo.Encode(place.Lat, place.Long, geohashLength)
+		cell := place.Geohash[:indexPrecision]
+		index.cells[cell] = append(index.cells[cell], len(index.places))
+		index.places = append(index.places, *place)
+	}
+	return index, nil
+}
@@ -0,0 +1,29 @@
+package services_test
+
+import (
+	"backend/services"
+	"math"
+	"testing"
+)
+
+func TestFindSavedPlacesRejectsInvalidPoints(t *testing.T) {
+	ctx := newUser(t, "u1")
+	rejectsAll(t, invalidPoints, func(point services.GeoPoint) error {
+		_, err := services.FindSavedPlacesNear(ctx, "u1", point, 1000, services.SavedPlaceFilter{})
+		return err
+	})
+	rejectsAll(t, map[string]float64{"NaN radius": math.NaN(), "infinite radius": math.Inf(1)}, func(radius float64) error {
+		_, err := services.FindSavedPlacesNear(ctx, "u1", services.GeoPoint{Lat: 52.5, Lon: 13.4}, radius, services.SavedPlaceFilter{})
+		return err
+	})
+
+	rejectsAll(t, invalidBoxes, func(box services.BoundingBox) error {
+		_, err := services.FindSavedPlacesIn(ctx, "u1", box, nil, services.SavedPlaceFilter{})
+		return err
+	})
+	box := services.BoundingBox{South: 52.4, West: 13.3, North: 52.6, East: 13.5}
+	rejectsAll(t, invalidPoints, func(origin services.GeoPoint) error {
+		_, err := services.FindSavedPlacesIn(ctx, "u1", box, &origin, services.SavedPlaceFilter{})
+		return err
+	})
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+)
+
+const (
+	// VisibilityPrivate lists can only be read by their owner. Lists stored
+	// before sharing existed have an empty visibility, which means the same.
+	VisibilityPrivate = "private"
+	// VisibilityUnlisted lists can be read by anyone who has the link.
+	VisibilityUnlisted = "unlisted"
+	// legacyVisibilityPublic was offered next to unlisted while lists could
+	// be listed on their owner's profile. Lists still stored with it are read
+	// as unlisted.
+	legacyVisibilityPublic = "public"
+)
+
+// PublicList is what everyone may see of a shared list.
+type PublicList struct {
+	ShareToken  string       `json:"share_token"`
+	ListName    string       `json:"list_name"`
+	Description string       `json:"description"`
+	Colour      string       `json:"colour"`
+	Icon        string       `json:"icon"`
+	Places      []data.Place `json:"places"`
+}
+
+// ShareList sets the visibility of a list. Sharing a list that has no share
+// token yet creates one; making it private revokes the token, so old links
+// stop working even if the list is shared again later.
+func ShareList(ctx context.Context, userID string, listRef string, visibility string) (*data.List, error) {
+	switch visibility {
+	case VisibilityPrivate, VisibilityUnlisted:
+	default:
+		return nil, ValidationError("visibility must be %q or %q", VisibilityPrivate, VisibilityUnlisted)
+	}
+
+	token := ""
+	if visibility != VisibilityPrivate {
+		var err error
+		if token, err = newShareToken(); err != nil {
+			return nil, err
+		}
+	}
+	return updateListSharing(ctx, userID, listRef, func(list *data.List) error {
+		list.Visibility = visibility
+		if visibility == VisibilityPrivate {
+			list.ShareToken = ""
+		} else if list.ShareToken == "" {
+			list.ShareToken = token
+		}
+		return nil
+	})
+}
+
+// RegenerateShareToken gives a shared list a new share token. Links with
+// the old token stop working.
+func RegenerateShareToken(ctx context.Context, userID string, listRef string) (*data.List, error) {
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	return updateListSharing(ctx, userID, listRef, func(list *data.List) error {
+		if !isShared(*list) {
+			return ValidationError("list is not shared")
+		}
+		list.ShareToken = token
+		return nil
+	})
+}
+
+// GetSharedList returns the list with the given share token. Lists that
+// were made private are reported as not found.
+func GetSharedList(ctx context.Context, shareToken string) (*PublicList, error) {
+	if shareToken == "" {
+		return nil, ErrListNotFound
+	}
+	_, list, err := repo.GetSharedList(ctx, shareToken)
+	if err != nil {
+		return nil, err
+	}
+	if !isShared(*list) {
+		return nil, ErrListNotFound
+	}
+	public := publicList(*list)
+	fillPlaceDetails(ctx, listEntries(public.Places)...)
+	return &public, nil
+}
+
+func updateListSharing(ctx context.Context, userID string, listRef string, update func(list *data.List) error) (*data.List, error) {
+	list, err := resolveList(ctx, userID, listRef)
+	if err != nil {
+		return nil, ownerOnly(ctx, userID, listRef, err)
+	}
+	list, err = updateList(ctx, userID, list.ID, update)
+	if err != nil {
+		return nil, err
+	}
+	numberPlaces(list)
+	fillPlaceDetails(ctx, listEntries(list.Places)...)
+	return list, nil
+}
+
+func isShared(list data.List) bool {
+	return (list.Visibility == VisibilityUnlisted || list.Visibility == legacyVisibilityPublic) && list.ShareToken != ""
+}
+
+// publicList leaves out who added each place, as that holds user IDs.
+func publicList(list data.List) PublicList {
+	places := make([]data.Place, len(list.Places))
+	for i, place := range list.Places {
+		place.AddedBy = ""
+		place.Position = i
+		places[i] = place
+	}
+	return PublicList{
+		ShareToken:  list.ShareToken,
+		ListName:    list.ListName,
+		Description: list.Description,
+		Colour:      list.Colour,
+		Icon:        list.Icon,
+		Places:      places,
+	}
+}
+
+// newShareToken returns 192 random bits, which cannot be guessed.
+func newShareToken() (string, error) {
+	return randomString(24)
+}
@@ -0,0 +1,121 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"backend/storage"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestShareList(t *testing.T) {
+	ctx := newUser(t, "u1")
+	listID, err := services.CreateList(ctx, "u1", data.List{ListName: "Best ramen", Places: []data.Place{{OsmID: "1", OsmType: "node"}}})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+
+	shared, err := services.ShareList(ctx, "u1", listID, services.VisibilityUnlisted)
+	if err != nil {
+		t.Fatalf("ShareList: %v", err)
+	}
+	if shared.ShareToken == "" {
+		t.Fatalf("ShareList = %+v, want a share token", shared)
+	}
+	public, err := services.GetSharedList(ctx, shared.ShareToken)
+	if err != nil {
+		t.Fatalf("GetSharedList: %v", err)
+	}
+	if public.ListName != "Best ramen" || len(public.Places) != 1 || public.Places[0].AddedBy != "" {
+		t.Errorf("GetSharedList = %+v, want the list without who added its places", public)
+	}
+
+	regenerated, err := services.RegenerateShareToken(ctx, "u1", listID)
+	if err != nil {
+		t.Fatalf("RegenerateShareToken: %v", err)
+	}
+	if _, err := services.GetSharedList(ctx, shared.ShareToken); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("GetSharedList with the old token: got %v, want ErrListNotFound", err)
+	}
+
+	if _, err := services.ShareList(ctx, "u1", listID, "public"); errorKind(err) != services.KindValidation {
+		t.Errorf("ShareList public: got %v, want a validation error", err)
+	}
+	if _, err := services.ShareList(ctx, "u1", listID, services.VisibilityPrivate); err != nil {
+		t.Fatalf("ShareList private: %v", err)
+	}
+	if _, err := services.GetSharedList(ctx, regenerated.ShareToken); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("GetSharedList after revoking: got %v, want ErrListNotFound", err)
+	}
+}
+
+func TestSharedListHidesShareTokenFromMembers(t *testing.T) {
+	ctx := newUser(t, "owner")
+	if _, err := services.CreateUser(ctx, &data.User{ID: "member"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	listID, err := services.CreateList(ctx, "owner", data.List{ListName: "Team lunch"})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	if _, err := services.ShareList(ctx, "owner", listID, services.VisibilityUnlisted); err != nil {
+		t.Fatalf("ShareList: %v", err)
+	}
+	if _, err := services.InviteMember(ctx, "owner", listID, "member", services.RoleEditor); err != nil {
+		t.Fatalf("InviteMember: %v", err)
+	}
+	if _, err := services.RespondToInvitation(ctx, "member", listID, true); err != nil {
+		t.Fatalf("RespondToInvitation: %v", err)
+	}
+
+	owned, err := services.GetList(ctx, "owner", listID)
+	if err != nil {
+		t.Fatalf("GetList as owner: %v", err)
+	}
+	if owned.ShareToken == "" {
+		t.Errorf("GetList as owner = %+v, want the share token", owned)
+	}
+
+	list, err := services.GetList(ctx, "member", listID)
+	if err != nil {
+		t.Fatalf("GetList as member: %v", err)
+	}
+	if list.ShareToken != "" || list.Role != services.RoleEditor {
+		t.Errorf("GetList as member = %+v, want the editor's view without a share token", list)
+	}
+	lists, err := services.GetLists(ctx, "member")
+	if err != nil {
+		t.Fatalf("GetLists as member: %v", err)
+	}
+	if len(lists) != 1 || lists[0].ShareToken != "" {
+		t.Errorf("GetLists as member = %+v, want the shared list without a share token", lists)
+	}
+	reordered, err := services.ReorderList(ctx, "member", listID, []string{})
+	if err != nil {
+		t.Fatalf("ReorderList as member: %v", err)
+	}
+	if reordered.ShareToken != "" {
+		t.Errorf("ReorderList as member = %+v, want no share token", reordered)
+	}
+	if _, err := services.RegenerateShareToken(ctx, "member", listID); !errors.Is(err, services.ErrListAccessDenied) {
+		t.Errorf("RegenerateShareToken as member: got %v, want ErrListAccessDenied", err)
+	}
+}
+
+func TestLegacyPublicListStaysShared(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	services.SetRepository(repo)
+	ctx := context.Background()
+	if _, err := services.CreateUser(ctx, &data.User{ID: "u1"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	// A list as it was stored while public was offered.
+	if err := repo.CreateList(ctx, "u1", data.List{ID: "l1", ListName: "Old", Visibility: "public", ShareToken: "token"}); err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+
+	if list, err := services.GetSharedList(ctx, "token"); err != nil || list.ListName != "Old" {
+		t.Errorf("GetSharedList = %+v, %v, want the list shared as unlisted", list, err)
+	}
+}
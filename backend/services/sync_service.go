@@ -0,0 +1,334 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Change kinds, the kinds of entities that sync tracks.
+	ChangeList  = "list"
+	ChangeVisit = "visit"
+	ChangeWatch = "watch"
+
+	// MaxSyncMutations caps the mutations a client may send in one sync.
+	MaxSyncMutations = 500
+
+	// Mutation operations. Lists are addressed by ID and places by OSM ID,
+	// qualified by OSM type where the client knows it.
+	OpListCreate      = "list.create"
+	OpListUpdate      = "list.update"
+	OpListDelete      = "list.delete"
+	OpListPlaceAdd    = "list.place.add"
+	OpListPlaceUpdate = "list.place.update"
+	OpListPlaceRemove = "list.place.remove"
+	OpVisitRecord     = "visit.record"
+	OpVisitDelete     = "visit.delete"
+	OpRatingSet       = "rating.set"
+	OpRatingClear     = "rating.clear"
+	OpWatchAdd        = "watch.add"
+	OpWatchDelete     = "watch.delete"
+
+	// MutationApplied mutations changed the server state or found it
+	// already as they would have left it. MutationRejected mutations were
+	// dropped; the client takes the server state from the sync result.
+	MutationApplied  = "applied"
+	MutationRejected = "rejected"
+)
+
+// Mutation is a change a client made while offline. Which fields are used
+// depends on Op.
+type Mutation struct {
+	ID          string
+	Op          string
+	ListID      string
+	OsmID       string
+	OsmType     string
+	ListName    *string
+	Description *string
+	Colour      *string
+	Icon        *string
+	Place       *data.Place
+	Note        *string
+	Position    *int
+	Visit       *data.Visit
+	Rating      *int8
+	Tags        []string
+}
+
+// MutationResult reports what became of a mutation. Code and Error say why
+// a mutation was rejected.
+type MutationResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Tombstone marks a list or place that was deleted, or a list that is no
+// longer shared with the user.
+type Tombstone struct {
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// SyncResult holds the entities that changed since the client's token, in
+// their current state. With Full set it holds all of them instead, and the
+// client replaces its data. Token is passed to the next sync.
+type SyncResult struct {
+	Token      string           `json:"token"`
+	Full       bool             `json:"full"`
+	Lists      []data.List      `json:"lists"`
+	Visits     []data.UserPlace `json:"visits"`
+	Watches    []data.UserPlace `json:"watches"`
+	Tombstones []Tombstone      `json:"tombstones"`
+	Results    []MutationResult `json:"results"`
+}
+
+// Sync applies a client's queued mutations and returns what changed since
+// token, or everything if token is empty or unknown to the server.
+//
+// Mutations are applied in order, each to the server state left by the
+// ones before, so conflicts resolve the same way every time:
+//   - field updates are applied over the server state, so the last write
+//     wins;
+//   - a deletion wins over edits: edits to a list or place that no longer
+//     exists are rejected, and deleting one that is already gone is applied;
+//   - adding a place that is already there, watching a watched place and
+//     creating a list with an existing ID are applied without a change.
+//
+// A rejected mutation does not stop the ones after it.
+func Sync(ctx context.Context, userID string, token string, mutations []Mutation, addedBy string) (*SyncResult, error) {
+	if len(mutations) > MaxSyncMutations {
+		return nil, ValidationError("a sync may hold at most %d mutations", MaxSyncMutations)
+	}
+	since, full := int64(0), token == ""
+	if !full {
+		var err error
+		if since, err = strconv.ParseInt(token, 10, 64); err != nil || since < 0 {
+			return nil, ValidationError("invalid sync token %q", token)
+		}
+	}
+
+	results := make([]MutationResult, len(mutations))
+	for i, mutation := range mutations {
+		results[i] = applyMutation(ctx, userID, mutation, addedBy)
+	}
+
+	// The latest sequence number is read before the entities, so changes
+	// made in between are sent again next time rather than missed.
+	changes, latest, err := repo.GetChanges(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	if since > latest {
+		// The token is from another server or database.
+		full = true
+	}
+
+	result := &SyncResult{
+		Token:      strconv.FormatInt(latest, 10),
+		Full:       full,
+		Lists:      []data.List{},
+		Visits:     []data.UserPlace{},
+		Watches:    []data.UserPlace{},
+		Tombstones: []Tombstone{},
+		Results:    results,
+	}
+	if full {
+		if result.Lists, err = GetLists(ctx, userID); err != nil {
+			return nil, err
+		}
+		if result.Visits, err = GetUserPlaces(ctx, userID, VisitedPlaces); err != nil {
+			return nil, err
+		}
+		if result.Watches, err = GetUserPlaces(ctx, userID, WatchedPlaces); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if err := fillDelta(ctx, userID, changes, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fillDelta adds the current state of each changed entity to result, or a
+// tombstone if it is gone.
+func fillDelta(ctx context.Context, userID string, changes []data.Change, result *SyncResult) error {
+	changed := map[string]bool{}
+	for _, change := range changes {
+		if !change.Deleted {
+			changed[change.Kind] = true
+		}
+	}
+
+	var lists []data.List
+	var visits, watches []data.UserPlace
+	var err error
+	if changed[ChangeList] {
+		if lists, err = GetLists(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if changed[ChangeVisit] {
+		if visits, err = GetUserPlaces(ctx, userID, VisitedPlaces); err != nil {
+			return err
+		}
+	}
+	if changed[ChangeWatch] {
+		if watches, err = GetUserPlaces(ctx, userID, WatchedPlaces); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		found := false
+		if !change.Deleted {
+			switch change.Kind {
+			case ChangeList:
+				for _, list := range lists {
+					if list.ID == change.Key {
+						result.Lists = append(result.Lists, list)
+						found = true
+						break
+					}
+				}
+			case ChangeVisit:
+				if place := findUserPlaceById(visits, change.Key); place != nil {
+					result.Visits = append(result.Visits, *place)
+					found = true
+				}
+			case ChangeWatch:
+				if place := findUserPlaceById(watches, change.Key); place != nil {
+					result.Watches = append(result.Watches, *place)
+					found = true
+				}
+			}
+		}
+		if !found {
+			// Deleted, or deleted after the change was recorded.
+			result.Tombstones = append(result.Tombstones, Tombstone{Kind: change.Kind, ID: change.Key, DeletedAt: change.ChangedAt})
+		}
+	}
+	return nil
+}
+
+func applyMutation(ctx context.Context, userID string, mutation Mutation, addedBy string) MutationResult {
+	result := MutationResult{ID: mutation.ID, Status: MutationApplied}
+	if err := mutate(ctx, userID, mutation, addedBy); err != nil {
+		var domainErr *Error
+		if !errors.As(err, &domainErr) {
+			log.Printf("Error applying %s mutation %q: %v", mutation.Op, mutation.ID, err)
+			domainErr = &Error{Code: "internal_error", Message: "internal server error"}
+		}
+		result.Status, result.Code, result.Error = MutationRejected, domainErr.Code, domainErr.Message
+	}
+	return result
+}
+
+func mutate(ctx context.Context, userID string, m Mutation, addedBy string) error {
+	placeKey := PlaceKey(m.OsmType, m.OsmID)
+	switch m.Op {
+	case OpListCreate:
+		if err := uuid.Validate(m.ListID); err != nil {
+			return ValidationError("list_id of a new list must be a UUID")
+		}
+		if _, err := repo.GetList(ctx, userID, m.ListID); err == nil {
+			return nil
+		}
+		list := data.List{ID: m.ListID}
+		if m.ListName != nil {
+			list.ListName = *m.ListName
+		}
+		if m.Description != nil {
+			list.Description = *m.Description
+		}
+		if m.Colour != nil {
+			list.Colour = *m.Colour
+		}
+		if m.Icon != nil {
+			list.Icon = *m.Icon
+		}
+		_, err := CreateList(ctx, userID, list)
+		return err
+	case OpListUpdate:
+		_, err := UpdateListDetails(ctx, userID, m.ListID, ListPatch{ListName: m.ListName, Description: m.Description, Colour: m.Colour, Icon: m.Icon})
+		return err
+	case OpListDelete:
+		return alreadyApplied(DeleteList(ctx, userID, m.ListID), ErrListNotFound)
+	case OpListPlaceAdd:
+		if m.Place == nil {
+			return ValidationError("missing place")
+		}
+		_, _, err := AppendPlace(ctx, userID, m.ListID, *m.Place, addedBy)
+		return err
+	case OpListPlaceUpdate:
+		_, err := UpdateListPlace(ctx, userID, m.ListID, placeKey, ListPlacePatch{Note: m.Note, Position: m.Position})
+		return err
+	case OpListPlaceRemove:
+		return alreadyApplied(RemovePlace(ctx, userID, m.ListID, placeKey), ErrPlaceNotFound)
+	case OpVisitRecord:
+		visit := data.Visit{}
+		if m.Visit != nil {
+			visit = *m.Visit
+		}
+		_, _, err := RecordVisit(ctx, userID, placeKey, visit)
+		return err
+	case OpVisitDelete:
+		return alreadyApplied(DeleteUserPlace(ctx, userID, VisitedPlaces, placeKey), ErrPlaceNotFound)
+	case OpRatingSet:
+		if m.Rating == nil {
+			return ValidationError("missing rating")
+		}
+		_, err := SetRating(ctx, userID, placeKey, *m.Rating)
+		return err
+	case OpRatingClear:
+		_, err := ClearRating(ctx, userID, placeKey)
+		return err
+	case OpWatchAdd:
+		_, err := WatchPlace(ctx, userID, data.UserPlace{OsmID: m.OsmID, OsmType: m.OsmType, Tags: m.Tags})
+		return alreadyApplied(err, ErrPlaceExists)
+	case OpWatchDelete:
+		return alreadyApplied(DeleteUserPlace(ctx, userID, WatchedPlaces, placeKey), ErrPlaceNotFound)
+	}
+	return ValidationError("unknown op %q", m.Op)
+}
+
+// alreadyApplied treats target, an error saying that the server state is
+// already what the mutation would make it, as success.
+func alreadyApplied(err error, target error) error {
+	if errors.Is(err, target) {
+		return nil
+	}
+	return err
+}
+
+// recordChange records a change for sync and drops the user's saved place
+// index. Clients holding a sync token only hear of changes through these
+// records, so a failure fails the request, and the client retries the
+// change, which is saved already, to have it recorded. Changes of deleted
+// users are not recorded.
+func recordChange(ctx context.Context, userID string, kind string, key string, deleted bool) error {
+	savedPlaces.forget(userID)
+	change := data.Change{Kind: kind, Key: key, Deleted: deleted, ChangedAt: time.Now()}
+	if _, err := repo.RecordChange(ctx, userID, change); err != nil && !errors.Is(err, ErrUserNotFound) {
+		return fmt.Errorf("recording %s change for user %s: %w", kind, userID, err)
+	}
+	return nil
+}
+
+func changeKind(kind PlaceKind) string {
+	if kind == VisitedPlaces {
+		return ChangeVisit
+	}
+	return ChangeWatch
+}
@@ -0,0 +1,166 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"backend/storage"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSyncFullThenDelta(t *testing.T) {
+	ctx := newUser(t, "u1")
+	listID, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch", Places: []data.Place{{OsmID: "1", OsmType: "node"}}})
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	if _, err := services.WatchPlace(ctx, "u1", data.UserPlace{OsmID: "2", OsmType: "node"}); err != nil {
+		t.Fatalf("WatchPlace: %v", err)
+	}
+
+	full, err := services.Sync(ctx, "u1", "", nil, "u1")
+	if err != nil {
+		t.Fatalf("Sync without a token: %v", err)
+	}
+	if !full.Full || len(full.Lists) != 1 || len(full.Watches) != 1 || full.Token == "" {
+		t.Fatalf("Sync without a token = %+v, want everything with a token", full)
+	}
+
+	unchanged, err := services.Sync(ctx, "u1", full.Token, nil, "u1")
+	if err != nil {
+		t.Fatalf("Sync with a token: %v", err)
+	}
+	if unchanged.Full || len(unchanged.Lists) != 0 || len(unchanged.Watches) != 0 || len(unchanged.Tombstones) != 0 || unchanged.Token != full.Token {
+		t.Errorf("Sync without changes = %+v, want an empty delta", unchanged)
+	}
+
+	if _, _, err := services.RecordVisit(ctx, "u1", "node/3", data.Visit{}); err != nil {
+		t.Fatalf("RecordVisit: %v", err)
+	}
+	if err := services.DeleteUserPlace(ctx, "u1", services.WatchedPlaces, "node/2"); err != nil {
+		t.Fatalf("DeleteUserPlace: %v", err)
+	}
+	delta, err := services.Sync(ctx, "u1", full.Token, nil, "u1")
+	if err != nil {
+		t.Fatalf("Sync after changes: %v", err)
+	}
+	if delta.Full || len(delta.Lists) != 0 || len(delta.Visits) != 1 || delta.Visits[0].OsmID != "3" {
+		t.Errorf("Sync after changes = %+v, want the new visit only", delta)
+	}
+	if len(delta.Tombstones) != 1 || delta.Tombstones[0].Kind != services.ChangeWatch || delta.Tombstones[0].ID != "node/2" {
+		t.Errorf("Sync tombstones = %+v, want the deleted watch", delta.Tombstones)
+	}
+
+	// A list deleted after it changed is sent as a tombstone, not as a list.
+	if _, err := services.ReorderList(ctx, "u1", listID, []string{"node/1"}); err != nil {
+		t.Fatalf("ReorderList: %v", err)
+	}
+	if err := services.DeleteList(ctx, "u1", listID); err != nil {
+		t.Fatalf("DeleteList: %v", err)
+	}
+	deleted, err := services.Sync(ctx, "u1", delta.Token, nil, "u1")
+	if err != nil {
+		t.Fatalf("Sync after deleting the list: %v", err)
+	}
+	if len(deleted.Lists) != 0 || len(deleted.Tombstones) != 1 || deleted.Tombstones[0].ID != listID {
+		t.Errorf("Sync after deleting the list = %+v, want its tombstone", deleted)
+	}
+}
+
+func TestSyncTokens(t *testing.T) {
+	ctx := newUser(t, "u1")
+	for _, token := range []string{"abc", "-1"} {
+		if _, err := services.Sync(ctx, "u1", token, nil, "u1"); errorKind(err) != services.KindValidation {
+			t.Errorf("Sync(%q): got %v, want a validation error", token, err)
+		}
+	}
+	// A token from another database is ahead of this one's changes.
+	result, err := services.Sync(ctx, "u1", "1000", nil, "u1")
+	if err != nil || !result.Full {
+		t.Errorf("Sync with an unknown token = %+v, %v, want a full sync", result, err)
+	}
+}
+
+func TestSyncMutationLimit(t *testing.T) {
+	ctx := newUser(t, "u1")
+	mutations := make([]services.Mutation, services.MaxSyncMutations+1)
+	for i := range mutations {
+		mutations[i] = services.Mutation{ID: uuid.NewString(), Op: services.OpWatchAdd, OsmID: "1", OsmType: "node"}
+	}
+	if _, err := services.Sync(ctx, "u1", "", mutations, "u1"); errorKind(err) != services.KindValidation {
+		t.Errorf("Sync of %d mutations: got %v, want a validation error", len(mutations), err)
+	}
+	if watches, err := services.GetUserPlaces(ctx, "u1", services.WatchedPlaces); err != nil || len(watches) != 0 {
+		t.Errorf("GetUserPlaces after the rejected sync = %+v, %v, want nothing applied", watches, err)
+	}
+
+	if _, err := services.Sync(ctx, "u1", "", mutations[:services.MaxSyncMutations], "u1"); err != nil {
+		t.Errorf("Sync of %d mutations: %v", services.MaxSyncMutations, err)
+	}
+}
+
+func TestSyncReplaysMutations(t *testing.T) {
+	ctx := newUser(t, "u1")
+	listID, name := uuid.NewString(), "Offline"
+	mutations := []services.Mutation{
+		{ID: "m1", Op: services.OpListCreate, ListID: listID, ListName: &name},
+		{ID: "m2", Op: services.OpListPlaceAdd, ListID: listID, Place: &data.Place{OsmID: "1", OsmType: "node"}},
+		{ID: "m3", Op: services.OpWatchAdd, OsmID: "2", OsmType: "node"},
+		{ID: "m4", Op: services.OpWatchDelete, OsmID: "2", OsmType: "node"},
+		{ID: "m5", Op: services.OpListPlaceRemove, ListID: listID, OsmID: "9", OsmType: "node"},
+	}
+
+	// A client that lost the response to its sync sends it again.
+	for attempt := 1; attempt <= 2; attempt++ {
+		result, err := services.Sync(ctx, "u1", "", mutations, "u1")
+		if err != nil {
+			t.Fatalf("Sync attempt %d: %v", attempt, err)
+		}
+		for _, mutation := range result.Results {
+			if mutation.Status != services.MutationApplied {
+				t.Errorf("attempt %d: mutation %s = %+v, want applied", attempt, mutation.ID, mutation)
+			}
+		}
+		if len(result.Lists) != 1 || len(result.Lists[0].Places) != 1 || len(result.Watches) != 0 {
+			t.Errorf("attempt %d: Sync = %+v, want one list with one place and no watches", attempt, result)
+		}
+	}
+
+	rejected, err := services.Sync(ctx, "u1", "", []services.Mutation{
+		{ID: "m6", Op: services.OpListUpdate, ListID: uuid.NewString(), ListName: &name},
+		{ID: "m7", Op: "list.archive"},
+	}, "u1")
+	if err != nil {
+		t.Fatalf("Sync of invalid mutations: %v", err)
+	}
+	if rejected.Results[0].Status != services.MutationRejected || rejected.Results[0].Code != "list_not_found" || rejected.Results[1].Status != services.MutationRejected {
+		t.Errorf("Sync of invalid mutations = %+v, want both rejected", rejected.Results)
+	}
+}
+
+// failingChanges is a repository that cannot record changes.
+type failingChanges struct {
+	*storage.MemoryRepository
+}
+
+func (failingChanges) RecordChange(ctx context.Context, userID string, change data.Change) (int64, error) {
+	return 0, errors.New("database unavailable")
+}
+
+func TestChangeRecordFailureFailsRequest(t *testing.T) {
+	ctx := context.Background()
+	services.SetRepository(failingChanges{storage.NewMemoryRepository()})
+	if _, err := services.CreateUser(ctx, &data.User{ID: "u1"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if _, err := services.WatchPlace(ctx, "u1", data.UserPlace{OsmID: "1", OsmType: "node"}); err == nil {
+		t.Error("WatchPlace: got no error although its change was not recorded")
+	}
+	if _, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch"}); err == nil {
+		t.Error("CreateList: got no error although its change was not recorded")
+	}
+}
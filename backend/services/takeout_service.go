@@ -0,0 +1,373 @@
+package services
+
+import (
+	"backend/data"
+	"backend/formats"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// TakeoutSource marks imports of Google Takeout saved places.
+	TakeoutSource = "google_takeout"
+	// MaxTakeoutEntries keeps a stored import well within Firestore's
+	// 1 MiB document limit.
+	MaxTakeoutEntries = 1000
+
+	// EntryMatched entries were matched to an OSM place automatically,
+	// EntryUnmatched entries were not. The user reviews entries by
+	// confirming them, possibly with another place, or dropping them.
+	EntryMatched   = "matched"
+	EntryUnmatched = "unmatched"
+	EntryConfirmed = "confirmed"
+	EntryDropped   = "dropped"
+
+	// matchRadius is how far, in metres, an OSM place may be from a saved
+	// place to be matched with it.
+	matchRadius        = 150
+	maxMatchCandidates = 3
+)
+
+// ImportEntryReview is the user's decision on an import entry. Match picks
+// the place to confirm the entry with; without it, the automatic match is
+// confirmed.
+type ImportEntryReview struct {
+	Status string
+	Match  *data.PlaceMatch
+}
+
+// ImportSummary describes an import without its entries. Counts holds the
+// number of entries per status.
+type ImportSummary struct {
+	ID        string         `json:"id"`
+	Source    string         `json:"source"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Entries   int            `json:"entries"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// ImportCommitResult reports what committing an import added to each list.
+// Skipped holds the unmatched entries that were neither confirmed nor
+// dropped.
+type ImportCommitResult struct {
+	Lists   []ImportResult `json:"lists"`
+	Skipped []int          `json:"skipped"`
+}
+
+// StartTakeoutImport matches places saved in Google Maps to OSM places and
+// stores the result as an import for the user to review. Nothing is added
+// to the user's lists until the import is committed.
+func StartTakeoutImport(ctx context.Context, userID string, places []formats.TakeoutPlace) (*data.Import, error) {
+	if len(places) == 0 {
+		return nil, ValidationError("the files hold no saved places")
+	}
+	if len(places) > MaxTakeoutEntries {
+		return nil, ValidationError("an import may hold at most %d places, upload the lists separately", MaxTakeoutEntries)
+	}
+
+	entries := make([]data.ImportEntry, len(places))
+	for i, place := range places {
+		listName := truncateRunes(strings.TrimSpace(place.List), maxListNameLength)
+		if listName == "" {
+			listName = formats.StarredPlacesList
+		}
+		entries[i] = data.ImportEntry{
+			ID:          i + 1,
+			List:        listName,
+			Title:       place.Title,
+			Address:     place.Address,
+			Note:        place.Note,
+			URL:         place.URL,
+			SavedAt:     place.SavedAt,
+			HasLocation: place.HasLocation,
+			Lat:         place.Lat,
+			Long:        place.Lon,
+			Status:      EntryUnmatched,
+			Candidates:  []data.PlaceMatch{},
+		}
+	}
+	shareLocations(entries)
+	if err := matchEntries(ctx, entries); err != nil {
+		return nil, err
+	}
+
+	imp := data.Import{
+		ID:        uuid.New().String(),
+		Source:    TakeoutSource,
+		CreatedAt: time.Now(),
+		Entries:   entries,
+	}
+	if err := repo.CreateImport(ctx, userID, imp); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+func GetImport(ctx context.Context, userID string, importID string) (*data.Import, error) {
+	return repo.GetImport(ctx, userID, importID)
+}
+
+// ListImports returns the user's imports that have not been committed or
+// deleted yet, oldest first.
+func ListImports(ctx context.Context, userID string) ([]ImportSummary, error) {
+	imports, err := repo.ListImports(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]ImportSummary, len(imports))
+	for i, imp := range imports {
+		counts := map[string]int{EntryMatched: 0, EntryUnmatched: 0, EntryConfirmed: 0, EntryDropped: 0}
+		for _, entry := range imp.Entries {
+			counts[entry.Status]++
+		}
+		summaries[i] = ImportSummary{
+			ID:        imp.ID,
+			Source:    imp.Source,
+			CreatedAt: imp.CreatedAt,
+			Entries:   len(imp.Entries),
+			Counts:    counts,
+		}
+	}
+	return summaries, nil
+}
+
+// ReviewImportEntry confirms or drops an entry of an import.
+func ReviewImportEntry(ctx context.Context, userID string, importID string, entryID int, review ImportEntryReview) (*data.ImportEntry, error) {
+	if review.Status != EntryConfirmed && review.Status != EntryDropped {
+		return nil, ValidationError("status must be %q or %q", EntryConfirmed, EntryDropped)
+	}
+
+	var entry data.ImportEntry
+	_, err := repo.UpdateImport(ctx, userID, importID, func(imp *data.Import) error {
+		i := findImportEntry(imp.Entries, entryID)
+		if i < 0 {
+			return ErrImportEntryNotFound
+		}
+		if review.Status == EntryConfirmed {
+			match, err := reviewedMatch(imp.Entries[i], review.Match)
+			if err != nil {
+				return err
+			}
+			imp.Entries[i].Match = match
+		}
+		imp.Entries[i].Status = review.Status
+		entry = imp.Entries[i]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CommitImport adds the matched and confirmed entries of an import to their
+// lists, creating lists that do not exist yet, and deletes the import.
+func CommitImport(ctx context.Context, userID string, importID string, addedBy string) (*ImportCommitResult, error) {
+	imp, err := repo.GetImport(ctx, userID, importID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportCommitResult{Lists: []ImportResult{}, Skipped: []int{}}
+	var listNames []string
+	places := make(map[string][]data.Place)
+	rows := make(map[string][]int)
+	now := time.Now()
+	for _, entry := range imp.Entries {
+		switch entry.Status {
+		case EntryUnmatched:
+			result.Skipped = append(result.Skipped, entry.ID)
+			continue
+		case EntryDropped:
+			continue
+		}
+		if entry.Match == nil {
+			result.Skipped = append(result.Skipped, entry.ID)
+			continue
+		}
+
+		if _, ok := places[entry.List]; !ok {
+			listNames = append(listNames, entry.List)
+		}
+		places[entry.List] = append(places[entry.List], data.Place{
+			OsmID:   entry.Match.OsmID,
+			OsmType: entry.Match.OsmType,
+			Long:    entry.Match.Long,
+			Lat:     entry.Match.Lat,
+			Note:    takeoutNote(entry),
+			AddedAt: &now,
+			AddedBy: addedBy,
+		})
+		rows[entry.List] = append(rows[entry.List], entry.ID)
+	}
+
+	for _, listName := range listNames {
+		listResult := ImportResult{ListName: listName, Duplicates: []int{}, Errors: []formats.RowError{}}
+		if err := appendImported(ctx, userID, &listResult, places[listName], rows[listName]); err != nil {
+			return nil, err
+		}
+		result.Lists = append(result.Lists, listResult)
+	}
+
+	if err := repo.DeleteImport(ctx, userID, importID); err != nil && !errors.Is(err, ErrImportNotFound) {
+		return nil, err
+	}
+	return result, nil
+}
+
+func DeleteImport(ctx context.Context, userID string, importID string) error {
+	return repo.DeleteImport(ctx, userID, importID)
+}
+
+// shareLocations gives entries without a location the location of another
+// entry with the same URL or title, as saved list CSVs carry no coordinates
+// but the same places are often starred too.
+func shareLocations(entries []data.ImportEntry) {
+	byURL := make(map[string]int)
+	byTitle := make(map[string]int)
+	for i, entry := range entries {
+		if !entry.HasLocation {
+			continue
+		}
+		if entry.URL != "" {
+			byURL[entry.URL] = i
+		}
+		if title := strings.ToLower(entry.Title); title != "" {
+			byTitle[title] = i
+		}
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.HasLocation {
+			continue
+		}
+		source, ok := byURL[entry.URL]
+		if !ok || entry.URL == "" {
+			source, ok = byTitle[strings.ToLower(entry.Title)]
+		}
+		if ok {
+			entry.HasLocation, entry.Lat, entry.Long = true, entries[source].Lat, entries[source].Long
+		}
+	}
+}
+
+// matchEntries looks up the places near each located entry and matches the
+// entry with the nearest one of the same name. The nearest places are kept
+// as candidates for the review.
+func matchEntries(ctx context.Context, entries []data.ImportEntry) error {
+	if placeMatcher == nil {
+		return nil
+	}
+
+	var points []GeoPoint
+	var located []int
+	for i, entry := range entries {
+		if entry.HasLocation {
+			points = append(points, GeoPoint{Lat: entry.Lat, Lon: entry.Long})
+			located = append(located, i)
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	nearby, err := placeMatcher.NearbyPlaces(ctx, points, matchRadius)
+	if err != nil {
+		return fmt.Errorf("looking up places: %w", err)
+	}
+
+	for k, i := range located {
+		if k >= len(nearby) {
+			break
+		}
+		entry := &entries[i]
+		candidates := append([]data.PlaceMatch{}, nearby[k]...)
+		for j := range candidates {
+			candidates[j].Distance = math.Round(Distance(points[k], GeoPoint{Lat: candidates[j].Lat, Lon: candidates[j].Long}))
+		}
+		sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].Distance < candidates[b].Distance })
+
+		for _, candidate := range candidates {
+			if candidate.Distance <= matchRadius && namesMatch(entry.Title, candidate.Name) {
+				match := candidate
+				entry.Match = &match
+				entry.Status = EntryMatched
+				break
+			}
+		}
+		if len(candidates) > maxMatchCandidates {
+			candidates = candidates[:maxMatchCandidates]
+		}
+		entry.Candidates = candidates
+	}
+	return nil
+}
+
+// reviewedMatch returns the place an entry is confirmed with. A chosen
+// place that is one of the entry's candidates only needs its OSM ID; any
+// other place also needs its type and coordinates.
+func reviewedMatch(entry data.ImportEntry, chosen *data.PlaceMatch) (*data.PlaceMatch, error) {
+	if chosen == nil {
+		if entry.Match == nil {
+			return nil, ValidationError("choose the place to confirm the entry with")
+		}
+		return entry.Match, nil
+	}
+	if chosen.OsmID == "" {
+		return nil, ValidationError("missing osm_id in match")
+	}
+	if !ValidOsmID(chosen.OsmID) {
+		return nil, ValidationError("invalid osm_id %q in match", chosen.OsmID)
+	}
+
+	known := entry.Candidates
+	if entry.Match != nil {
+		known = append([]data.PlaceMatch{*entry.Match}, known...)
+	}
+	for _, candidate := range known {
+		if candidate.OsmID == chosen.OsmID && (chosen.OsmType == "" || candidate.OsmType == chosen.OsmType) {
+			return &candidate, nil
+		}
+	}
+
+	switch chosen.OsmType {
+	case "node", "way", "relation":
+	default:
+		return nil, ValidationError("match needs an osm_type of node, way or relation")
+	}
+	if (chosen.Lat == 0 && chosen.Long == 0) || chosen.Lat < -90 || chosen.Lat > 90 || chosen.Long < -180 || chosen.Long > 180 {
+		return nil, ValidationError("match needs the lat and long of the place")
+	}
+	match := *chosen
+	match.Distance = 0
+	if entry.HasLocation {
+		match.Distance = math.Round(Distance(GeoPoint{Lat: entry.Lat, Lon: entry.Long}, GeoPoint{Lat: match.Lat, Lon: match.Long}))
+	}
+	return &match, nil
+}
+
+// takeoutNote keeps the Google title of a place, as list entries have no
+// name of their own, followed by the user's note.
+func takeoutNote(entry data.ImportEntry) string {
+	note := entry.Title
+	if entry.Note != "" {
+		note = strings.TrimSpace(note + "\n" + entry.Note)
+	}
+	return truncateRunes(note, maxListNoteLength)
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
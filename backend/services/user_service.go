@@ -2,142 +2,290 @@ package services
 
 import (
 	"backend/data"
-	"backend/utils"
 	"context"
-	"errors"
 	"time"
 )
 
-func CreateUser(ctx context.Context, user *data.User) (string, error) {
-	query := utils.FirestoreClient.Collection("users").Where("ID", "==", user.ID).Limit(1)
-	docs, err := query.Documents(ctx).GetAll()
-	if err != nil {
-		return "", err
-	}
-	if len(docs) > 0 {
-		return "", errors.New("user already exists")
-	}
+const (
+	// MinRating and MaxRating bound the rating a user can give a place.
+	MinRating = 1
+	MaxRating = 5
+)
 
+var errVisitedAtReadOnly = ValidationError("visitedAt of a visited place follows its visits; add or edit a visit instead")
+
+func CreateUser(ctx context.Context, user *data.User) (string, error) {
 	user.CreatedOn = time.Now()
 	user.Lists = []data.List{}
 	user.VisitedPlaces = []data.UserPlace{}
 
-	docRef, _, err := utils.FirestoreClient.Collection("users").Add(ctx, user)
-	if err != nil {
-		return "", err
-	}
-	return docRef.ID, nil
+	return repo.CreateUser(ctx, user)
 }
 
 func GetUserByID(ctx context.Context, id string) (*data.User, error) {
-	user, _, err := getUserByID(ctx, id)
+	user, err := repo.GetUser(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	summarizePlaces(user.VisitedPlaces)
+	numberLists(user.Lists)
+	if user.Lists, err = withSharedLists(ctx, id, user.Lists); err != nil {
+		return nil, err
+	}
+	fillListDetails(ctx, user.Lists)
+	fillUserPlaceDetails(ctx, user.VisitedPlaces)
+	fillUserPlaceDetails(ctx, user.WatchedPlaces)
 	return user, nil
 }
 
+// DeleteUserByID deletes the user and tells the members of the user's lists
+// that the lists are gone.
 func DeleteUserByID(ctx context.Context, id string) error {
-	_, docSnap, err := getUserByID(ctx, id)
+	lists, err := repo.GetLists(ctx, id)
 	if err != nil {
 		return err
 	}
+	members := make(map[string][]data.ListMember, len(lists))
+	for _, list := range lists {
+		if members[list.ID], err = repo.GetListMembers(ctx, id, list.ID); err != nil {
+			return err
+		}
+	}
 
-	_, err = utils.FirestoreClient.Collection("users").Doc(docSnap.Ref.ID).Delete(ctx)
-	return err
+	if err := repo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	var recordErr error
+	for listID, listMembers := range members {
+		for _, member := range listMembers {
+			if member.Status != MemberAccepted {
+				continue
+			}
+			if err := publishListRemoved(ctx, member.UserID, id, listID); err != nil && recordErr == nil {
+				recordErr = err
+			}
+		}
+	}
+	return recordErr
 }
 
+// VisitPlace records a visit to the place on place.VisitedAt, or now. The
+// rest of place is only used if the place has not been visited before.
 func VisitPlace(ctx context.Context, userID string, place data.UserPlace) (*data.UserPlace, error) {
-	user, docSnap, err := getUserByID(ctx, userID)
-	if err != nil {
-		return nil, err
+	if place.Tags == nil {
+		place.Tags = []string{}
 	}
+	place.OsmType = knownOsmType(place.OsmType)
+	place.Details = nil
 
-	if place.OsmID == "" {
-		return nil, errors.New("missing OsmID in place")
+	var visit data.Visit
+	if place.VisitedAt != nil {
+		visit.VisitedAt = *place.VisitedAt
 	}
+	visited, _, err := recordVisit(ctx, userID, place, visit)
+	return visited, err
+}
 
-	user.VisitedPlaces = append(user.VisitedPlaces, place)
+func GetVisitedPlace(ctx context.Context, userID string, osmID string) (*data.UserPlace, error) {
+	return GetUserPlace(ctx, userID, VisitedPlaces, osmID)
+}
 
-	if err := saveUser(ctx, user, docSnap); err != nil {
+func WatchPlace(ctx context.Context, userID string, place data.UserPlace) (*data.UserPlace, error) {
+	if err := validateOsmID(place.OsmID); err != nil {
+		return nil, err
+	}
+	if place.Tags == nil {
+		place.Tags = []string{}
+	}
+	place.OsmType = knownOsmType(place.OsmType)
+	place.Details = nil
+
+	if err := repo.AddUserPlace(ctx, userID, WatchedPlaces, place); err != nil {
+		return nil, err
+	}
+	if err := publishUserPlace(ctx, userID, WatchedPlaces, &place); err != nil {
 		return nil, err
 	}
 
 	return &place, nil
 }
 
-func GetVisitedPlace(ctx context.Context, userID string, osmID string) (*data.UserPlace, error) {
-	user, _, err := getUserByID(ctx, userID)
+func GetWatchedPlace(ctx context.Context, userID string, osmID string) (*data.UserPlace, error) {
+	return GetUserPlace(ctx, userID, WatchedPlaces, osmID)
+}
+
+// UserPlacePatch holds the fields of a visited or watched place that a
+// partial update changes. Nil fields are left as they are.
+type UserPlacePatch struct {
+	Tags      []string
+	VisitedAt *time.Time
+}
+
+// GetUserPlaces returns all of the user's places of the given kind.
+func GetUserPlaces(ctx context.Context, userID string, kind PlaceKind) ([]data.UserPlace, error) {
+	places, err := repo.GetUserPlaces(ctx, userID, kind)
 	if err != nil {
 		return nil, err
 	}
-
-	place := findVisitById(user.VisitedPlaces, osmID)
-
-	return place, nil
+	if kind == VisitedPlaces {
+		summarizePlaces(places)
+	}
+	fillUserPlaceDetails(ctx, places)
+	return places, nil
 }
 
-func WatchPlace(ctx context.Context, userID string, place data.UserPlace) (*data.UserPlace, error) {
-	user, docSnap, err := getUserByID(ctx, userID)
+// GetUserPlace returns the user's place of the given kind with the given
+// OSM ID or place key, or ErrPlaceNotFound.
+func GetUserPlace(ctx context.Context, userID string, kind PlaceKind, osmID string) (*data.UserPlace, error) {
+	places, err := GetUserPlaces(ctx, userID, kind)
 	if err != nil {
 		return nil, err
 	}
 
-	if place.OsmID == "" {
-		return nil, errors.New("missing OsmID in place")
+	place := findUserPlaceById(places, osmID)
+	if place == nil {
+		return nil, ErrPlaceNotFound
 	}
 
-	user.WatchedPlaces = append(user.WatchedPlaces, place)
+	return place, nil
+}
 
-	if err := saveUser(ctx, user, docSnap); err != nil {
-		return nil, err
+// ReplaceUserPlace overwrites the tags and visit date of a place. Ratings
+// are managed through SetRating and ClearRating and are kept. The visit date
+// of a visited place follows its visits and cannot be set here.
+func ReplaceUserPlace(ctx context.Context, userID string, kind PlaceKind, osmID string, tags []string, visitedAt *time.Time) (*data.UserPlace, error) {
+	if kind == VisitedPlaces && visitedAt != nil {
+		return nil, errVisitedAtReadOnly
+	}
+	if tags == nil {
+		tags = []string{}
 	}
 
-	return &place, nil
+	return updateUserPlace(ctx, userID, kind, osmID, func(place *data.UserPlace) error {
+		place.Tags = tags
+		place.VisitedAt = visitedAt
+		return nil
+	})
 }
 
-func GetWatchedPlace(ctx context.Context, userID string, osmID string) (*data.UserPlace, error) {
-	user, _, err := getUserByID(ctx, userID)
-	if err != nil {
-		return nil, err
+func PatchUserPlace(ctx context.Context, userID string, kind PlaceKind, osmID string, patch UserPlacePatch) (*data.UserPlace, error) {
+	if kind == VisitedPlaces && patch.VisitedAt != nil {
+		return nil, errVisitedAtReadOnly
 	}
 
-	place := findWatchedById(user.WatchedPlaces, osmID)
+	return updateUserPlace(ctx, userID, kind, osmID, func(place *data.UserPlace) error {
+		if patch.Tags != nil {
+			place.Tags = patch.Tags
+		}
+		if patch.VisitedAt != nil {
+			place.VisitedAt = patch.VisitedAt
+		}
+		return nil
+	})
+}
+
+// updateUserPlace wraps repo.UpdateUserPlace, keeps the visit summary of
+// visited places up to date and publishes the updated place.
+func updateUserPlace(ctx context.Context, userID string, kind PlaceKind, placeKey string, update func(place *data.UserPlace) error) (*data.UserPlace, error) {
+	osmType, osmID := SplitPlaceKey(placeKey)
+	if kind != VisitedPlaces {
+		place, err := repo.UpdateUserPlace(ctx, userID, kind, osmType, osmID, update)
+		if err != nil {
+			return nil, err
+		}
+		if err := publishUserPlace(ctx, userID, kind, place); err != nil {
+			return nil, err
+		}
+		return place, nil
+	}
 
+	place, err := repo.UpdateUserPlace(ctx, userID, kind, osmType, osmID, func(place *data.UserPlace) error {
+		loadVisits(place)
+		if err := update(place); err != nil {
+			return err
+		}
+		summarizeVisits(place)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	summarizeVisits(place)
+	if err := publishUserPlace(ctx, userID, kind, place); err != nil {
+		return nil, err
+	}
 	return place, nil
 }
 
-/*
-func CreateRating(ctx context.Context, userID string, rating data.Rating) (string, error) {
-	user, docSnap, err := getUserByID(ctx, userID)
+// DeleteUserPlace removes the user's place of the given kind with the given
+// OSM ID or place key.
+func DeleteUserPlace(ctx context.Context, userID string, kind PlaceKind, placeKey string) error {
+	// The place is looked up first so that its deletion is published under
+	// the key it was published with, even if placeKey is a bare OSM ID.
+	places, err := repo.GetUserPlaces(ctx, userID, kind)
 	if err != nil {
-		return "", err
+		return err
 	}
+	place := findUserPlaceById(places, placeKey)
+	if place == nil {
+		return ErrPlaceNotFound
+	}
+	if err := repo.DeleteUserPlace(ctx, userID, kind, place.OsmType, place.OsmID); err != nil {
+		return err
+	}
+	return publishUserPlaceDeleted(ctx, userID, kind, place.OsmType, place.OsmID)
+}
 
-	if rating.OsmID == "" {
-		return "", errors.New("missing OsmID in rating")
+// SetRating rates a visited place, replacing any earlier rating.
+func SetRating(ctx context.Context, userID string, osmID string, rating int8) (*data.UserPlace, error) {
+	if rating < MinRating || rating > MaxRating {
+		return nil, ValidationError("rating must be between %d and %d", MinRating, MaxRating)
 	}
 
-	user.RatedPlaces = append(user.RatedPlaces, rating)
+	return updateUserPlace(ctx, userID, VisitedPlaces, osmID, func(place *data.UserPlace) error {
+		now := time.Now()
+		place.Rating = &rating
+		place.RatedAt = &now
+		return nil
+	})
+}
+
+// ClearRating removes the rating from a visited place.
+func ClearRating(ctx context.Context, userID string, osmID string) (*data.UserPlace, error) {
+	return updateUserPlace(ctx, userID, VisitedPlaces, osmID, func(place *data.UserPlace) error {
+		place.Rating = nil
+		place.RatedAt = nil
+		return nil
+	})
+}
 
-	if err := saveUser(ctx, user, docSnap); err != nil {
-		return "", err
+// GetRatedPlaces returns the user's visited places that have a rating.
+func GetRatedPlaces(ctx context.Context, userID string) ([]data.UserPlace, error) {
+	places, err := GetUserPlaces(ctx, userID, VisitedPlaces)
+	if err != nil {
+		return nil, err
 	}
 
-	return rating.OsmID, nil
+	rated := []data.UserPlace{}
+	for _, place := range places {
+		if place.Rating != nil {
+			rated = append(rated, place)
+		}
+	}
+	return rated, nil
 }
 
-func GetRating(ctx context.Context, userID string, osmID string) (*data.Rating, error) {
-	user, _, err := getUserByID(ctx, userID)
+// GetRating returns the rated visit of a place, or ErrPlaceNotFound if the
+// place has not been visited or is not rated.
+func GetRating(ctx context.Context, userID string, osmID string) (*data.UserPlace, error) {
+	places, err := GetUserPlaces(ctx, userID, VisitedPlaces)
 	if err != nil {
 		return nil, err
 	}
 
-	_, rating := findRatingById(user.RatedPlaces, osmID)
-	if rating == nil {
-		return nil, errors.New("rating not found")
+	place := findUserPlaceById(places, osmID)
+	if place == nil || place.Rating == nil {
+		return nil, ErrPlaceNotFound
 	}
-
-	return rating, nil
+	return place, nil
 }
-*/
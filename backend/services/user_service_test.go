@@ -0,0 +1,159 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"backend/storage"
+	"context"
+	"errors"
+	"testing"
+)
+
+// newUser points the services at an empty in-memory repository and creates
+// a user in it.
+func newUser(t *testing.T, userID string) context.Context {
+	t.Helper()
+	services.SetRepository(storage.NewMemoryRepository())
+	ctx := context.Background()
+	if _, err := services.CreateUser(ctx, &data.User{ID: userID}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return ctx
+}
+
+// errorKind returns the kind of a domain error, or 0 for other errors.
+func errorKind(err error) services.ErrorKind {
+	var domainErr *services.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	return 0
+}
+
+func TestCreateUserTwice(t *testing.T) {
+	ctx := newUser(t, "u1")
+
+	_, err := services.CreateUser(ctx, &data.User{ID: "u1"})
+	if !errors.Is(err, services.ErrUserAlreadyExists) {
+		t.Fatalf("CreateUser of an existing user: got %v, want ErrUserAlreadyExists", err)
+	}
+}
+
+func TestGetUserByID(t *testing.T) {
+	ctx := newUser(t, "u1")
+
+	user, err := services.GetUserByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if user.ID != "u1" || user.CreatedOn.IsZero() {
+		t.Errorf("GetUserByID = %+v, want user u1 with a creation time", user)
+	}
+	if _, err := services.GetUserByID(ctx, "nobody"); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("GetUserByID of an unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestDeleteUserByID(t *testing.T) {
+	ctx := newUser(t, "u1")
+	if _, err := services.CreateList(ctx, "u1", data.List{ListName: "Lunch"}); err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+
+	if err := services.DeleteUserByID(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUserByID: %v", err)
+	}
+	if _, err := services.GetUserByID(ctx, "u1"); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("GetUserByID after delete: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := services.GetLists(ctx, "u1"); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("GetLists after delete: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestWatchPlace(t *testing.T) {
+	ctx := newUser(t, "u1")
+	place := data.UserPlace{OsmID: "123", OsmType: "node"}
+
+	if _, err := services.WatchPlace(ctx, "u1", place); err != nil {
+		t.Fatalf("WatchPlace: %v", err)
+	}
+	if _, err := services.WatchPlace(ctx, "u1", place); !errors.Is(err, services.ErrPlaceExists) {
+		t.Errorf("WatchPlace twice: got %v, want ErrPlaceExists", err)
+	}
+	if _, err := services.WatchPlace(ctx, "u1", data.UserPlace{}); errorKind(err) != services.KindValidation {
+		t.Errorf("WatchPlace without an OSM ID: got %v, want a validation error", err)
+	}
+	if _, err := services.WatchPlace(ctx, "u1", data.UserPlace{OsmID: "abc", OsmType: "node"}); errorKind(err) != services.KindValidation {
+		t.Errorf("WatchPlace with a non-numeric OSM ID: got %v, want a validation error", err)
+	}
+
+	watched, err := services.GetUserPlaces(ctx, "u1", services.WatchedPlaces)
+	if err != nil {
+		t.Fatalf("GetUserPlaces: %v", err)
+	}
+	if len(watched) != 1 || watched[0].OsmID != "123" {
+		t.Errorf("GetUserPlaces = %+v, want the watched place", watched)
+	}
+
+	if err := services.DeleteUserPlace(ctx, "u1", services.WatchedPlaces, "123"); err != nil {
+		t.Fatalf("DeleteUserPlace: %v", err)
+	}
+	if _, err := services.GetWatchedPlace(ctx, "u1", "123"); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("GetWatchedPlace after delete: got %v, want ErrPlaceNotFound", err)
+	}
+}
+
+func TestSetRating(t *testing.T) {
+	ctx := newUser(t, "u1")
+	if _, err := services.VisitPlace(ctx, "u1", data.UserPlace{OsmID: "1", OsmType: "node"}); err != nil {
+		t.Fatalf("VisitPlace: %v", err)
+	}
+
+	for _, rating := range []int8{services.MinRating - 1, services.MaxRating + 1, -128, 127} {
+		if _, err := services.SetRating(ctx, "u1", "1", rating); errorKind(err) != services.KindValidation {
+			t.Errorf("SetRating(%d): got %v, want a validation error", rating, err)
+		}
+	}
+	if _, err := services.GetRating(ctx, "u1", "1"); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("GetRating of an unrated place: got %v, want ErrPlaceNotFound", err)
+	}
+
+	for _, rating := range []int8{services.MinRating, services.MaxRating} {
+		place, err := services.SetRating(ctx, "u1", "1", rating)
+		if err != nil {
+			t.Fatalf("SetRating(%d): %v", rating, err)
+		}
+		if place.Rating == nil || *place.Rating != rating || place.RatedAt == nil {
+			t.Errorf("SetRating(%d) = %+v, want the rating with its time", rating, place)
+		}
+	}
+	if rated, err := services.GetRatedPlaces(ctx, "u1"); err != nil || len(rated) != 1 {
+		t.Errorf("GetRatedPlaces = %+v, %v, want the rated place", rated, err)
+	}
+
+	if _, err := services.ClearRating(ctx, "u1", "1"); err != nil {
+		t.Fatalf("ClearRating: %v", err)
+	}
+	if _, err := services.GetRating(ctx, "u1", "1"); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("GetRating after ClearRating: got %v, want ErrPlaceNotFound", err)
+	}
+}
+
+func TestSetRatingOfUnvisitedPlace(t *testing.T) {
+	ctx := newUser(t, "u1")
+	if _, err := services.WatchPlace(ctx, "u1", data.UserPlace{OsmID: "1", OsmType: "node"}); err != nil {
+		t.Fatalf("WatchPlace: %v", err)
+	}
+
+	// Only visited places can be rated; a watched place is not visited.
+	if _, err := services.SetRating(ctx, "u1", "1", 3); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("SetRating of an unvisited place: got %v, want ErrPlaceNotFound", err)
+	}
+	if _, err := services.ClearRating(ctx, "u1", "1"); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("ClearRating of an unvisited place: got %v, want ErrPlaceNotFound", err)
+	}
+	if _, err := services.GetRating(ctx, "u1", "2"); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("GetRating of an unknown place: got %v, want ErrPlaceNotFound", err)
+	}
+}
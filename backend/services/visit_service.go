@@ -0,0 +1,186 @@
+package services
+
+import (
+	"backend/data"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// legacyVisitID identifies the visit that stands in for the single VisitedAt
+// of places recorded before visits were tracked individually.
+const legacyVisitID = "legacy"
+
+// VisitPatch holds the fields of a visit that an update changes. Nil fields
+// are left as they are.
+type VisitPatch struct {
+	VisitedAt *time.Time
+	Rating    *int8
+	Notes     *string
+	Tags      []string
+}
+
+// RecordVisit adds a visit to the place with the given OSM ID or place key,
+// marking the place as visited first if it is not yet. A zero VisitedAt
+// means now.
+func RecordVisit(ctx context.Context, userID string, placeKey string, visit data.Visit) (*data.UserPlace, *data.Visit, error) {
+	osmType, osmID := SplitPlaceKey(placeKey)
+	return recordVisit(ctx, userID, data.UserPlace{OsmID: osmID, OsmType: osmType, Tags: []string{}}, visit)
+}
+
+// recordVisit appends visit to the visited place that is newPlace, or stores
+// newPlace with visit as its only visit if there is none.
+func recordVisit(ctx context.Context, userID string, newPlace data.UserPlace, visit data.Visit) (*data.UserPlace, *data.Visit, error) {
+	if err := validateOsmID(newPlace.OsmID); err != nil {
+		return nil, nil, err
+	}
+	if err := validateVisitRating(visit.Rating); err != nil {
+		return nil, nil, err
+	}
+	visit.ID = uuid.New().String()
+	if visit.VisitedAt.IsZero() {
+		visit.VisitedAt = time.Now()
+	}
+	if visit.Tags == nil {
+		visit.Tags = []string{}
+	}
+
+	placeKey := PlaceKey(newPlace.OsmType, newPlace.OsmID)
+	place, err := appendVisit(ctx, userID, placeKey, visit)
+	if errors.Is(err, ErrPlaceNotFound) {
+		newPlace.Visits = []data.Visit{visit}
+		summarizeVisits(&newPlace)
+		err = repo.AddUserPlace(ctx, userID, VisitedPlaces, newPlace)
+		if err == nil {
+			if err := publishUserPlace(ctx, userID, VisitedPlaces, &newPlace); err != nil {
+				return nil, nil, err
+			}
+			return &newPlace, &visit, nil
+		}
+		if errors.Is(err, ErrPlaceExists) {
+			// Someone else added the place in the meantime.
+			place, err = appendVisit(ctx, userID, placeKey, visit)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return place, &visit, nil
+}
+
+// appendVisit adds visit to a visited place. A place recorded without its
+// OSM type takes the type from placeKey.
+func appendVisit(ctx context.Context, userID string, placeKey string, visit data.Visit) (*data.UserPlace, error) {
+	osmType, _ := SplitPlaceKey(placeKey)
+	return updateUserPlace(ctx, userID, VisitedPlaces, placeKey, func(place *data.UserPlace) error {
+		place.Visits = append(place.Visits, visit)
+		if place.OsmType == "" {
+			place.OsmType = osmType
+		}
+		return nil
+	})
+}
+
+// GetVisits returns the visit history of a visited place, oldest first.
+func GetVisits(ctx context.Context, userID string, osmID string) ([]data.Visit, error) {
+	place, err := GetUserPlace(ctx, userID, VisitedPlaces, osmID)
+	if err != nil {
+		return nil, err
+	}
+	return place.Visits, nil
+}
+
+func UpdateVisit(ctx context.Context, userID string, osmID string, visitID string, patch VisitPatch) (*data.UserPlace, *data.Visit, error) {
+	if err := validateVisitRating(patch.Rating); err != nil {
+		return nil, nil, err
+	}
+
+	var updated data.Visit
+	place, err := updateUserPlace(ctx, userID, VisitedPlaces, osmID, func(place *data.UserPlace) error {
+		i := findVisitIndex(place.Visits, visitID)
+		if i < 0 {
+			return ErrVisitNotFound
+		}
+
+		visit := &place.Visits[i]
+		if patch.VisitedAt != nil {
+			visit.VisitedAt = *patch.VisitedAt
+		}
+		if patch.Rating != nil {
+			visit.Rating = patch.Rating
+		}
+		if patch.Notes != nil {
+			visit.Notes = *patch.Notes
+		}
+		if patch.Tags != nil {
+			visit.Tags = patch.Tags
+		}
+		updated = *visit
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return place, &updated, nil
+}
+
+// DeleteVisit removes one visit. The place stays visited even when its last
+// visit is removed; use DeleteUserPlace to un-visit it.
+func DeleteVisit(ctx context.Context, userID string, osmID string, visitID string) (*data.UserPlace, error) {
+	return updateUserPlace(ctx, userID, VisitedPlaces, osmID, func(place *data.UserPlace) error {
+		i := findVisitIndex(place.Visits, visitID)
+		if i < 0 {
+			return ErrVisitNotFound
+		}
+		place.Visits = append(place.Visits[:i], place.Visits[i+1:]...)
+		return nil
+	})
+}
+
+// loadVisits prepares a visited place as it was stored. A place recorded
+// before visits were tracked gets its VisitedAt turned into a visit, and the
+// visits are summarized. Places whose visits were changed since they were
+// loaded are summarized with summarizeVisits instead, as deleting the last
+// visit leaves VisitedAt set until then.
+func loadVisits(place *data.UserPlace) {
+	if len(place.Visits) == 0 && place.VisitedAt != nil {
+		place.Visits = []data.Visit{{ID: legacyVisitID, VisitedAt: *place.VisitedAt, Tags: []string{}}}
+	}
+	summarizeVisits(place)
+}
+
+// summarizeVisits sorts the visit history and fills in VisitCount and
+// LastVisitedAt. VisitedAt is kept equal to the last visit for clients that
+// only know about a single visit date.
+func summarizeVisits(place *data.UserPlace) {
+	if place.Visits == nil {
+		place.Visits = []data.Visit{}
+	}
+	sort.SliceStable(place.Visits, func(i, j int) bool {
+		return place.Visits[i].VisitedAt.Before(place.Visits[j].VisitedAt)
+	})
+
+	place.VisitCount = len(place.Visits)
+	place.LastVisitedAt = nil
+	if place.VisitCount > 0 {
+		last := place.Visits[place.VisitCount-1].VisitedAt
+		place.LastVisitedAt = &last
+	}
+	place.VisitedAt = place.LastVisitedAt
+}
+
+func summarizePlaces(places []data.UserPlace) {
+	for i := range places {
+		loadVisits(&places[i])
+	}
+}
+
+func validateVisitRating(rating *int8) error {
+	if rating != nil && (*rating < MinRating || *rating > MaxRating) {
+		return ValidationError("rating must be between %d and %d", MinRating, MaxRating)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"backend/storage"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordVisit(t *testing.T) {
+	ctx := newUser(t, "u1")
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 1, 0)
+
+	if _, _, err := services.RecordVisit(ctx, "u1", "node/1", data.Visit{VisitedAt: second}); err != nil {
+		t.Fatalf("RecordVisit: %v", err)
+	}
+	place, visit, err := services.RecordVisit(ctx, "u1", "node/1", data.Visit{VisitedAt: first})
+	if err != nil {
+		t.Fatalf("RecordVisit of a visited place: %v", err)
+	}
+	if visit.ID == "" || !visit.VisitedAt.Equal(first) {
+		t.Errorf("RecordVisit visit = %+v, want a new visit on %v", visit, first)
+	}
+	if place.VisitCount != 2 || !place.Visits[0].VisitedAt.Equal(first) || !place.LastVisitedAt.Equal(second) || !place.VisitedAt.Equal(second) {
+		t.Errorf("RecordVisit place = %+v, want two visits in date order, last on %v", place, second)
+	}
+
+	for _, key := range []string{"node/-1", "node/1a", "way/1);node(2"} {
+		if _, _, err := services.RecordVisit(ctx, "u1", key, data.Visit{}); errorKind(err) != services.KindValidation {
+			t.Errorf("RecordVisit of %q: got %v, want a validation error", key, err)
+		}
+	}
+}
+
+func TestDeleteLastVisit(t *testing.T) {
+	ctx := newUser(t, "u1")
+	_, visit, err := services.RecordVisit(ctx, "u1", "node/1", data.Visit{})
+	if err != nil {
+		t.Fatalf("RecordVisit: %v", err)
+	}
+
+	place, err := services.DeleteVisit(ctx, "u1", "node/1", visit.ID)
+	if err != nil {
+		t.Fatalf("DeleteVisit: %v", err)
+	}
+	if len(place.Visits) != 0 || place.VisitCount != 0 || place.VisitedAt != nil || place.LastVisitedAt != nil {
+		t.Errorf("DeleteVisit = %+v, want a place without visits", place)
+	}
+
+	visits, err := services.GetVisits(ctx, "u1", "node/1")
+	if err != nil {
+		t.Fatalf("GetVisits: %v", err)
+	}
+	if len(visits) != 0 {
+		t.Errorf("GetVisits after deleting the last visit = %+v, want none", visits)
+	}
+	if _, err := services.DeleteVisit(ctx, "u1", "node/1", visit.ID); !errors.Is(err, services.ErrVisitNotFound) {
+		t.Errorf("DeleteVisit twice: got %v, want ErrVisitNotFound", err)
+	}
+}
+
+func TestLegacyVisit(t *testing.T) {
+	repo := storage.NewMemoryRepository()
+	services.SetRepository(repo)
+	ctx := context.Background()
+	if _, err := services.CreateUser(ctx, &data.User{ID: "u1"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	// A place as it was stored before visits were tracked.
+	visitedAt := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	legacy := data.UserPlace{OsmID: "1", OsmType: "node", Tags: []string{}, VisitedAt: &visitedAt}
+	if err := repo.AddUserPlace(ctx, "u1", services.VisitedPlaces, legacy); err != nil {
+		t.Fatalf("AddUserPlace: %v", err)
+	}
+
+	visits, err := services.GetVisits(ctx, "u1", "node/1")
+	if err != nil {
+		t.Fatalf("GetVisits: %v", err)
+	}
+	if len(visits) != 1 || !visits[0].VisitedAt.Equal(visitedAt) {
+		t.Fatalf("GetVisits = %+v, want the legacy visit on %v", visits, visitedAt)
+	}
+
+	// Deleting the legacy visit must not bring it back.
+	place, err := services.DeleteVisit(ctx, "u1", "node/1", visits[0].ID)
+	if err != nil {
+		t.Fatalf("DeleteVisit: %v", err)
+	}
+	if place.VisitCount != 0 || place.VisitedAt != nil {
+		t.Errorf("DeleteVisit = %+v, want a place without visits", place)
+	}
+	if visits, err := services.GetVisits(ctx, "u1", "node/1"); err != nil || len(visits) != 0 {
+		t.Errorf("GetVisits after deleting the legacy visit = %+v, %v; want none", visits, err)
+	}
+}
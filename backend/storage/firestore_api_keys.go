@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const apiKeysCollection = "apiKeys"
+
+func (r *FirestoreRepository) CreateAPIKey(ctx context.Context, key data.APIKey) error {
+	_, err := r.client.Collection(apiKeysCollection).Doc(key.ID).Create(ctx, key)
+	if status.Code(err) == codes.AlreadyExists {
+		return services.ErrAPIKeyExists
+	}
+	return err
+}
+
+func (r *FirestoreRepository) GetAPIKey(ctx context.Context, keyID string) (*data.APIKey, error) {
+	docSnap, err := r.client.Collection(apiKeysCollection).Doc(keyID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, services.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var key data.APIKey
+	if err := docSnap.DataTo(&key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *FirestoreRepository) ListAPIKeys(ctx context.Context) ([]data.APIKey, error) {
+	docs, err := r.client.Collection(apiKeysCollection).OrderBy("CreatedAt", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]data.APIKey, len(docs))
+	for i, docSnap := range docs {
+		if err := docSnap.DataTo(&keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (r *FirestoreRepository) UpdateAPIKey(ctx context.Context, keyID string, update func(key *data.APIKey) error) (*data.APIKey, error) {
+	keyRef := r.client.Collection(apiKeysCollection).Doc(keyID)
+	var key data.APIKey
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(keyRef)
+		if status.Code(err) == codes.NotFound {
+			return services.ErrAPIKeyNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		key = data.APIKey{}
+		if err := docSnap.DataTo(&key); err != nil {
+			return err
+		}
+		if err := update(&key); err != nil {
+			return err
+		}
+		key.ID = keyID
+		return tx.Set(keyRef, key)
+	}, firestore.MaxAttempts(transactionAttempts))
+	if err != nil {
+		return nil, transactionError(err)
+	}
+	return &key, nil
+}
+
+func (r *FirestoreRepository) DeleteAPIKey(ctx context.Context, keyID string) error {
+	_, err := r.client.Collection(apiKeysCollection).Doc(keyID).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return services.ErrAPIKeyNotFound
+	}
+	return err
+}
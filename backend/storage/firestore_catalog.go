@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"backend/data"
+	"context"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// placesCollection holds the place catalog. Document IDs are the catalog
+// keys with the slash replaced, such as "node:123", as IDs cannot hold one.
+const placesCollection = "places"
+
+// catalogGetBatch bounds the documents read in a single call.
+const catalogGetBatch = 300
+
+func (r *FirestoreRepository) GetCatalogPlaces(ctx context.Context, keys []string) ([]data.CatalogPlace, error) {
+	places := []data.CatalogPlace{}
+	for start := 0; start < len(keys); start += catalogGetBatch {
+		batch := keys[start:min(start+catalogGetBatch, len(keys))]
+		refs := make([]*firestore.DocumentRef, len(batch))
+		for i, key := range batch {
+			refs[i] = r.catalogRef(key)
+		}
+		docs, err := r.client.GetAll(ctx, refs)
+		if err != nil {
+			return nil, err
+		}
+		for _, docSnap := range docs {
+			if !docSnap.Exists() {
+				continue
+			}
+			var place data.CatalogPlace
+			if err := docSnap.DataTo(&place); err != nil {
+				return nil, err
+			}
+			places = append(places, place)
+		}
+	}
+	return places, nil
+}
+
+func (r *FirestoreRepository) SaveCatalogPlaces(ctx context.Context, places []data.CatalogPlace) error {
+	bulkWriter := r.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(places))
+	for _, place := range places {
+		job, err := bulkWriter.Set(r.catalogRef(place.Key), place)
+		if err != nil {
+			bulkWriter.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bulkWriter.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *FirestoreRepository) catalogRef(key string) *firestore.DocumentRef {
+	return r.client.Collection(placesCollection).Doc(strings.ReplaceAll(key, "/", ":"))
+}
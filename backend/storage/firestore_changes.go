@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"net/url"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// changesCollection holds one document per changed entity of a user, keyed
+// by kind and key. The user's latest sequence number is kept in the
+// ChangeSeq field of the user document.
+const changesCollection = "changes"
+
+func (r *FirestoreRepository) RecordChange(ctx context.Context, userID string, change data.Change) (int64, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	changeRef := userRef.Collection(changesCollection).Doc(changeDocID(change))
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(userRef)
+		if status.Code(err) == codes.NotFound {
+			return services.ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var user firestoreUser
+		if err := docSnap.DataTo(&user); err != nil {
+			return err
+		}
+
+		change.Seq = user.ChangeSeq + 1
+		if err := tx.Update(userRef, []firestore.Update{{Path: "ChangeSeq", Value: change.Seq}}); err != nil {
+			return err
+		}
+		return tx.Set(changeRef, change)
+	}, firestore.MaxAttempts(transactionAttempts))
+	if err != nil {
+		return 0, transactionError(err)
+	}
+	return change.Seq, nil
+}
+
+func (r *FirestoreRepository) GetChanges(ctx context.Context, userID string, since int64) ([]data.Change, int64, error) {
+	docSnap, err := r.findUserDoc(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	var user firestoreUser
+	if err := docSnap.DataTo(&user); err != nil {
+		return nil, 0, err
+	}
+
+	// Changes recorded after the user document was read are left for the
+	// next sync.
+	query := docSnap.Ref.Collection(changesCollection).Where("Seq", ">", since).Where("Seq", "<=", user.ChangeSeq).OrderBy("Seq", firestore.Asc)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, 0, err
+	}
+	changes := make([]data.Change, len(docs))
+	for i, doc := range docs {
+		if err := doc.DataTo(&changes[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return changes, user.ChangeSeq, nil
+}
+
+// changeDocID escapes the key, as OSM IDs and list IDs from clients could
+// hold slashes.
+func changeDocID(change data.Change) string {
+	return change.Kind + ":" + url.PathEscape(change.Key)
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const importsCollection = "imports"
+
+func (r *FirestoreRepository) CreateImport(ctx context.Context, userID string, imp data.Import) error {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return err
+	}
+	_, err = userRef.Collection(importsCollection).Doc(imp.ID).Create(ctx, imp)
+	return err
+}
+
+func (r *FirestoreRepository) GetImport(ctx context.Context, userID string, importID string) (*data.Import, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	docSnap, err := userRef.Collection(importsCollection).Doc(importID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, services.ErrImportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var imp data.Import
+	if err := docSnap.DataTo(&imp); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+func (r *FirestoreRepository) ListImports(ctx context.Context, userID string) ([]data.Import, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := userRef.Collection(importsCollection).OrderBy("CreatedAt", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	imports := make([]data.Import, len(docs))
+	for i, docSnap := range docs {
+		if err := docSnap.DataTo(&imports[i]); err != nil {
+			return nil, err
+		}
+	}
+	return imports, nil
+}
+
+func (r *FirestoreRepository) UpdateImport(ctx context.Context, userID string, importID string, update func(imp *data.Import) error) (*data.Import, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	importRef := userRef.Collection(importsCollection).Doc(importID)
+	var imp data.Import
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(importRef)
+		if status.Code(err) == codes.NotFound {
+			return services.ErrImportNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		imp = data.Import{}
+		if err := docSnap.DataTo(&imp); err != nil {
+			return err
+		}
+		if err := update(&imp); err != nil {
+			return err
+		}
+		imp.ID = importID
+		return tx.Set(importRef, imp)
+	}, firestore.MaxAttempts(transactionAttempts))
+	if err != nil {
+		return nil, transactionError(err)
+	}
+	return &imp, nil
+}
+
+func (r *FirestoreRepository) DeleteImport(ctx context.Context, userID string, importID string) error {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = userRef.Collection(importsCollection).Doc(importID).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return services.ErrImportNotFound
+	}
+	return err
+}
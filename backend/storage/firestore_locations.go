@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// locationsCollection caches reverse geocoding results. Document IDs are
+// the location keys.
+const locationsCollection = "locations"
+
+func (r *FirestoreRepository) GetLocation(ctx context.Context, key string) (*data.Location, error) {
+	docSnap, err := r.locationRef(key).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, services.ErrLocationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var location data.Location
+	if err := docSnap.DataTo(&location); err != nil {
+		return nil, err
+	}
+	location.Key = key
+	return &location, nil
+}
+
+func (r *FirestoreRepository) SaveLocation(ctx context.Context, location data.Location) error {
+	_, err := r.locationRef(location.Key).Set(ctx, location)
+	return err
+}
+
+func (r *FirestoreRepository) locationRef(key string) *firestore.DocumentRef {
+	return r.client.Collection(locationsCollection).Doc(key)
+}
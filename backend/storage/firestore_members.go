@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// listMembersCollection is a top-level collection, as members are looked
+// up both by list and by member.
+const listMembersCollection = "listMembers"
+
+func (r *FirestoreRepository) AddListMember(ctx context.Context, member data.ListMember) error {
+	if _, err := r.findUserDoc(ctx, member.UserID); err != nil {
+		return err
+	}
+	if _, err := r.GetList(ctx, member.OwnerID, member.ListID); err != nil {
+		return err
+	}
+
+	_, err := r.memberRef(member.OwnerID, member.ListID, member.UserID).Create(ctx, member)
+	if status.Code(err) == codes.AlreadyExists {
+		return services.ErrMemberExists
+	}
+	return err
+}
+
+func (r *FirestoreRepository) GetListMembers(ctx context.Context, ownerID string, listID string) ([]data.ListMember, error) {
+	query := r.client.Collection(listMembersCollection).Where("OwnerID", "==", ownerID).Where("ListID", "==", listID)
+	return readMembers(ctx, query)
+}
+
+func (r *FirestoreRepository) GetMemberships(ctx context.Context, userID string) ([]data.ListMember, error) {
+	return readMembers(ctx, r.client.Collection(listMembersCollection).Where("UserID", "==", userID))
+}
+
+func (r *FirestoreRepository) UpdateListMember(ctx context.Context, ownerID string, listID string, userID string, update func(member *data.ListMember) error) (*data.ListMember, error) {
+	memberRef := r.memberRef(ownerID, listID, userID)
+	var member data.ListMember
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(memberRef)
+		if status.Code(err) == codes.NotFound {
+			return services.ErrMemberNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		member = data.ListMember{}
+		if err := docSnap.DataTo(&member); err != nil {
+			return err
+		}
+		if err := update(&member); err != nil {
+			return err
+		}
+		member.OwnerID, member.ListID, member.UserID = ownerID, listID, userID
+		return tx.Set(memberRef, member)
+	}, firestore.MaxAttempts(transactionAttempts))
+	if err != nil {
+		return nil, transactionError(err)
+	}
+	return &member, nil
+}
+
+func (r *FirestoreRepository) DeleteListMember(ctx context.Context, ownerID string, listID string, userID string) error {
+	_, err := r.memberRef(ownerID, listID, userID).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return services.ErrMemberNotFound
+	}
+	return err
+}
+
+// memberRef keys member documents by list and member, so a user can be
+// invited to a list only once.
+func (r *FirestoreRepository) memberRef(ownerID string, listID string, userID string) *firestore.DocumentRef {
+	return r.client.Collection(listMembersCollection).Doc(ownerID + ":" + listID + ":" + userID)
+}
+
+// deleteMembers deletes the member documents matched by query.
+func (r *FirestoreRepository) deleteMembers(ctx context.Context, query firestore.Query) error {
+	bulkWriter := r.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+	for {
+		docSnap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bulkWriter.End()
+			return err
+		}
+		job, err := bulkWriter.Delete(docSnap.Ref)
+		if err != nil {
+			bulkWriter.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bulkWriter.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMembers returns the members matched by query in invitation order,
+// sorted here so the queries need no composite index.
+func readMembers(ctx context.Context, query firestore.Query) ([]data.ListMember, error) {
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]data.ListMember, len(docs))
+	for i, docSnap := range docs {
+		if err := docSnap.DataTo(&members[i]); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(members, func(i, j int) bool { return members[i].InvitedAt.Before(members[j].InvitedAt) })
+	return members, nil
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// legacyFirestoreUser is the user document as it was stored before lists,
+// visits and watches moved into subcollections.
+type legacyFirestoreUser struct {
+	ID            string
+	CreatedOn     time.Time
+	Lists         []data.List
+	VisitedPlaces []data.UserPlace
+	WatchedPlaces []data.UserPlace
+}
+
+// MigrateEmbeddedUsers moves the Lists, VisitedPlaces and WatchedPlaces
+// arrays of every user document into subcollections and removes the arrays
+// from the document. Documents are written with deterministic IDs, so an
+// interrupted run can simply be started again. It returns the number of
+// users that were converted.
+func MigrateEmbeddedUsers(ctx context.Context, client *firestore.Client) (int, error) {
+	migrated := 0
+	iter := client.Collection(usersCollection).Documents(ctx)
+	defer iter.Stop()
+	for {
+		docSnap, err := iter.Next()
+		if err == iterator.Done {
+			return migrated, nil
+		}
+		if err != nil {
+			return migrated, err
+		}
+
+		raw := docSnap.Data()
+		_, hasLists := raw["Lists"]
+		_, hasVisited := raw["VisitedPlaces"]
+		_, hasWatched := raw["WatchedPlaces"]
+		if !hasLists && !hasVisited && !hasWatched {
+			continue
+		}
+
+		var legacy legacyFirestoreUser
+		if err := docSnap.DataTo(&legacy); err != nil {
+			return migrated, fmt.Errorf("user document %s: %w", docSnap.Ref.ID, err)
+		}
+		if err := migrateEmbeddedUser(ctx, client, docSnap.Ref, legacy); err != nil {
+			return migrated, fmt.Errorf("user document %s: %w", docSnap.Ref.ID, err)
+		}
+		migrated++
+	}
+}
+
+func migrateEmbeddedUser(ctx context.Context, client *firestore.Client, userRef *firestore.DocumentRef, legacy legacyFirestoreUser) error {
+	bulkWriter := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	set := func(docRef *firestore.DocumentRef, doc interface{}) error {
+		job, err := bulkWriter.Set(docRef, doc)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+		return nil
+	}
+
+	for i, list := range legacy.Lists {
+		if list.ID == "" {
+			list.ID = uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("%s/lists/%d", userRef.Path, i))).String()
+		}
+		if list.Places == nil {
+			list.Places = []data.Place{}
+		}
+		if err := set(userRef.Collection(listsCollection).Doc(list.ID), firestoreList{List: list, SortKey: int64(i)}); err != nil {
+			bulkWriter.End()
+			return err
+		}
+	}
+	for collection, places := range map[string][]data.UserPlace{
+		string(services.VisitedPlaces): legacy.VisitedPlaces,
+		string(services.WatchedPlaces): legacy.WatchedPlaces,
+	} {
+		for i, place := range places {
+			docRef := userRef.Collection(collection).Doc(fmt.Sprintf("legacy-%06d", i))
+			if err := set(docRef, firestoreUserPlace{UserPlace: place, SortKey: int64(i)}); err != nil {
+				bulkWriter.End()
+				return err
+			}
+		}
+	}
+	bulkWriter.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+
+	_, err := userRef.Update(ctx, []firestore.Update{
+		{Path: "Lists", Value: firestore.Delete},
+		{Path: "VisitedPlaces", Value: firestore.Delete},
+		{Path: "WatchedPlaces", Value: firestore.Delete},
+	})
+	return err
+}
+
+// userSubcollections lists every collection stored under a user document.
+var userSubcollections = []string{listsCollection, string(services.VisitedPlaces), string(services.WatchedPlaces), importsCollection, changesCollection}
+
+// rekeyedFromField marks a user document that RekeyUsers created with the
+// ID of the document it is moving. It is removed once the move is done.
+const rekeyedFromField = "RekeyedFrom"
+
+// RekeyUsers moves user documents that were created with auto-generated IDs
+// to users/{ID}, where ID is the Clerk user ID stored in the document, and
+// copies their subcollections along. If a document already exists at the
+// target path the source is left untouched and its ID is returned in
+// skipped, so duplicates can be reviewed by hand. An interrupted run can
+// be started again: a target that an earlier run created for the same
+// source is not a duplicate, and its move is finished instead.
+func RekeyUsers(ctx context.Context, client *firestore.Client) (moved int, skipped []string, err error) {
+	docs, err := client.Collection(usersCollection).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, docSnap := range docs {
+		userID, _ := docSnap.Data()["ID"].(string)
+		if userID == "" || userID == docSnap.Ref.ID {
+			continue
+		}
+
+		target := client.Collection(usersCollection).Doc(userID)
+		resume, err := createRekeyedUser(ctx, docSnap, target)
+		if err != nil {
+			return moved, skipped, fmt.Errorf("user document %s: %w", docSnap.Ref.ID, err)
+		}
+		if !resume {
+			skipped = append(skipped, docSnap.Ref.ID)
+			continue
+		}
+
+		if err := moveUserSubcollections(ctx, client, docSnap.Ref, target); err != nil {
+			return moved, skipped, fmt.Errorf("user document %s: %w", docSnap.Ref.ID, err)
+		}
+		if _, err := docSnap.Ref.Delete(ctx); err != nil {
+			return moved, skipped, fmt.Errorf("user document %s: %w", docSnap.Ref.ID, err)
+		}
+		if _, err := target.Update(ctx, []firestore.Update{{Path: rekeyedFromField, Value: firestore.Delete}}); err != nil {
+			return moved, skipped, fmt.Errorf("user document %s: %w", docSnap.Ref.ID, err)
+		}
+		moved++
+	}
+	return moved, skipped, nil
+}
+
+// createRekeyedUser creates target as a copy of source, marked as moved
+// from it. It reports false if target already holds another document, and
+// true if it was created now or by an earlier run for the same source.
+func createRekeyedUser(ctx context.Context, source *firestore.DocumentSnapshot, target *firestore.DocumentRef) (bool, error) {
+	fields := source.Data()
+	fields[rekeyedFromField] = source.Ref.ID
+	_, err := target.Create(ctx, fields)
+	if status.Code(err) != codes.AlreadyExists {
+		return err == nil, err
+	}
+
+	existing, err := target.Get(ctx)
+	if err != nil {
+		return false, err
+	}
+	rekeyedFrom, _ := existing.Data()[rekeyedFromField].(string)
+	return rekeyedFrom == source.Ref.ID, nil
+}
+
+func moveUserSubcollections(ctx context.Context, client *firestore.Client, from *firestore.DocumentRef, to *firestore.DocumentRef) error {
+	for _, collection := range userSubcollections {
+		docs, err := from.Collection(collection).Documents(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+
+		bulkWriter := client.BulkWriter(ctx)
+		jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+		for _, docSnap := range docs {
+			job, err := bulkWriter.Set(to.Collection(collection).Doc(docSnap.Ref.ID), docSnap.Data())
+			if err != nil {
+				bulkWriter.End()
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+		bulkWriter.End()
+		for _, job := range jobs {
+			if _, err := job.Results(); err != nil {
+				return err
+			}
+		}
+
+		if err := deleteCollection(ctx, client, from.Collection(collection)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,472 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	usersCollection     = "users"
+	listsCollection     = "lists"
+	transactionAttempts = 5
+)
+
+// firestoreUser is the user document. Lists, visits and watches live in
+// subcollections underneath it rather than in the document itself.
+type firestoreUser struct {
+	ID        string
+	CreatedOn time.Time
+	// ChangeSeq is the sequence number of the user's latest change.
+	ChangeSeq int64
+}
+
+// firestoreList is a document in users/{user}/lists, keyed by list ID.
+type firestoreList struct {
+	data.List
+	// SortKey keeps lists in creation order.
+	SortKey int64
+}
+
+// firestoreUserPlace is a document in users/{user}/visits or users/{user}/watches.
+type firestoreUserPlace struct {
+	data.UserPlace
+	// SortKey keeps places in the order they were added.
+	SortKey int64
+}
+
+// FirestoreRepository stores each user at users/{userID}, keyed by the Clerk
+// user ID, with its lists, visits and watches in subcollections.
+type FirestoreRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreRepository(client *firestore.Client) *FirestoreRepository {
+	return &FirestoreRepository{client: client}
+}
+
+func (r *FirestoreRepository) findUserDoc(ctx context.Context, userID string) (*firestore.DocumentSnapshot, error) {
+	docSnap, err := r.client.Collection(usersCollection).Doc(userID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, services.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return docSnap, nil
+}
+
+func (r *FirestoreRepository) userRef(ctx context.Context, userID string) (*firestore.DocumentRef, error) {
+	docSnap, err := r.findUserDoc(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return docSnap.Ref, nil
+}
+
+// CreateUser stores the user at users/{userID}. Create fails if the document
+// already exists, so two racing creates cannot both succeed.
+func (r *FirestoreRepository) CreateUser(ctx context.Context, user *data.User) (string, error) {
+	docRef := r.client.Collection(usersCollection).Doc(user.ID)
+	_, err := docRef.Create(ctx, firestoreUser{ID: user.ID, CreatedOn: user.CreatedOn})
+	if status.Code(err) == codes.AlreadyExists {
+		return "", services.ErrUserAlreadyExists
+	}
+	if err != nil {
+		return "", err
+	}
+	return docRef.ID, nil
+}
+
+func (r *FirestoreRepository) GetUser(ctx context.Context, userID string) (*data.User, error) {
+	docSnap, err := r.findUserDoc(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored firestoreUser
+	if err := docSnap.DataTo(&stored); err != nil {
+		return nil, err
+	}
+	user := data.User{ID: stored.ID, CreatedOn: stored.CreatedOn}
+
+	if user.Lists, err = r.readLists(ctx, docSnap.Ref); err != nil {
+		return nil, err
+	}
+	if user.VisitedPlaces, err = r.readUserPlaces(ctx, docSnap.Ref, services.VisitedPlaces); err != nil {
+		return nil, err
+	}
+	if user.WatchedPlaces, err = r.readUserPlaces(ctx, docSnap.Ref, services.WatchedPlaces); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *FirestoreRepository) DeleteUser(ctx context.Context, userID string) error {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range userSubcollections {
+		if err := deleteCollection(ctx, r.client, userRef.Collection(collection)); err != nil {
+			return err
+		}
+	}
+	members := r.client.Collection(listMembersCollection)
+	if err := r.deleteMembers(ctx, members.Where("UserID", "==", userID)); err != nil {
+		return err
+	}
+	if err := r.deleteMembers(ctx, members.Where("OwnerID", "==", userID)); err != nil {
+		return err
+	}
+	_, err = userRef.Delete(ctx)
+	return err
+}
+
+func (r *FirestoreRepository) GetLists(ctx context.Context, userID string) ([]data.List, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.readLists(ctx, userRef)
+}
+
+func (r *FirestoreRepository) CreateList(ctx context.Context, userID string, list data.List) error {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	lists := userRef.Collection(listsCollection)
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		taken, err := firestoreListNameTaken(tx, lists, list.ListName, "")
+		if err != nil {
+			return err
+		}
+		if taken {
+			return services.ErrListNameTaken
+		}
+
+		doc := firestoreList{List: list, SortKey: time.Now().UnixNano()}
+		return tx.Create(lists.Doc(list.ID), doc)
+	}, firestore.MaxAttempts(transactionAttempts))
+	return transactionError(err)
+}
+
+func (r *FirestoreRepository) GetList(ctx context.Context, userID string, listID string) (*data.List, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	docSnap, err := userRef.Collection(listsCollection).Doc(listID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, services.ErrListNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc firestoreList
+	if err := docSnap.DataTo(&doc); err != nil {
+		return nil, err
+	}
+	return &doc.List, nil
+}
+
+// UpdateList runs the read-modify-write in a Firestore transaction. Firestore
+// retries the transaction itself when another write lands in between.
+func (r *FirestoreRepository) UpdateList(ctx context.Context, userID string, listID string, update func(list *data.List) error) (*data.List, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := userRef.Collection(listsCollection)
+	listRef := lists.Doc(listID)
+	var doc firestoreList
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnap, err := tx.Get(listRef)
+		if status.Code(err) == codes.NotFound {
+			return services.ErrListNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		doc = firestoreList{}
+		if err := docSnap.DataTo(&doc); err != nil {
+			return err
+		}
+		oldName := doc.ListName
+		if err := update(&doc.List); err != nil {
+			return err
+		}
+		doc.ID = listID
+		if doc.ListName != oldName {
+			taken, err := firestoreListNameTaken(tx, lists, doc.ListName, listID)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return services.ErrListNameTaken
+			}
+		}
+		return tx.Set(listRef, doc)
+	}, firestore.MaxAttempts(transactionAttempts))
+	if err != nil {
+		return nil, transactionError(err)
+	}
+	return &doc.List, nil
+}
+
+func (r *FirestoreRepository) DeleteList(ctx context.Context, userID string, listID string) error {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = userRef.Collection(listsCollection).Doc(listID).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return services.ErrListNotFound
+	}
+	if err != nil {
+		return err
+	}
+	query := r.client.Collection(listMembersCollection).Where("OwnerID", "==", userID).Where("ListID", "==", listID)
+	return r.deleteMembers(ctx, query)
+}
+
+func (r *FirestoreRepository) GetUserPlaces(ctx context.Context, userID string, kind services.PlaceKind) ([]data.UserPlace, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.readUserPlaces(ctx, userRef, kind)
+}
+
+func (r *FirestoreRepository) AddUserPlace(ctx context.Context, userID string, kind services.PlaceKind, place data.UserPlace) error {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// The query inside the transaction keeps a concurrent add of the same
+	// place from slipping in between the check and the write.
+	collection := userRef.Collection(string(kind))
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(collection.Where("OsmID", "==", place.OsmID)).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, docSnap := range docs {
+			var existing firestoreUserPlace
+			if err := docSnap.DataTo(&existing); err != nil {
+				return err
+			}
+			if services.SamePlace(existing.OsmType, existing.OsmID, place.OsmType, place.OsmID) {
+				return services.ErrPlaceExists
+			}
+		}
+
+		doc := firestoreUserPlace{UserPlace: place, SortKey: time.Now().UnixNano()}
+		return tx.Create(collection.NewDoc(), doc)
+	}, firestore.MaxAttempts(transactionAttempts))
+	return transactionError(err)
+}
+
+// UpdateUserPlace updates the first place that is the given place in a
+// transaction, like UpdateList.
+func (r *FirestoreRepository) UpdateUserPlace(ctx context.Context, userID string, kind services.PlaceKind, osmType string, osmID string, update func(place *data.UserPlace) error) (*data.UserPlace, error) {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ordering by SortKey in the query would need a composite index, so the
+	// earliest match is picked here instead. The type is compared here as
+	// well, as places without one match any type.
+	query := userRef.Collection(string(kind)).Where("OsmID", "==", osmID)
+	var doc firestoreUserPlace
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+
+		var ref *firestore.DocumentRef
+		doc = firestoreUserPlace{}
+		for _, docSnap := range docs {
+			var candidate firestoreUserPlace
+			if err := docSnap.DataTo(&candidate); err != nil {
+				return err
+			}
+			if !services.SamePlace(candidate.OsmType, candidate.OsmID, osmType, osmID) {
+				continue
+			}
+			if ref == nil || candidate.SortKey < doc.SortKey {
+				ref, doc = docSnap.Ref, candidate
+			}
+		}
+		if ref == nil {
+			return services.ErrPlaceNotFound
+		}
+
+		if err := update(&doc.UserPlace); err != nil {
+			return err
+		}
+		doc.OsmID = osmID
+		return tx.Set(ref, doc)
+	}, firestore.MaxAttempts(transactionAttempts))
+	if err != nil {
+		return nil, transactionError(err)
+	}
+	return &doc.UserPlace, nil
+}
+
+func (r *FirestoreRepository) DeleteUserPlace(ctx context.Context, userID string, kind services.PlaceKind, osmType string, osmID string) error {
+	userRef, err := r.userRef(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	query := userRef.Collection(string(kind)).Where("OsmID", "==", osmID)
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+		deleted := 0
+		for _, docSnap := range docs {
+			var place firestoreUserPlace
+			if err := docSnap.DataTo(&place); err != nil {
+				return err
+			}
+			if !services.SamePlace(place.OsmType, place.OsmID, osmType, osmID) {
+				continue
+			}
+			if err := tx.Delete(docSnap.Ref); err != nil {
+				return err
+			}
+			deleted++
+		}
+		if deleted == 0 {
+			return services.ErrPlaceNotFound
+		}
+		return nil
+	}, firestore.MaxAttempts(transactionAttempts))
+	return transactionError(err)
+}
+
+// GetSharedList searches the lists of all users, which needs the
+// collection group index on lists.ShareToken to be enabled.
+func (r *FirestoreRepository) GetSharedList(ctx context.Context, shareToken string) (string, *data.List, error) {
+	docs, err := r.client.CollectionGroup(listsCollection).Where("ShareToken", "==", shareToken).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(docs) == 0 {
+		return "", nil, services.ErrListNotFound
+	}
+
+	var doc firestoreList
+	if err := docs[0].DataTo(&doc); err != nil {
+		return "", nil, err
+	}
+	return docs[0].Ref.Parent.Parent.ID, &doc.List, nil
+}
+
+func (r *FirestoreRepository) readLists(ctx context.Context, userRef *firestore.DocumentRef) ([]data.List, error) {
+	docs, err := userRef.Collection(listsCollection).OrderBy("SortKey", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]data.List, len(docs))
+	for i, docSnap := range docs {
+		var doc firestoreList
+		if err := docSnap.DataTo(&doc); err != nil {
+			return nil, err
+		}
+		lists[i] = doc.List
+	}
+	return lists, nil
+}
+
+func (r *FirestoreRepository) readUserPlaces(ctx context.Context, userRef *firestore.DocumentRef, kind services.PlaceKind) ([]data.UserPlace, error) {
+	docs, err := userRef.Collection(string(kind)).OrderBy("SortKey", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	places := make([]data.UserPlace, len(docs))
+	for i, docSnap := range docs {
+		var doc firestoreUserPlace
+		if err := docSnap.DataTo(&doc); err != nil {
+			return nil, err
+		}
+		places[i] = doc.UserPlace
+	}
+	return places, nil
+}
+
+// firestoreListNameTaken reports whether a list other than exceptID is
+// called name. Running the query in the transaction keeps two lists from being given the
+// same name at once.
+func firestoreListNameTaken(tx *firestore.Transaction, lists *firestore.CollectionRef, name string, exceptID string) (bool, error) {
+	docs, err := tx.Documents(lists.Where("ListName", "==", name)).GetAll()
+	if err != nil {
+		return false, err
+	}
+	for _, docSnap := range docs {
+		if docSnap.Ref.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// transactionError turns an exhausted transaction into ErrConflict.
+func transactionError(err error) error {
+	if status.Code(err) == codes.Aborted {
+		return services.ErrConflict
+	}
+	return err
+}
+
+// deleteCollection deletes every document in a collection.
+func deleteCollection(ctx context.Context, client *firestore.Client, collection *firestore.CollectionRef) error {
+	bulkWriter := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	iter := collection.DocumentRefs(ctx)
+	for {
+		docRef, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			bulkWriter.End()
+			return err
+		}
+		job, err := bulkWriter.Delete(docRef)
+		if err != nil {
+			bulkWriter.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	bulkWriter.End()
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
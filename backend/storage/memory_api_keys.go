@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"sort"
+)
+
+func (r *MemoryRepository) CreateAPIKey(_ context.Context, key data.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.apiKeys[key.ID]; ok {
+		return services.ErrAPIKeyExists
+	}
+	r.apiKeys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+func (r *MemoryRepository) GetAPIKey(_ context.Context, keyID string) (*data.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.apiKeys[keyID]
+	if !ok {
+		return nil, services.ErrAPIKeyNotFound
+	}
+	key = copyAPIKey(key)
+	return &key, nil
+}
+
+func (r *MemoryRepository) ListAPIKeys(_ context.Context) ([]data.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]data.APIKey, 0, len(r.apiKeys))
+	for _, key := range r.apiKeys {
+		keys = append(keys, copyAPIKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (r *MemoryRepository) UpdateAPIKey(_ context.Context, keyID string, update func(key *data.APIKey) error) (*data.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.apiKeys[keyID]
+	if !ok {
+		return nil, services.ErrAPIKeyNotFound
+	}
+	key := copyAPIKey(stored)
+	if err := update(&key); err != nil {
+		return nil, err
+	}
+	key.ID = keyID
+	r.apiKeys[keyID] = copyAPIKey(key)
+	return &key, nil
+}
+
+func (r *MemoryRepository) DeleteAPIKey(_ context.Context, keyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.apiKeys[keyID]; !ok {
+		return services.ErrAPIKeyNotFound
+	}
+	delete(r.apiKeys, keyID)
+	return nil
+}
+
+func copyAPIKey(key data.APIKey) data.APIKey {
+	key.Scopes = append([]string{}, key.Scopes...)
+	return key
+}
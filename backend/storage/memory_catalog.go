@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"backend/data"
+	"context"
+	"maps"
+)
+
+func (r *MemoryRepository) GetCatalogPlaces(_ context.Context, keys []string) ([]data.CatalogPlace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	places := []data.CatalogPlace{}
+	for _, key := range keys {
+		if place, ok := r.catalog[key]; ok {
+			places = append(places, copyCatalogPlace(place))
+		}
+	}
+	return places, nil
+}
+
+func (r *MemoryRepository) SaveCatalogPlaces(_ context.Context, places []data.CatalogPlace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, place := range places {
+		r.catalog[place.Key] = copyCatalogPlace(place)
+	}
+	return nil
+}
+
+func copyCatalogPlace(place data.CatalogPlace) data.CatalogPlace {
+	place.Tags = maps.Clone(place.Tags)
+	return place
+}
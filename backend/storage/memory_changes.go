@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"sort"
+)
+
+func (r *MemoryRepository) RecordChange(_ context.Context, userID string, change data.Change) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return 0, services.ErrUserNotFound
+	}
+	r.changeSeqs[userID]++
+	change.Seq = r.changeSeqs[userID]
+	if r.changes[userID] == nil {
+		r.changes[userID] = make(map[string]data.Change)
+	}
+	r.changes[userID][change.Kind+":"+change.Key] = change
+	return change.Seq, nil
+}
+
+func (r *MemoryRepository) GetChanges(_ context.Context, userID string, since int64) ([]data.Change, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return nil, 0, services.ErrUserNotFound
+	}
+	changes := []data.Change{}
+	for _, change := range r.changes[userID] {
+		if change.Seq > since {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq })
+	return changes, r.changeSeqs[userID], nil
+}
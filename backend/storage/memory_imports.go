@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+)
+
+func (r *MemoryRepository) CreateImport(_ context.Context, userID string, imp data.Import) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return services.ErrUserNotFound
+	}
+	r.imports[userID] = append(r.imports[userID], copyImport(imp))
+	return nil
+}
+
+func (r *MemoryRepository) GetImport(_ context.Context, userID string, importID string) (*data.Import, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, err := r.findImport(userID, importID)
+	if err != nil {
+		return nil, err
+	}
+	imp := copyImport(r.imports[userID][i])
+	return &imp, nil
+}
+
+func (r *MemoryRepository) ListImports(_ context.Context, userID string) ([]data.Import, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return nil, services.ErrUserNotFound
+	}
+	imports := make([]data.Import, len(r.imports[userID]))
+	for i, imp := range r.imports[userID] {
+		imports[i] = copyImport(imp)
+	}
+	return imports, nil
+}
+
+func (r *MemoryRepository) UpdateImport(_ context.Context, userID string, importID string, update func(imp *data.Import) error) (*data.Import, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.findImport(userID, importID)
+	if err != nil {
+		return nil, err
+	}
+	imp := copyImport(r.imports[userID][i])
+	if err := update(&imp); err != nil {
+		return nil, err
+	}
+	imp.ID = importID
+	r.imports[userID][i] = copyImport(imp)
+	return &imp, nil
+}
+
+func (r *MemoryRepository) DeleteImport(_ context.Context, userID string, importID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.findImport(userID, importID)
+	if err != nil {
+		return err
+	}
+	imports := r.imports[userID]
+	r.imports[userID] = append(imports[:i:i], imports[i+1:]...)
+	return nil
+}
+
+// findImport must be called with r.mu held.
+func (r *MemoryRepository) findImport(userID string, importID string) (int, error) {
+	if _, ok := r.users[userID]; !ok {
+		return -1, services.ErrUserNotFound
+	}
+	for i, imp := range r.imports[userID] {
+		if imp.ID == importID {
+			return i, nil
+		}
+	}
+	return -1, services.ErrImportNotFound
+}
+
+func copyImport(imp data.Import) data.Import {
+	entries := make([]data.ImportEntry, len(imp.Entries))
+	for i, entry := range imp.Entries {
+		if entry.Match != nil {
+			match := *entry.Match
+			entry.Match = &match
+		}
+		entry.Candidates = append([]data.PlaceMatch{}, entry.Candidates...)
+		entries[i] = entry
+	}
+	imp.Entries = entries
+	return imp
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+)
+
+func (r *MemoryRepository) GetLocation(_ context.Context, key string) (*data.Location, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	location, ok := r.locations[key]
+	if !ok {
+		return nil, services.ErrLocationNotFound
+	}
+	return &location, nil
+}
+
+func (r *MemoryRepository) SaveLocation(_ context.Context, location data.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.locations[location.Key] = location
+	return nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+)
+
+func (r *MemoryRepository) AddListMember(_ context.Context, member data.ListMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.findMember(member.OwnerID, member.ListID, member.UserID) >= 0 {
+		return services.ErrMemberExists
+	}
+	r.members = append(r.members, copyMember(member))
+	return nil
+}
+
+func (r *MemoryRepository) GetListMembers(_ context.Context, ownerID string, listID string) ([]data.ListMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := []data.ListMember{}
+	for _, member := range r.members {
+		if member.OwnerID == ownerID && member.ListID == listID {
+			members = append(members, copyMember(member))
+		}
+	}
+	return members, nil
+}
+
+func (r *MemoryRepository) GetMemberships(_ context.Context, userID string) ([]data.ListMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	memberships := []data.ListMember{}
+	for _, member := range r.members {
+		if member.UserID == userID {
+			memberships = append(memberships, copyMember(member))
+		}
+	}
+	return memberships, nil
+}
+
+func (r *MemoryRepository) UpdateListMember(_ context.Context, ownerID string, listID string, userID string, update func(member *data.ListMember) error) (*data.ListMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.findMember(ownerID, listID, userID)
+	if i < 0 {
+		return nil, services.ErrMemberNotFound
+	}
+	member := copyMember(r.members[i])
+	if err := update(&member); err != nil {
+		return nil, err
+	}
+	member.OwnerID, member.ListID, member.UserID = ownerID, listID, userID
+	r.members[i] = copyMember(member)
+	return &member, nil
+}
+
+func (r *MemoryRepository) DeleteListMember(_ context.Context, ownerID string, listID string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.findMember(ownerID, listID, userID)
+	if i < 0 {
+		return services.ErrMemberNotFound
+	}
+	r.members = append(r.members[:i:i], r.members[i+1:]...)
+	return nil
+}
+
+// findMember must be called with r.mu held.
+func (r *MemoryRepository) findMember(ownerID string, listID string, userID string) int {
+	for i, member := range r.members {
+		if member.OwnerID == ownerID && member.ListID == listID && member.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeMembers must be called with r.mu held.
+func (r *MemoryRepository) removeMembers(remove func(member data.ListMember) bool) {
+	kept := r.members[:0:0]
+	for _, member := range r.members {
+		if !remove(member) {
+			kept = append(kept, member)
+		}
+	}
+	r.members = kept
+}
+
+func copyMember(member data.ListMember) data.ListMember {
+	if member.AcceptedAt != nil {
+		acceptedAt := *member.AcceptedAt
+		member.AcceptedAt = &acceptedAt
+	}
+	return member
+}
@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"sync"
+)
+
+// MemoryRepository keeps all data in process memory. It is meant for local
+// development and tests; nothing survives a restart.
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	users   map[string]*data.User
+	apiKeys map[string]data.APIKey
+	// members holds the members of all lists in invitation order.
+	members []data.ListMember
+	// changes holds the latest change to each entity of a user, keyed by
+	// kind and key, and changeSeqs the user's latest sequence number.
+	changes    map[string]map[string]data.Change
+	changeSeqs map[string]int64
+	// imports holds each user's imports in creation order.
+	imports map[string][]data.Import
+	// catalog holds the place catalog by key.
+	catalog map[string]data.CatalogPlace
+	// locations holds cached reverse geocoding results by key.
+	locations map[string]data.Location
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users:      make(map[string]*data.User),
+		apiKeys:    make(map[string]data.APIKey),
+		changes:    make(map[string]map[string]data.Change),
+		changeSeqs: make(map[string]int64),
+		imports:    make(map[string][]data.Import),
+		catalog:    make(map[string]data.CatalogPlace),
+		locations:  make(map[string]data.Location),
+	}
+}
+
+func (r *MemoryRepository) CreateUser(_ context.Context, user *data.User) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return "", services.ErrUserAlreadyExists
+	}
+	r.users[user.ID] = &data.User{
+		ID:            user.ID,
+		CreatedOn:     user.CreatedOn,
+		Lists:         []data.List{},
+		VisitedPlaces: []data.UserPlace{},
+		WatchedPlaces: []data.UserPlace{},
+	}
+	return user.ID, nil
+}
+
+func (r *MemoryRepository) GetUser(_ context.Context, userID string) (*data.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	copied := copyUser(*user)
+	return &copied, nil
+}
+
+func (r *MemoryRepository) DeleteUser(_ context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return services.ErrUserNotFound
+	}
+	delete(r.users, userID)
+	delete(r.imports, userID)
+	delete(r.changes, userID)
+	delete(r.changeSeqs, userID)
+	r.removeMembers(func(member data.ListMember) bool {
+		return member.OwnerID == userID || member.UserID == userID
+	})
+	return nil
+}
+
+func (r *MemoryRepository) GetLists(_ context.Context, userID string) ([]data.List, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	return copyUser(*user).Lists, nil
+}
+
+func (r *MemoryRepository) CreateList(_ context.Context, userID string, list data.List) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return services.ErrUserNotFound
+	}
+	if listNameTaken(user.Lists, list.ListName, "") {
+		return services.ErrListNameTaken
+	}
+	user.Lists = append(user.Lists, copyList(list))
+	return nil
+}
+
+func (r *MemoryRepository) GetList(_ context.Context, userID string, listID string) (*data.List, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	for _, list := range user.Lists {
+		if list.ID == listID {
+			copied := copyList(list)
+			return &copied, nil
+		}
+	}
+	return nil, services.ErrListNotFound
+}
+
+func (r *MemoryRepository) UpdateList(_ context.Context, userID string, listID string, update func(list *data.List) error) (*data.List, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	for i := range user.Lists {
+		if user.Lists[i].ID != listID {
+			continue
+		}
+		list := copyList(user.Lists[i])
+		if err := update(&list); err != nil {
+			return nil, err
+		}
+		list.ID = listID
+		if listNameTaken(user.Lists, list.ListName, listID) {
+			return nil, services.ErrListNameTaken
+		}
+		user.Lists[i] = copyList(list)
+		return &list, nil
+	}
+	return nil, services.ErrListNotFound
+}
+
+func (r *MemoryRepository) DeleteList(_ context.Context, userID string, listID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return services.ErrUserNotFound
+	}
+	for i := range user.Lists {
+		if user.Lists[i].ID == listID {
+			user.Lists = append(user.Lists[:i], user.Lists[i+1:]...)
+			r.removeMembers(func(member data.ListMember) bool {
+				return member.OwnerID == userID && member.ListID == listID
+			})
+			return nil
+		}
+	}
+	return services.ErrListNotFound
+}
+
+func (r *MemoryRepository) GetUserPlaces(_ context.Context, userID string, kind services.PlaceKind) ([]data.UserPlace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	return copyUserPlaces(*userPlaces(user, kind)), nil
+}
+
+func (r *MemoryRepository) AddUserPlace(_ context.Context, userID string, kind services.PlaceKind, place data.UserPlace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return services.ErrUserNotFound
+	}
+	places := userPlaces(user, kind)
+	for _, existing := range *places {
+		if services.SamePlace(existing.OsmType, existing.OsmID, place.OsmType, place.OsmID) {
+			return services.ErrPlaceExists
+		}
+	}
+	*places = append(*places, copyUserPlaces([]data.UserPlace{place})...)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateUserPlace(_ context.Context, userID string, kind services.PlaceKind, osmType string, osmID string, update func(place *data.UserPlace) error) (*data.UserPlace, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	places := *userPlaces(user, kind)
+	for i := range places {
+		if !services.SamePlace(places[i].OsmType, places[i].OsmID, osmType, osmID) {
+			continue
+		}
+		place := copyUserPlaces(places[i : i+1])[0]
+		if err := update(&place); err != nil {
+			return nil, err
+		}
+		place.OsmID = osmID
+		places[i] = copyUserPlaces([]data.UserPlace{place})[0]
+		return &place, nil
+	}
+	return nil, services.ErrPlaceNotFound
+}
+
+func (r *MemoryRepository) DeleteUserPlace(_ context.Context, userID string, kind services.PlaceKind, osmType string, osmID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return services.ErrUserNotFound
+	}
+	places := userPlaces(user, kind)
+	kept := []data.UserPlace{}
+	for _, place := range *places {
+		if !services.SamePlace(place.OsmType, place.OsmID, osmType, osmID) {
+			kept = append(kept, place)
+		}
+	}
+	if len(kept) == len(*places) {
+		return services.ErrPlaceNotFound
+	}
+	*places = kept
+	return nil
+}
+
+func (r *MemoryRepository) GetSharedList(_ context.Context, shareToken string) (string, *data.List, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for userID, user := range r.users {
+		for _, list := range user.Lists {
+			if list.ShareToken == shareToken {
+				copied := copyList(list)
+				return userID, &copied, nil
+			}
+		}
+	}
+	return "", nil, services.ErrListNotFound
+}
+
+// listNameTaken reports whether a list other than exceptID is called name.
+func listNameTaken(lists []data.List, name string, exceptID string) bool {
+	for _, list := range lists {
+		if list.ListName == name && list.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func userPlaces(user *data.User, kind services.PlaceKind) *[]data.UserPlace {
+	if kind == services.WatchedPlaces {
+		return &user.WatchedPlaces
+	}
+	return &user.VisitedPlaces
+}
+
+// copyUser deep-copies a user so callers never share slices with the store.
+func copyUser(user data.User) data.User {
+	lists := make([]data.List, len(user.Lists))
+	for i, list := range user.Lists {
+		lists[i] = copyList(list)
+	}
+	user.Lists = lists
+	user.VisitedPlaces = copyUserPlaces(user.VisitedPlaces)
+	user.WatchedPlaces = copyUserPlaces(user.WatchedPlaces)
+	return user
+}
+
+func copyList(list data.List) data.List {
+	list.Places = append([]data.Place{}, list.Places...)
+	return list
+}
+
+func copyUserPlaces(places []data.UserPlace) []data.UserPlace {
+	copied := make([]data.UserPlace, len(places))
+	for i, place := range places {
+		if place.Tags != nil {
+			place.Tags = append([]string{}, place.Tags...)
+		}
+		if place.Visits != nil {
+			visits := make([]data.Visit, len(place.Visits))
+			for j, visit := range place.Visits {
+				if visit.Tags != nil {
+					visit.Tags = append([]string{}, visit.Tags...)
+				}
+				visits[j] = visit
+			}
+			place.Visits = visits
+		}
+		copied[i] = place
+	}
+	return copied
+}
@@ -0,0 +1,46 @@
+CREATE TABLE users (
+    id         TEXT PRIMARY KEY,
+    created_on TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE lists (
+    user_id   TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    id        TEXT NOT NULL,
+    position  INTEGER NOT NULL,
+    list_name TEXT NOT NULL,
+    PRIMARY KEY (user_id, id)
+);
+
+CREATE TABLE list_places (
+    user_id  TEXT NOT NULL,
+    list_id  TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    osm_id   TEXT NOT NULL,
+    osm_type TEXT NOT NULL,
+    lat      DOUBLE PRECISION NOT NULL,
+    lng      DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (user_id, list_id, position),
+    FOREIGN KEY (user_id, list_id) REFERENCES lists (user_id, id) ON DELETE CASCADE
+);
+
+CREATE TABLE visits (
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    osm_id     TEXT NOT NULL,
+    tags       TEXT NOT NULL,
+    rating     SMALLINT,
+    visited_at TIMESTAMPTZ,
+    rated_at   TIMESTAMPTZ,
+    PRIMARY KEY (user_id, position)
+);
+
+CREATE TABLE watches (
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    osm_id     TEXT NOT NULL,
+    tags       TEXT NOT NULL,
+    rating     SMALLINT,
+    visited_at TIMESTAMPTZ,
+    rated_at   TIMESTAMPTZ,
+    PRIMARY KEY (user_id, position)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE list_places ADD COLUMN note TEXT NOT NULL DEFAULT '';
+ALTER TABLE list_places ADD COLUMN added_at TIMESTAMPTZ;
+ALTER TABLE list_places ADD COLUMN added_by TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Imports waiting for review. Entries are stored as JSON, as they are only
+-- ever read and written together.
+CREATE TABLE imports (
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    id         TEXT NOT NULL,
+    source     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    entries    TEXT NOT NULL,
+    PRIMARY KEY (user_id, id)
+);
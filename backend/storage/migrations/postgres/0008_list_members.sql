@@ -0,0 +1,16 @@
+-- Users other than the owner with access to a list. Members go with the
+-- list, and with the member's own account.
+CREATE TABLE list_members (
+    owner_id    TEXT NOT NULL,
+    list_id     TEXT NOT NULL,
+    user_id     TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role        TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    invited_by  TEXT NOT NULL,
+    invited_at  TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    PRIMARY KEY (owner_id, list_id, user_id),
+    FOREIGN KEY (owner_id, list_id) REFERENCES lists (user_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX list_members_user_id ON list_members (user_id);
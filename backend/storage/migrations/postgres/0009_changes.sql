@@ -0,0 +1,13 @@
+-- The latest change to each list, visited place and watched place of a
+-- user, for delta sync. Rows of deleted entities are kept as tombstones.
+CREATE TABLE changes (
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL,
+    entity_key TEXT NOT NULL,
+    seq        BIGINT NOT NULL,
+    deleted    BOOLEAN NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, kind, entity_key)
+);
+
+CREATE INDEX changes_user_seq ON changes (user_id, seq);
@@ -0,0 +1,22 @@
+-- Visits and watches keep the OSM type of their place. Places recorded
+-- before it was kept have an empty type.
+ALTER TABLE visits ADD COLUMN osm_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE watches ADD COLUMN osm_type TEXT NOT NULL DEFAULT '';
+
+-- The place catalog shared by all users, cached from OSM.
+CREATE TABLE places (
+    osm_type      TEXT NOT NULL,
+    osm_id        TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    lat           DOUBLE PRECISION NOT NULL,
+    lng           DOUBLE PRECISION NOT NULL,
+    cuisine       TEXT NOT NULL,
+    address       TEXT NOT NULL,
+    opening_hours TEXT NOT NULL,
+    website       TEXT NOT NULL,
+    phone         TEXT NOT NULL,
+    tags          TEXT NOT NULL,
+    missing       BOOLEAN NOT NULL,
+    fetched_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (osm_type, osm_id)
+);
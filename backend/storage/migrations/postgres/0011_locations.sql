@@ -0,0 +1,18 @@
+-- Where saved places are, filled in by reverse geocoding.
+ALTER TABLE list_places ADD COLUMN city TEXT NOT NULL DEFAULT '';
+ALTER TABLE list_places ADD COLUMN neighbourhood TEXT NOT NULL DEFAULT '';
+ALTER TABLE list_places ADD COLUMN country TEXT NOT NULL DEFAULT '';
+
+-- Reverse geocoding results shared by all users, keyed by rounded
+-- coordinates.
+CREATE TABLE locations (
+    location_key  TEXT PRIMARY KEY,
+    lat           DOUBLE PRECISION NOT NULL,
+    lng           DOUBLE PRECISION NOT NULL,
+    city          TEXT NOT NULL,
+    neighbourhood TEXT NOT NULL,
+    country       TEXT NOT NULL,
+    country_code  TEXT NOT NULL,
+    display_name  TEXT NOT NULL,
+    fetched_at    TIMESTAMPTZ NOT NULL
+);
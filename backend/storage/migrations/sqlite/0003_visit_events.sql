@@ -0,0 +1,11 @@
+CREATE TABLE visit_events (
+    user_id        TEXT NOT NULL,
+    place_position INTEGER NOT NULL,
+    id             TEXT NOT NULL,
+    visited_at     TIMESTAMP NOT NULL,
+    rating         INTEGER,
+    notes          TEXT NOT NULL,
+    tags           TEXT NOT NULL,
+    PRIMARY KEY (user_id, place_position, id),
+    FOREIGN KEY (user_id, place_position) REFERENCES visits (user_id, position) ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+ALTER TABLE lists ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE lists ADD COLUMN colour TEXT NOT NULL DEFAULT '';
+ALTER TABLE lists ADD COLUMN icon TEXT NOT NULL DEFAULT '';
+
+-- List names are unique per user from now on. Older duplicates keep their
+-- first occurrence and get the list ID appended to the others.
+UPDATE lists SET list_name = list_name || ' (' || id || ')'
+WHERE EXISTS (
+    SELECT 1 FROM lists AS earlier
+    WHERE earlier.user_id = lists.user_id
+      AND earlier.list_name = lists.list_name
+      AND earlier.position < lists.position
+);
+
+CREATE UNIQUE INDEX lists_user_id_list_name ON lists (user_id, list_name);
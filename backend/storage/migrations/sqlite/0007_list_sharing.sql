@@ -0,0 +1,4 @@
+ALTER TABLE lists ADD COLUMN visibility TEXT NOT NULL DEFAULT 'private';
+ALTER TABLE lists ADD COLUMN share_token TEXT;
+
+CREATE UNIQUE INDEX lists_share_token ON lists (share_token);
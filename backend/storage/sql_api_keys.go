@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+const apiKeyColumns = `id, name, hash, scopes, created_at, expires_at, rotated_at`
+
+func (r *SQLRepository) CreateAPIKey(ctx context.Context, key data.APIKey) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := r.loadAPIKey(ctx, tx, key.ID, false)
+		if err == nil {
+			return services.ErrAPIKeyExists
+		}
+		if err != services.ErrAPIKeyNotFound {
+			return err
+		}
+		scopes, err := json.Marshal(key.Scopes)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			key.ID, key.Name, key.Hash, string(scopes), key.CreatedAt.UTC(), nullTime(key.ExpiresAt), nullTime(key.RotatedAt))
+		return err
+	})
+}
+
+func (r *SQLRepository) GetAPIKey(ctx context.Context, keyID string) (*data.APIKey, error) {
+	return r.loadAPIKey(ctx, r.db, keyID, false)
+}
+
+func (r *SQLRepository) ListAPIKeys(ctx context.Context) ([]data.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []data.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *SQLRepository) UpdateAPIKey(ctx context.Context, keyID string, update func(key *data.APIKey) error) (*data.APIKey, error) {
+	var key *data.APIKey
+	err := r.retryTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		key, err = r.loadAPIKey(ctx, tx, keyID, true)
+		if err != nil {
+			return err
+		}
+		if err := update(key); err != nil {
+			return err
+		}
+
+		scopes, err := json.Marshal(key.Scopes)
+		if err != nil {
+			return err
+		}
+		key.ID = keyID
+		_, err = tx.ExecContext(ctx, r.rebind(`UPDATE api_keys SET name = ?, hash = ?, scopes = ?, expires_at = ?, rotated_at = ? WHERE id = ?`),
+			key.Name, key.Hash, string(scopes), nullTime(key.ExpiresAt), nullTime(key.RotatedAt), keyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *SQLRepository) DeleteAPIKey(ctx context.Context, keyID string) error {
+	res, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM api_keys WHERE id = ?`), keyID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return services.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) loadAPIKey(ctx context.Context, q sqlQueryer, keyID string, lock bool) (*data.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ?`
+	if lock && r.dialect == DialectPostgres {
+		query += ` FOR UPDATE`
+	}
+
+	rows, err := q.QueryContext(ctx, r.rebind(query), keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, services.ErrAPIKeyNotFound
+	}
+	return scanAPIKey(rows)
+}
+
+func scanAPIKey(rows *sql.Rows) (*data.APIKey, error) {
+	var key data.APIKey
+	var scopes string
+	var expiresAt, rotatedAt sql.NullTime
+	if err := rows.Scan(&key.ID, &key.Name, &key.Hash, &scopes, &key.CreatedAt, &expiresAt, &rotatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+	key.ExpiresAt = timePtr(expiresAt)
+	key.RotatedAt = timePtr(rotatedAt)
+	return &key, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	catalogColumns = `osm_type, osm_id, name, lat, lng, cuisine, address, opening_hours, website, phone, tags, missing, fetched_at`
+	// catalogQueryBatch bounds the IDs bound to a single query.
+	catalogQueryBatch = 500
+)
+
+func (r *SQLRepository) GetCatalogPlaces(ctx context.Context, keys []string) ([]data.CatalogPlace, error) {
+	idsByType := map[string][]any{}
+	for _, key := range keys {
+		osmType, osmID := services.SplitPlaceKey(key)
+		idsByType[osmType] = append(idsByType[osmType], osmID)
+	}
+
+	places := []data.CatalogPlace{}
+	for osmType, ids := range idsByType {
+		for start := 0; start < len(ids); start += catalogQueryBatch {
+			batch := ids[start:min(start+catalogQueryBatch, len(ids))]
+			query := `SELECT ` + catalogColumns + ` FROM places WHERE osm_type = ? AND osm_id IN (?` + strings.Repeat(`, ?`, len(batch)-1) + `)`
+			rows, err := r.db.QueryContext(ctx, r.rebind(query), append([]any{osmType}, batch...)...)
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				place, err := scanCatalogPlace(rows)
+				if err != nil {
+					rows.Close()
+					return nil, err
+				}
+				places = append(places, place)
+			}
+			err = rows.Err()
+			rows.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return places, nil
+}
+
+func (r *SQLRepository) SaveCatalogPlaces(ctx context.Context, places []data.CatalogPlace) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, place := range places {
+			tags, err := json.Marshal(place.Tags)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO places (`+catalogColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (osm_type, osm_id) DO UPDATE SET name = excluded.name, lat = excluded.lat, lng = excluded.lng,
+					cuisine = excluded.cuisine, address = excluded.address, opening_hours = excluded.opening_hours,
+					website = excluded.website, phone = excluded.phone, tags = excluded.tags,
+					missing = excluded.missing, fetched_at = excluded.fetched_at`),
+				place.OsmType, place.OsmID, place.Name, place.Lat, place.Long, place.Cuisine, place.Address,
+				place.OpeningHours, place.Website, place.Phone, string(tags), place.Missing, place.FetchedAt.UTC())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func scanCatalogPlace(rows *sql.Rows) (data.CatalogPlace, error) {
+	var place data.CatalogPlace
+	var tags string
+	err := rows.Scan(&place.OsmType, &place.OsmID, &place.Name, &place.Lat, &place.Long, &place.Cuisine, &place.Address,
+		&place.OpeningHours, &place.Website, &place.Phone, &tags, &place.Missing, &place.FetchedAt)
+	if err != nil {
+		return place, err
+	}
+	if err := json.Unmarshal([]byte(tags), &place.Tags); err != nil {
+		return place, err
+	}
+	place.Key = services.PlaceKey(place.OsmType, place.OsmID)
+	return place, nil
+}
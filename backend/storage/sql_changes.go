@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"backend/data"
+	"context"
+	"database/sql"
+)
+
+func (r *SQLRepository) RecordChange(ctx context.Context, userID string, change data.Change) (int64, error) {
+	err := r.retryTx(ctx, func(tx *sql.Tx) error {
+		// Locking the user row hands out sequence numbers one at a time.
+		if err := r.checkUser(ctx, tx, userID, true); err != nil {
+			return err
+		}
+		err := tx.QueryRowContext(ctx, r.rebind(`SELECT COALESCE(MAX(seq), 0) + 1 FROM changes WHERE user_id = ?`), userID).Scan(&change.Seq)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO changes (user_id, kind, entity_key, seq, deleted, changed_at) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, kind, entity_key) DO UPDATE SET seq = excluded.seq, deleted = excluded.deleted, changed_at = excluded.changed_at`),
+			userID, change.Kind, change.Key, change.Seq, change.Deleted, change.ChangedAt.UTC())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return change.Seq, nil
+}
+
+func (r *SQLRepository) GetChanges(ctx context.Context, userID string, since int64) ([]data.Change, int64, error) {
+	var changes []data.Change
+	var latest int64
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, userID, false); err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, r.rebind(`SELECT COALESCE(MAX(seq), 0) FROM changes WHERE user_id = ?`), userID).Scan(&latest); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, r.rebind(`SELECT kind, entity_key, seq, deleted, changed_at FROM changes WHERE user_id = ? AND seq > ? AND seq <= ? ORDER BY seq`), userID, since, latest)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		changes = []data.Change{}
+		for rows.Next() {
+			var change data.Change
+			if err := rows.Scan(&change.Kind, &change.Key, &change.Seq, &change.Deleted, &change.ChangedAt); err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return changes, latest, nil
+}
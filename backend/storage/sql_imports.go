@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+const importColumns = `id, source, created_at, entries`
+
+func (r *SQLRepository) CreateImport(ctx context.Context, userID string, imp data.Import) error {
+	entries, err := json.Marshal(imp.Entries)
+	if err != nil {
+		return err
+	}
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, userID, false); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, r.rebind(`INSERT INTO imports (user_id, `+importColumns+`) VALUES (?, ?, ?, ?, ?)`),
+			userID, imp.ID, imp.Source, imp.CreatedAt.UTC(), string(entries))
+		return err
+	})
+}
+
+func (r *SQLRepository) GetImport(ctx context.Context, userID string, importID string) (*data.Import, error) {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return nil, err
+	}
+	return r.loadImport(ctx, r.db, userID, importID, false)
+}
+
+func (r *SQLRepository) ListImports(ctx context.Context, userID string) ([]data.Import, error) {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT `+importColumns+` FROM imports WHERE user_id = ? ORDER BY created_at, id`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []data.Import{}
+	for rows.Next() {
+		imp, err := scanImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, *imp)
+	}
+	return imports, rows.Err()
+}
+
+func (r *SQLRepository) UpdateImport(ctx context.Context, userID string, importID string, update func(imp *data.Import) error) (*data.Import, error) {
+	var imp *data.Import
+	err := r.retryTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, userID, false); err != nil {
+			return err
+		}
+		var err error
+		imp, err = r.loadImport(ctx, tx, userID, importID, true)
+		if err != nil {
+			return err
+		}
+		if err := update(imp); err != nil {
+			return err
+		}
+
+		entries, err := json.Marshal(imp.Entries)
+		if err != nil {
+			return err
+		}
+		imp.ID = importID
+		_, err = tx.ExecContext(ctx, r.rebind(`UPDATE imports SET source = ?, entries = ? WHERE user_id = ? AND id = ?`),
+			imp.Source, string(entries), userID, importID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+func (r *SQLRepository) DeleteImport(ctx context.Context, userID string, importID string) error {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM imports WHERE user_id = ? AND id = ?`), userID, importID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return services.ErrImportNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) loadImport(ctx context.Context, q sqlQueryer, userID string, importID string, lock bool) (*data.Import, error) {
+	query := `SELECT ` + importColumns + ` FROM imports WHERE user_id = ? AND id = ?`
+	if lock && r.dialect == DialectPostgres {
+		query += ` FOR UPDATE`
+	}
+
+	rows, err := q.QueryContext(ctx, r.rebind(query), userID, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, services.ErrImportNotFound
+	}
+	return scanImport(rows)
+}
+
+func scanImport(rows *sql.Rows) (*data.Import, error) {
+	var imp data.Import
+	var entries string
+	if err := rows.Scan(&imp.ID, &imp.Source, &imp.CreatedAt, &entries); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(entries), &imp.Entries); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
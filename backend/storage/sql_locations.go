@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"database/sql"
+)
+
+func (r *SQLRepository) GetLocation(ctx context.Context, key string) (*data.Location, error) {
+	location := data.Location{Key: key}
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT lat, lng, city, neighbourhood, country, country_code, display_name, fetched_at FROM locations WHERE location_key = ?`), key).
+		Scan(&location.Lat, &location.Long, &location.City, &location.Neighbourhood, &location.Country, &location.CountryCode, &location.DisplayName, &location.FetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, services.ErrLocationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *SQLRepository) SaveLocation(ctx context.Context, location data.Location) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO locations (location_key, lat, lng, city, neighbourhood, country, country_code, display_name, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (location_key) DO UPDATE SET lat = excluded.lat, lng = excluded.lng, city = excluded.city,
+			neighbourhood = excluded.neighbourhood, country = excluded.country, country_code = excluded.country_code,
+			display_name = excluded.display_name, fetched_at = excluded.fetched_at`),
+		location.Key, location.Lat, location.Long, location.City, location.Neighbourhood, location.Country,
+		location.CountryCode, location.DisplayName, location.FetchedAt.UTC())
+	return err
+}
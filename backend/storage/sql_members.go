@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"database/sql"
+)
+
+const memberColumns = `owner_id, list_id, user_id, role, status, invited_by, invited_at, accepted_at`
+
+func (r *SQLRepository) AddListMember(ctx context.Context, member data.ListMember) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, member.UserID, false); err != nil {
+			return err
+		}
+		if _, err := r.loadList(ctx, tx, member.OwnerID, member.ListID); err != nil {
+			return err
+		}
+		_, err := r.loadMember(ctx, tx, member.OwnerID, member.ListID, member.UserID, false)
+		if err == nil {
+			return services.ErrMemberExists
+		}
+		if err != services.ErrMemberNotFound {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO list_members (`+memberColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			member.OwnerID, member.ListID, member.UserID, member.Role, member.Status, member.InvitedBy, member.InvitedAt.UTC(), nullTime(member.AcceptedAt))
+		return err
+	})
+}
+
+func (r *SQLRepository) GetListMembers(ctx context.Context, ownerID string, listID string) ([]data.ListMember, error) {
+	return r.queryMembers(ctx, `SELECT `+memberColumns+` FROM list_members WHERE owner_id = ? AND list_id = ? ORDER BY invited_at, user_id`, ownerID, listID)
+}
+
+func (r *SQLRepository) GetMemberships(ctx context.Context, userID string) ([]data.ListMember, error) {
+	return r.queryMembers(ctx, `SELECT `+memberColumns+` FROM list_members WHERE user_id = ? ORDER BY invited_at, owner_id, list_id`, userID)
+}
+
+func (r *SQLRepository) UpdateListMember(ctx context.Context, ownerID string, listID string, userID string, update func(member *data.ListMember) error) (*data.ListMember, error) {
+	var member *data.ListMember
+	err := r.retryTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		member, err = r.loadMember(ctx, tx, ownerID, listID, userID, true)
+		if err != nil {
+			return err
+		}
+		if err := update(member); err != nil {
+			return err
+		}
+
+		member.OwnerID, member.ListID, member.UserID = ownerID, listID, userID
+		_, err = tx.ExecContext(ctx, r.rebind(`UPDATE list_members SET role = ?, status = ?, accepted_at = ? WHERE owner_id = ? AND list_id = ? AND user_id = ?`),
+			member.Role, member.Status, nullTime(member.AcceptedAt), ownerID, listID, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *SQLRepository) DeleteListMember(ctx context.Context, ownerID string, listID string, userID string) error {
+	res, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM list_members WHERE owner_id = ? AND list_id = ? AND user_id = ?`), ownerID, listID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return services.ErrMemberNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) queryMembers(ctx context.Context, query string, args ...any) ([]data.ListMember, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []data.ListMember{}
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *member)
+	}
+	return members, rows.Err()
+}
+
+func (r *SQLRepository) loadMember(ctx context.Context, q sqlQueryer, ownerID string, listID string, userID string, lock bool) (*data.ListMember, error) {
+	query := `SELECT ` + memberColumns + ` FROM list_members WHERE owner_id = ? AND list_id = ? AND user_id = ?`
+	if lock && r.dialect == DialectPostgres {
+		query += ` FOR UPDATE`
+	}
+
+	rows, err := q.QueryContext(ctx, r.rebind(query), ownerID, listID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, services.ErrMemberNotFound
+	}
+	return scanMember(rows)
+}
+
+func scanMember(rows *sql.Rows) (*data.ListMember, error) {
+	var member data.ListMember
+	var acceptedAt sql.NullTime
+	if err := rows.Scan(&member.OwnerID, &member.ListID, &member.UserID, &member.Role, &member.Status, &member.InvitedBy, &member.InvitedAt, &acceptedAt); err != nil {
+		return nil, err
+	}
+	member.AcceptedAt = timePtr(acceptedAt)
+	return &member, nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrate applies every migration for the dialect that has not been recorded
+// in schema_migrations yet. Files are named <version>_<description>.sql and
+// run in version order, each in its own transaction.
+func (r *SQLRepository) migrate(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := r.db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dir := path.Join("migrations", r.dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+		if applied[version] {
+			continue
+		}
+
+		script, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := r.applyMigration(ctx, version, string(script)); err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (r *SQLRepository) applyMigration(ctx context.Context, version int, script string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range splitStatements(script) {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, r.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version)
+		return err
+	})
+}
+
+// splitStatements splits a script into its statements at the semicolons
+// that end them. Semicolons inside quoted strings, quoted identifiers and
+// comments are kept with their statement.
+func splitStatements(script string) []string {
+	var statements []string
+	start := 0
+	for i := 0; i < len(script); i++ {
+		switch script[i] {
+		case '\'', '"':
+			// A doubled quote is an escaped quote, which the next pass over
+			// this case reopens.
+			if end := strings.IndexByte(script[i+1:], script[i]); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(script)
+			}
+		case '-':
+			if strings.HasPrefix(script[i:], "--") {
+				if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+					i += end
+				} else {
+					i = len(script)
+				}
+			}
+		case '/':
+			if strings.HasPrefix(script[i:], "/*") {
+				if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+					i += end + 3
+				} else {
+					i = len(script)
+				}
+			}
+		case ';':
+			statements = append(statements, script[start:i])
+			start = i + 1
+		}
+	}
+	statements = append(statements, script[start:])
+
+	nonEmpty := statements[:0]
+	for _, stmt := range statements {
+		if strings.TrimSpace(stmt) != "" {
+			nonEmpty = append(nonEmpty, stmt)
+		}
+	}
+	return nonEmpty
+}
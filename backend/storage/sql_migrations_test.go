@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- Names get a suffix; older ones keep theirs.
+UPDATE lists SET list_name = list_name || ';' WHERE icon = 'a;b';
+CREATE TABLE "odd;name" (id TEXT);
+/* a comment; with a semicolon */ SELECT 'it''s; fine';
+`
+	got := splitStatements(script)
+	for i := range got {
+		got[i] = strings.TrimSpace(got[i])
+	}
+	want := []string{
+		"-- Names get a suffix; older ones keep theirs.\nUPDATE lists SET list_name = list_name || ';' WHERE icon = 'a;b'",
+		`CREATE TABLE "odd;name" (id TEXT)`,
+		"/* a comment; with a semicolon */ SELECT 'it''s; fine'",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("splitStatements = %q, want %q", got, want)
+	}
+}
+
+func TestMigrateTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	for range 2 {
+		repo, err := OpenSQL(t.Context(), DialectSQLite, path)
+		if err != nil {
+			t.Fatalf("OpenSQL: %v", err)
+		}
+		if err := repo.migrate(t.Context()); err != nil {
+			t.Errorf("migrate again: %v", err)
+		}
+		var versions int
+		if err := repo.db.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM schema_migrations`).Scan(&versions); err != nil {
+			t.Fatalf("counting migrations: %v", err)
+		}
+		entries, err := migrationFiles.ReadDir("migrations/" + DialectSQLite)
+		if err != nil {
+			t.Fatalf("reading migrations: %v", err)
+		}
+		if versions != len(entries) {
+			t.Errorf("schema_migrations holds %d versions, want %d", versions, len(entries))
+		}
+		repo.Close()
+	}
+}
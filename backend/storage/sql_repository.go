@@ -0,0 +1,665 @@
+package storage
+
+import (
+	"backend/data"
+	"backend/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// samePlaceCondition matches the rows of visits or watches that are the
+// place with a given OSM ID and type by services.SamePlace. It takes the ID
+// and then the type twice.
+const samePlaceCondition = `osm_id = ? AND (? = '' OR osm_type = '' OR osm_type = ?)`
+
+// SQLRepository stores users, lists and places in a relational database. SQLite and Postgres
+// are supported; the schema is created and upgraded on open.
+type SQLRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+// OpenSQL connects to the database described by dsn and applies any pending
+// migrations. For SQLite the dsn is a file path such as "eatfinder.db".
+func OpenSQL(ctx context.Context, dialect string, dsn string) (*SQLRepository, error) {
+	var driver string
+	switch dialect {
+	case DialectSQLite:
+		driver = "sqlite"
+		dsn = sqliteDSN(dsn)
+	case DialectPostgres:
+		driver = "pgx"
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dialect == DialectSQLite {
+		// SQLite allows a single writer; sharing one connection avoids SQLITE_BUSY.
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	r := &SQLRepository{db: db, dialect: dialect}
+	if err := r.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// sqliteDSN turns on foreign keys so ON DELETE CASCADE is honoured.
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "foreign_keys") {
+		return dsn
+	}
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=foreign_keys(1)"
+}
+
+func (r *SQLRepository) Close() error {
+	return r.db.Close()
+}
+
+// rebind rewrites ? placeholders into the $n form Postgres expects.
+func (r *SQLRepository) rebind(query string) string {
+	if r.dialect != DialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// isRetryable reports whether err is a Postgres serialization failure or
+// deadlock, after which the whole transaction can safely run again.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
+func (r *SQLRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// retryTx runs fn in a transaction, running it again after Postgres
+// serialization failures and deadlocks. It returns ErrConflict once the
+// attempts are used up.
+func (r *SQLRepository) retryTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	for attempt := 0; attempt < transactionAttempts; attempt++ {
+		err := r.withTx(ctx, fn)
+		if !isRetryable(err) {
+			return err
+		}
+	}
+	return services.ErrConflict
+}
+
+// sqlQueryer is satisfied by both *sql.DB and *sql.Tx.
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// checkUser returns ErrUserNotFound if the user does not exist. With lock set
+// on Postgres the user row is held until the surrounding transaction ends, so
+// writes to the same user's lists and places run one after another.
+func (r *SQLRepository) checkUser(ctx context.Context, q sqlQueryer, userID string, lock bool) error {
+	query := `SELECT id FROM users WHERE id = ?`
+	if lock && r.dialect == DialectPostgres {
+		query += ` FOR UPDATE`
+	}
+
+	var id string
+	err := q.QueryRowContext(ctx, r.rebind(query), userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return services.ErrUserNotFound
+	}
+	return err
+}
+
+// nextPosition returns the position after the last row the user has in table.
+func (r *SQLRepository) nextPosition(ctx context.Context, tx *sql.Tx, table string, userID string) (int, error) {
+	var position int
+	err := tx.QueryRowContext(ctx, r.rebind(`SELECT COALESCE(MAX(position), -1) + 1 FROM `+table+` WHERE user_id = ?`), userID).Scan(&position)
+	return position, err
+}
+
+func (r *SQLRepository) CreateUser(ctx context.Context, user *data.User) (string, error) {
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		err := r.checkUser(ctx, tx, user.ID, false)
+		if err == nil {
+			return services.ErrUserAlreadyExists
+		}
+		if err != services.ErrUserNotFound {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO users (id, created_on) VALUES (?, ?)`), user.ID, user.CreatedOn.UTC())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+func (r *SQLRepository) GetUser(ctx context.Context, userID string) (*data.User, error) {
+	user := data.User{ID: userID}
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT created_on FROM users WHERE id = ?`), userID).Scan(&user.CreatedOn)
+	if err == sql.ErrNoRows {
+		return nil, services.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Lists, err = r.loadLists(ctx, r.db, userID); err != nil {
+		return nil, err
+	}
+	if user.VisitedPlaces, err = r.loadUserPlaces(ctx, r.db, services.VisitedPlaces, userID); err != nil {
+		return nil, err
+	}
+	if user.WatchedPlaces, err = r.loadUserPlaces(ctx, r.db, services.WatchedPlaces, userID); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *SQLRepository) DeleteUser(ctx context.Context, userID string) error {
+	res, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM users WHERE id = ?`), userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return services.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetLists(ctx context.Context, userID string) ([]data.List, error) {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return nil, err
+	}
+	return r.loadLists(ctx, r.db, userID)
+}
+
+func (r *SQLRepository) CreateList(ctx context.Context, userID string, list data.List) error {
+	return r.retryTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, userID, true); err != nil {
+			return err
+		}
+		if err := r.checkListName(ctx, tx, userID, list.ListName, ""); err != nil {
+			return err
+		}
+		position, err := r.nextPosition(ctx, tx, "lists", userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO lists (user_id, id, position, list_name, description, colour, icon, visibility, share_token) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			userID, list.ID, position, list.ListName, list.Description, list.Colour, list.Icon, list.Visibility, nullString(list.ShareToken))
+		if err != nil {
+			return err
+		}
+		return r.insertListPlaces(ctx, tx, userID, list.ID, list.Places)
+	})
+}
+
+func (r *SQLRepository) GetList(ctx context.Context, userID string, listID string) (*data.List, error) {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return nil, err
+	}
+	return r.loadList(ctx, r.db, userID, listID)
+}
+
+func (r *SQLRepository) UpdateList(ctx context.Context, userID string, listID string, update func(list *data.List) error) (*data.List, error) {
+	var list *data.List
+	err := r.retryTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, userID, true); err != nil {
+			return err
+		}
+		var err error
+		list, err = r.loadList(ctx, tx, userID, listID)
+		if err != nil {
+			return err
+		}
+		if err := update(list); err != nil {
+			return err
+		}
+
+		list.ID = listID
+		if err := r.checkListName(ctx, tx, userID, list.ListName, listID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`UPDATE lists SET list_name = ?, description = ?, colour = ?, icon = ?, visibility = ?, share_token = ? WHERE user_id = ? AND id = ?`),
+			list.ListName, list.Description, list.Colour, list.Icon, list.Visibility, nullString(list.ShareToken), userID, listID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`DELETE FROM list_places WHERE user_id = ? AND list_id = ?`), userID, listID)
+		if err != nil {
+			return err
+		}
+		return r.insertListPlaces(ctx, tx, userID, listID, list.Places)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *SQLRepository) DeleteList(ctx context.Context, userID string, listID string) error {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM lists WHERE user_id = ? AND id = ?`), userID, listID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return services.ErrListNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetUserPlaces(ctx context.Context, userID string, kind services.PlaceKind) ([]data.UserPlace, error) {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return nil, err
+	}
+	return r.loadUserPlaces(ctx, r.db, kind, userID)
+}
+
+func (r *SQLRepository) AddUserPlace(ctx context.Context, userID string, kind services.PlaceKind, place data.UserPlace) error {
+	table := string(kind)
+	return r.retryTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, userID, true); err != nil {
+			return err
+		}
+		var exists bool
+		err := tx.QueryRowContext(ctx, r.rebind(`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE user_id = ? AND `+samePlaceCondition+`)`), userID, place.OsmID, place.OsmType, place.OsmType).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return services.ErrPlaceExists
+		}
+		position, err := r.nextPosition(ctx, tx, table, userID)
+		if err != nil {
+			return err
+		}
+
+		tags, err := json.Marshal(place.Tags)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO `+table+` (user_id, position, osm_id, osm_type, tags, rating, visited_at, rated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			userID, position, place.OsmID, place.OsmType, string(tags), nullRating(place.Rating), nullTime(place.VisitedAt), nullTime(place.RatedAt))
+		if err != nil || kind != services.VisitedPlaces {
+			return err
+		}
+		return r.insertVisitEvents(ctx, tx, userID, position, place.Visits)
+	})
+}
+
+func (r *SQLRepository) UpdateUserPlace(ctx context.Context, userID string, kind services.PlaceKind, osmType string, osmID string, update func(place *data.UserPlace) error) (*data.UserPlace, error) {
+	table := string(kind)
+	var place data.UserPlace
+	err := r.retryTx(ctx, func(tx *sql.Tx) error {
+		if err := r.checkUser(ctx, tx, userID, true); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, r.rebind(`SELECT position, osm_id, osm_type, tags, rating, visited_at, rated_at FROM `+table+` WHERE user_id = ? AND `+samePlaceCondition+` ORDER BY position LIMIT 1`), userID, osmID, osmType, osmType)
+		if err != nil {
+			return err
+		}
+		var position int
+		found := rows.Next()
+		if found {
+			place, err = scanUserPlace(rows, &position)
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if !found {
+			return services.ErrPlaceNotFound
+		}
+		if kind == services.VisitedPlaces {
+			events, err := r.loadVisitEvents(ctx, tx, userID)
+			if err != nil {
+				return err
+			}
+			place.Visits = events[position]
+		}
+
+		if err := update(&place); err != nil {
+			return err
+		}
+		place.OsmID = osmID
+		tags, err := json.Marshal(place.Tags)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`UPDATE `+table+` SET osm_type = ?, tags = ?, rating = ?, visited_at = ?, rated_at = ? WHERE user_id = ? AND position = ?`),
+			place.OsmType, string(tags), nullRating(place.Rating), nullTime(place.VisitedAt), nullTime(place.RatedAt), userID, position)
+		if err != nil || kind != services.VisitedPlaces {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`DELETE FROM visit_events WHERE user_id = ? AND place_position = ?`), userID, position)
+		if err != nil {
+			return err
+		}
+		return r.insertVisitEvents(ctx, tx, userID, position, place.Visits)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &place, nil
+}
+
+func (r *SQLRepository) DeleteUserPlace(ctx context.Context, userID string, kind services.PlaceKind, osmType string, osmID string) error {
+	if err := r.checkUser(ctx, r.db, userID, false); err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM `+string(kind)+` WHERE user_id = ? AND `+samePlaceCondition), userID, osmID, osmType, osmType)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return services.ErrPlaceNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetSharedList(ctx context.Context, shareToken string) (string, *data.List, error) {
+	var userID, listID string
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT user_id, id FROM lists WHERE share_token = ?`), shareToken).Scan(&userID, &listID)
+	if err == sql.ErrNoRows {
+		return "", nil, services.ErrListNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	list, err := r.loadList(ctx, r.db, userID, listID)
+	if err != nil {
+		return "", nil, err
+	}
+	return userID, list, nil
+}
+
+// checkListName returns ErrListNameTaken if a list other than exceptID is
+// called name.
+func (r *SQLRepository) checkListName(ctx context.Context, tx *sql.Tx, userID string, name string, exceptID string) error {
+	var taken bool
+	err := tx.QueryRowContext(ctx, r.rebind(`SELECT EXISTS (SELECT 1 FROM lists WHERE user_id = ? AND list_name = ? AND id <> ?)`), userID, name, exceptID).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return services.ErrListNameTaken
+	}
+	return nil
+}
+
+func (r *SQLRepository) insertListPlaces(ctx context.Context, tx *sql.Tx, userID string, listID string, places []data.Place) error {
+	for i, place := range places {
+		_, err := tx.ExecContext(ctx, r.rebind(`INSERT INTO list_places (user_id, list_id, position, osm_id, osm_type, lat, lng, city, neighbourhood, country, note, added_at, added_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			userID, listID, i, place.OsmID, place.OsmType, place.Lat, place.Long, place.City, place.Neighbourhood, place.Country, place.Note, nullTime(place.AddedAt), place.AddedBy)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLRepository) loadLists(ctx context.Context, q sqlQueryer, userID string) ([]data.List, error) {
+	rows, err := q.QueryContext(ctx, r.rebind(`SELECT id, list_name, description, colour, icon, visibility, share_token FROM lists WHERE user_id = ? ORDER BY position`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []data.List{}
+	for rows.Next() {
+		var list data.List
+		var shareToken sql.NullString
+		if err := rows.Scan(&list.ID, &list.ListName, &list.Description, &list.Colour, &list.Icon, &list.Visibility, &shareToken); err != nil {
+			return nil, err
+		}
+		list.ShareToken = shareToken.String
+		lists = append(lists, list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range lists {
+		if lists[i].Places, err = r.loadListPlaces(ctx, q, userID, lists[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return lists, nil
+}
+
+func (r *SQLRepository) loadList(ctx context.Context, q sqlQueryer, userID string, listID string) (*data.List, error) {
+	list := data.List{ID: listID}
+	var shareToken sql.NullString
+	err := q.QueryRowContext(ctx, r.rebind(`SELECT list_name, description, colour, icon, visibility, share_token FROM lists WHERE user_id = ? AND id = ?`), userID, listID).
+		Scan(&list.ListName, &list.Description, &list.Colour, &list.Icon, &list.Visibility, &shareToken)
+	if err == sql.ErrNoRows {
+		return nil, services.ErrListNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list.ShareToken = shareToken.String
+	if list.Places, err = r.loadListPlaces(ctx, q, userID, listID); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *SQLRepository) loadListPlaces(ctx context.Context, q sqlQueryer, userID string, listID string) ([]data.Place, error) {
+	rows, err := q.QueryContext(ctx, r.rebind(`SELECT osm_id, osm_type, lat, lng, city, neighbourhood, country, note, added_at, added_by FROM list_places WHERE user_id = ? AND list_id = ? ORDER BY position`), userID, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	places := []data.Place{}
+	for rows.Next() {
+		var place data.Place
+		var addedAt sql.NullTime
+		if err := rows.Scan(&place.OsmID, &place.OsmType, &place.Lat, &place.Long, &place.City, &place.Neighbourhood, &place.Country, &place.Note, &addedAt, &place.AddedBy); err != nil {
+			return nil, err
+		}
+		place.AddedAt = timePtr(addedAt)
+		places = append(places, place)
+	}
+	return places, rows.Err()
+}
+
+func (r *SQLRepository) loadUserPlaces(ctx context.Context, q sqlQueryer, kind services.PlaceKind, userID string) ([]data.UserPlace, error) {
+	rows, err := q.QueryContext(ctx, r.rebind(`SELECT position, osm_id, osm_type, tags, rating, visited_at, rated_at FROM `+string(kind)+` WHERE user_id = ? ORDER BY position`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	places := []data.UserPlace{}
+	var positions []int
+	for rows.Next() {
+		var position int
+		place, err := scanUserPlace(rows, &position)
+		if err != nil {
+			return nil, err
+		}
+		places = append(places, place)
+		positions = append(positions, position)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if kind != services.VisitedPlaces {
+		return places, nil
+	}
+	events, err := r.loadVisitEvents(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range places {
+		places[i].Visits = events[positions[i]]
+	}
+	return places, nil
+}
+
+// loadVisitEvents returns the user's visit events keyed by the position of
+// the visited place they belong to.
+func (r *SQLRepository) loadVisitEvents(ctx context.Context, q sqlQueryer, userID string) (map[int][]data.Visit, error) {
+	rows, err := q.QueryContext(ctx, r.rebind(`SELECT place_position, id, visited_at, rating, notes, tags FROM visit_events WHERE user_id = ? ORDER BY visited_at, id`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make(map[int][]data.Visit)
+	for rows.Next() {
+		var position int
+		var visit data.Visit
+		var rating sql.NullInt16
+		var tags string
+		if err := rows.Scan(&position, &visit.ID, &visit.VisitedAt, &rating, &visit.Notes, &tags); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tags), &visit.Tags); err != nil {
+			return nil, err
+		}
+		if rating.Valid {
+			value := int8(rating.Int16)
+			visit.Rating = &value
+		}
+		events[position] = append(events[position], visit)
+	}
+	return events, rows.Err()
+}
+
+func (r *SQLRepository) insertVisitEvents(ctx context.Context, tx *sql.Tx, userID string, position int, visits []data.Visit) error {
+	for _, visit := range visits {
+		tags, err := json.Marshal(visit.Tags)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.rebind(`INSERT INTO visit_events (user_id, place_position, id, visited_at, rating, notes, tags) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			userID, position, visit.ID, visit.VisitedAt.UTC(), nullRating(visit.Rating), visit.Notes, string(tags))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanUserPlace scans osm_id, osm_type, tags, rating, visited_at and
+// rated_at, after any extra leading columns given in dest.
+func scanUserPlace(rows *sql.Rows, dest ...any) (data.UserPlace, error) {
+	var place data.UserPlace
+	var tags string
+	var rating sql.NullInt16
+	var visitedAt, ratedAt sql.NullTime
+	dest = append(dest, &place.OsmID, &place.OsmType, &tags, &rating, &visitedAt, &ratedAt)
+	if err := rows.Scan(dest...); err != nil {
+		return place, err
+	}
+	if err := json.Unmarshal([]byte(tags), &place.Tags); err != nil {
+		return place, err
+	}
+	if rating.Valid {
+		value := int8(rating.Int16)
+		place.Rating = &value
+	}
+	place.VisitedAt = timePtr(visitedAt)
+	place.RatedAt = timePtr(ratedAt)
+	return place, nil
+}
+
+func nullRating(rating *int8) sql.NullInt16 {
+	if rating == nil {
+		return sql.NullInt16{}
+	}
+	return sql.NullInt16{Int16: int16(*rating), Valid: true}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	value := t.Time
+	return &value
+}
@@ -0,0 +1,201 @@
+package storage_test
+
+import (
+	"backend/data"
+	"backend/services"
+	"backend/storage"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openSQLite returns a repository on a new SQLite file.
+func openSQLite(t *testing.T) *storage.SQLRepository {
+	t.Helper()
+	repo, err := storage.OpenSQL(t.Context(), storage.DialectSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenSQL: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func createUser(t *testing.T, repo *storage.SQLRepository, userID string) {
+	t.Helper()
+	if _, err := repo.CreateUser(t.Context(), &data.User{ID: userID, CreatedOn: time.Now()}); err != nil {
+		t.Fatalf("CreateUser(%s): %v", userID, err)
+	}
+}
+
+func TestSQLUsers(t *testing.T) {
+	repo := openSQLite(t)
+	ctx := t.Context()
+	createUser(t, repo, "u1")
+	if _, err := repo.CreateUser(ctx, &data.User{ID: "u1"}); !errors.Is(err, services.ErrUserAlreadyExists) {
+		t.Errorf("CreateUser twice: got %v, want ErrUserAlreadyExists", err)
+	}
+
+	user, err := repo.GetUser(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user.ID != "u1" || len(user.Lists) != 0 || len(user.VisitedPlaces) != 0 {
+		t.Errorf("GetUser = %+v, want an empty u1", user)
+	}
+
+	if err := repo.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := repo.GetUser(ctx, "u1"); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("GetUser after DeleteUser: got %v, want ErrUserNotFound", err)
+	}
+	if err := repo.DeleteUser(ctx, "u1"); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("DeleteUser twice: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestSQLLists(t *testing.T) {
+	repo := openSQLite(t)
+	ctx := t.Context()
+	createUser(t, repo, "u1")
+
+	addedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	list := data.List{ID: "l1", ListName: "Lunch", Visibility: "private", Places: []data.Place{
+		{OsmID: "1", OsmType: "node", Lat: 52.5, Long: 13.4, Note: "window seat", AddedAt: &addedAt, AddedBy: "u1"},
+		{OsmID: "2", OsmType: "way", Lat: 52.6, Long: 13.5},
+	}}
+	if err := repo.CreateList(ctx, "u1", list); err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	if err := repo.CreateList(ctx, "u1", data.List{ID: "l2", ListName: "Lunch"}); !errors.Is(err, services.ErrListNameTaken) {
+		t.Errorf("CreateList with a taken name: got %v, want ErrListNameTaken", err)
+	}
+	if err := repo.CreateList(ctx, "nobody", data.List{ID: "l3", ListName: "Dinner"}); !errors.Is(err, services.ErrUserNotFound) {
+		t.Errorf("CreateList for an unknown user: got %v, want ErrUserNotFound", err)
+	}
+
+	got, err := repo.GetList(ctx, "u1", "l1")
+	if err != nil {
+		t.Fatalf("GetList: %v", err)
+	}
+	if len(got.Places) != 2 || got.Places[0].Note != "window seat" || got.Places[0].AddedAt == nil || !got.Places[0].AddedAt.Equal(addedAt) || got.Places[1].OsmID != "2" {
+		t.Errorf("GetList = %+v, want both places in order with their entry details", got)
+	}
+
+	updated, err := repo.UpdateList(ctx, "u1", "l1", func(list *data.List) error {
+		list.ListName = "Lunch spots"
+		list.Places = list.Places[1:]
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateList: %v", err)
+	}
+	if updated.ListName != "Lunch spots" || len(updated.Places) != 1 {
+		t.Errorf("UpdateList = %+v, want the renamed list with one place", updated)
+	}
+	lists, err := repo.GetLists(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetLists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].ListName != "Lunch spots" || len(lists[0].Places) != 1 || lists[0].Places[0].OsmID != "2" {
+		t.Errorf("GetLists = %+v, want the updated list", lists)
+	}
+
+	if err := repo.DeleteList(ctx, "u1", "l1"); err != nil {
+		t.Fatalf("DeleteList: %v", err)
+	}
+	if _, err := repo.GetList(ctx, "u1", "l1"); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("GetList after DeleteList: got %v, want ErrListNotFound", err)
+	}
+	if err := repo.DeleteList(ctx, "u1", "l1"); !errors.Is(err, services.ErrListNotFound) {
+		t.Errorf("DeleteList twice: got %v, want ErrListNotFound", err)
+	}
+}
+
+func TestSQLUserPlaces(t *testing.T) {
+	repo := openSQLite(t)
+	ctx := t.Context()
+	createUser(t, repo, "u1")
+
+	rating := int8(4)
+	visitedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"1", "2", "3"} {
+		place := data.UserPlace{OsmID: id, OsmType: "node", Tags: []string{"lunch"}, Visits: []data.Visit{
+			{ID: "v" + id, VisitedAt: visitedAt, Rating: &rating, Tags: []string{}},
+		}}
+		if err := repo.AddUserPlace(ctx, "u1", services.VisitedPlaces, place); err != nil {
+			t.Fatalf("AddUserPlace(%s): %v", id, err)
+		}
+	}
+	if err := repo.AddUserPlace(ctx, "u1", services.VisitedPlaces, data.UserPlace{OsmID: "1", OsmType: "node"}); !errors.Is(err, services.ErrPlaceExists) {
+		t.Errorf("AddUserPlace twice: got %v, want ErrPlaceExists", err)
+	}
+
+	// Places are stored by position, so visits must stay with their place
+	// when one before them is deleted.
+	if err := repo.DeleteUserPlace(ctx, "u1", services.VisitedPlaces, "node", "2"); err != nil {
+		t.Fatalf("DeleteUserPlace: %v", err)
+	}
+	updated, err := repo.UpdateUserPlace(ctx, "u1", services.VisitedPlaces, "node", "3", func(place *data.UserPlace) error {
+		place.Visits = append(place.Visits, data.Visit{ID: "v3b", VisitedAt: visitedAt.Add(time.Hour), Tags: []string{}})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateUserPlace: %v", err)
+	}
+	if len(updated.Visits) != 2 {
+		t.Errorf("UpdateUserPlace = %+v, want 2 visits", updated)
+	}
+	places, err := repo.GetUserPlaces(ctx, "u1", services.VisitedPlaces)
+	if err != nil {
+		t.Fatalf("GetUserPlaces: %v", err)
+	}
+	if len(places) != 2 || places[0].OsmID != "1" || len(places[0].Visits) != 1 || places[0].Visits[0].ID != "v1" ||
+		places[1].OsmID != "3" || len(places[1].Visits) != 2 || places[1].Visits[1].ID != "v3b" {
+		t.Errorf("GetUserPlaces = %+v, want places 1 and 3 with their own visits", places)
+	}
+
+	// Deleting the last place frees its position for the next one, which
+	// must not inherit the deleted place's visits.
+	if err := repo.DeleteUserPlace(ctx, "u1", services.VisitedPlaces, "node", "3"); err != nil {
+		t.Fatalf("DeleteUserPlace: %v", err)
+	}
+	if err := repo.AddUserPlace(ctx, "u1", services.VisitedPlaces, data.UserPlace{OsmID: "4", OsmType: "node", Tags: []string{}}); err != nil {
+		t.Fatalf("AddUserPlace(4): %v", err)
+	}
+	places, err = repo.GetUserPlaces(ctx, "u1", services.VisitedPlaces)
+	if err != nil {
+		t.Fatalf("GetUserPlaces: %v", err)
+	}
+	if len(places) != 2 || places[1].OsmID != "4" || len(places[1].Visits) != 0 {
+		t.Errorf("GetUserPlaces = %+v, want place 4 without visits", places)
+	}
+
+	if err := repo.AddUserPlace(ctx, "u1", services.WatchedPlaces, data.UserPlace{OsmID: "1", OsmType: "node", Tags: []string{}}); err != nil {
+		t.Fatalf("AddUserPlace watch: %v", err)
+	}
+	if err := repo.DeleteUserPlace(ctx, "u1", services.WatchedPlaces, "node", "5"); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("DeleteUserPlace of an unknown watch: got %v, want ErrPlaceNotFound", err)
+	}
+	if _, err := repo.UpdateUserPlace(ctx, "u1", services.WatchedPlaces, "node", "5", func(*data.UserPlace) error { return nil }); !errors.Is(err, services.ErrPlaceNotFound) {
+		t.Errorf("UpdateUserPlace of an unknown watch: got %v, want ErrPlaceNotFound", err)
+	}
+	watches, err := repo.GetUserPlaces(ctx, "u1", services.WatchedPlaces)
+	if err != nil || len(watches) != 1 || watches[0].Visits != nil {
+		t.Errorf("GetUserPlaces watches = %+v, %v, want place 1 without visits", watches, err)
+	}
+
+	// Deleting the user takes the places and their visits with it.
+	if err := repo.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	createUser(t, repo, "u1")
+	if err := repo.AddUserPlace(ctx, "u1", services.VisitedPlaces, data.UserPlace{OsmID: "6", OsmType: "node", Tags: []string{}}); err != nil {
+		t.Fatalf("AddUserPlace after recreating the user: %v", err)
+	}
+	places, err = repo.GetUserPlaces(ctx, "u1", services.VisitedPlaces)
+	if err != nil || len(places) != 1 || len(places[0].Visits) != 0 {
+		t.Errorf("GetUserPlaces after recreating the user = %+v, %v, want place 6 alone without visits", places, err)
+	}
+}
@@ -1,29 +1,194 @@
 package utils
 
 import (
-	"github.com/gin-gonic/gin"
+	"backend/data"
+	"backend/services"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/MicahParks/keyfunc"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	authSubjectKey = "authSubject"
+	apiKeyKey      = "apiKey"
 )
 
+// APIKeyMiddleware authenticates server-to-server clients that send an
+// "X-API-Key" header. Requests without the header are passed on untouched so
+// JWTMiddleware can authenticate them instead, as are requests that send
+// the legacy shared key along with a bearer token, so clients moving off the
+// shared key are held to their user as soon as they send tokens.
 func APIKeyMiddleware() gin.HandlerFunc {
-	expectedAPIKey := os.Getenv("API_KEY")
-	if expectedAPIKey == "" {
-		panic("API_KEY environment variable not set!")
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" {
+			c.Next()
+			return
+		}
+
+		key, err := services.AuthenticateAPIKey(c.Request.Context(), apiKey)
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			abortUnauthorized(c, "Invalid API Key")
+			return
+		}
+		if err != nil {
+			log.Printf("Error checking API key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error", "code": "internal_error"})
+			return
+		}
+
+		if key.ID == services.LegacyAPIKeyID && c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+
+		c.Set(apiKeyKey, key)
+		c.Next()
+	}
+}
+
+// JWTMiddleware verifies Clerk session tokens sent as "Authorization: Bearer
+// <token>". Requests already authenticated by APIKeyMiddleware are passed
+// on without a token. Signing keys are fetched from CLERK_JWKS_URL and
+// refreshed in the background. If CLERK_ISSUER is set, the token's iss claim
+// must match it. It returns an error if CLERK_JWKS_URL is not set or the
+// keys cannot be fetched.
+func JWTMiddleware() (gin.HandlerFunc, error) {
+	jwksURL := os.Getenv("CLERK_JWKS_URL")
+	if jwksURL == "" {
+		return nil, errors.New("CLERK_JWKS_URL environment variable is not set")
+	}
+
+	jwks, err := keyfunc.Get(jwksURL, keyfunc.Options{
+		RefreshInterval:   time.Hour,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshUnknownKID: true,
+		RefreshErrorHandler: func(err error) {
+			log.Printf("Error refreshing JWKS: %v", err)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching JWKS from %s: %w", jwksURL, err)
 	}
 
+	return NewJWTMiddleware(jwks.Keyfunc, os.Getenv("CLERK_ISSUER")), nil
+}
+
+// NewJWTMiddleware is JWTMiddleware with an explicit key source, such as a
+// keyfunc.JWKS pointed at a local stand-in server. An empty issuer skips the
+// iss check.
+func NewJWTMiddleware(keyFunc jwt.Keyfunc, issuer string) gin.HandlerFunc {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}))
+
 	return func(c *gin.Context) {
-		apiKey := c.GetHeader("X-API-Key")
+		if _, ok := c.Get(apiKeyKey); ok {
+			c.Next()
+			return
+		}
 
-		if apiKey == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API Key is Missing!"})
+		header := c.GetHeader("Authorization")
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || tokenString == "" {
+			abortUnauthorized(c, "Bearer token is missing")
+			return
+		}
+
+		var claims jwt.RegisteredClaims
+		if _, err := parser.ParseWithClaims(tokenString, &claims, keyFunc); err != nil {
+			abortUnauthorized(c, "Invalid token")
+			return
+		}
+		if claims.Subject == "" || (issuer != "" && !claims.VerifyIssuer(issuer, true)) {
+			abortUnauthorized(c, "Invalid token")
 			return
 		}
 
-		if apiKey != expectedAPIKey {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key"})
+		c.Set(authSubjectKey, claims.Subject)
+		c.Next()
+	}
+}
+
+// AuthSubject returns the user ID of the authenticated caller, or "" if the
+// caller authenticated with an API key.
+func AuthSubject(c *gin.Context) string {
+	return c.GetString(authSubjectKey)
+}
+
+// Actor identifies the caller in fields such as AddedBy: the user ID, or
+// "apikey:<key ID>" for API key callers.
+func Actor(c *gin.Context) string {
+	if subject := AuthSubject(c); subject != "" {
+		return subject
+	}
+	if value, ok := c.Get(apiKeyKey); ok {
+		return "apikey:" + value.(*data.APIKey).ID
+	}
+	return ""
+}
+
+// HasScope reports whether the caller authenticated with an API key that
+// grants scope. Admin keys have every scope.
+func HasScope(c *gin.Context, scope string) bool {
+	value, ok := c.Get(apiKeyKey)
+	if !ok {
+		return false
+	}
+	key := value.(*data.APIKey)
+	for _, granted := range key.Scopes {
+		if granted == scope || granted == services.ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessUser reports whether the caller may read or change the data of
+// the given user. Users may only access themselves; API keys need the write
+// scope, or the read scope for GET requests.
+func CanAccessUser(c *gin.Context, userID string) bool {
+	if subject := AuthSubject(c); subject != "" {
+		return subject == userID
+	}
+	if HasScope(c, services.ScopeWrite) {
+		return true
+	}
+	readOnly := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+	return readOnly && HasScope(c, services.ScopeRead)
+}
+
+// RequireScope rejects callers that did not authenticate with an API key
+// granting scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasScope(c, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key scope " + scope + " required", "code": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// AuthorizeUser rejects requests whose :id path parameter names a user other
+// than the authenticated caller. Routes without an :id are let through.
+func AuthorizeUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if userID != "" && !CanAccessUser(c, userID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access to this user is not allowed", "code": "forbidden"})
 			return
 		}
 		c.Next()
 	}
 }
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message, "code": "unauthorized"})
+}
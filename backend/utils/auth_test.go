@@ -0,0 +1,214 @@
+package utils_test
+
+import (
+	"backend/services"
+	"backend/storage"
+	"backend/utils"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testKeyID  = "test-key"
+	testIssuer = "https://clerk.example.test"
+)
+
+// jwksServer serves the public half of a new signing key as a JWKS, as
+// Clerk does, and points CLERK_JWKS_URL and CLERK_ISSUER at it. It returns
+// the private key.
+func jwksServer(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	jwks := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": testKeyID,
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("CLERK_JWKS_URL", server.URL)
+	t.Setenv("CLERK_ISSUER", testIssuer)
+	return key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+func validClaims(subject string) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		Issuer:    testIssuer,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+// authRouter serves /users/:id and /admin behind the same middleware as the
+// API, with API keys kept in an empty in-memory repository.
+func authRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	services.SetRepository(storage.NewMemoryRepository())
+	jwtMiddleware, err := utils.JWTMiddleware()
+	if err != nil {
+		t.Fatalf("JWTMiddleware: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(utils.APIKeyMiddleware(), jwtMiddleware, utils.AuthorizeUser())
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"subject": utils.AuthSubject(c)})
+	})
+	admin := router.Group("/admin")
+	admin.Use(utils.RequireScope(services.ScopeAdmin))
+	admin.GET("/keys", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// request sends a GET request with the given header names and values, in
+// pairs. Pairs with an empty name are left out.
+func request(router *gin.Engine, path string, headers ...string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		if headers[i] != "" {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	key := jwksServer(t)
+	router := authRouter(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	expired := validClaims("user_1")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	wrongIssuer := validClaims("user_1")
+	wrongIssuer.Issuer = "https://elsewhere.example.test"
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"valid token", "/users/user_1", "Authorization", "Bearer " + signToken(t, key, validClaims("user_1")), http.StatusOK},
+		{"another user", "/users/user_2", "Authorization", "Bearer " + signToken(t, key, validClaims("user_1")), http.StatusForbidden},
+		{"no token", "/users/user_1", "", "", http.StatusUnauthorized},
+		{"not a bearer token", "/users/user_1", "Authorization", signToken(t, key, validClaims("user_1")), http.StatusUnauthorized},
+		{"expired token", "/users/user_1", "Authorization", "Bearer " + signToken(t, key, expired), http.StatusUnauthorized},
+		{"wrong issuer", "/users/user_1", "Authorization", "Bearer " + signToken(t, key, wrongIssuer), http.StatusUnauthorized},
+		{"unknown signing key", "/users/user_1", "Authorization", "Bearer " + signToken(t, otherKey, validClaims("user_1")), http.StatusUnauthorized},
+		{"no subject", "/users/user_1", "Authorization", "Bearer " + signToken(t, key, validClaims("")), http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := request(router, test.path, test.header, test.value); got != test.want {
+				t.Errorf("GET %s: status %d, want %d", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAdminScope(t *testing.T) {
+	key := jwksServer(t)
+	router := authRouter(t)
+	ctx := context.Background()
+	_, adminKey, err := services.CreateAPIKey(ctx, "admin", []string{services.ScopeAdmin}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	_, writeKey, err := services.CreateAPIKey(ctx, "write", []string{services.ScopeWrite}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"admin key", "X-API-Key", adminKey, http.StatusOK},
+		{"write key", "X-API-Key", writeKey, http.StatusForbidden},
+		{"unknown key", "X-API-Key", "efk_unknown", http.StatusUnauthorized},
+		{"user token", "Authorization", "Bearer " + signToken(t, key, validClaims("user_1")), http.StatusForbidden},
+		{"no credentials", "", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := request(router, "/admin/keys", test.header, test.value); got != test.want {
+				t.Errorf("GET /admin/keys: status %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLegacyAPIKey(t *testing.T) {
+	key := jwksServer(t)
+	router := authRouter(t)
+	token := "Bearer " + signToken(t, key, validClaims("user_1"))
+
+	if got := request(router, "/users/user_2", "X-API-Key", "shared"); got != http.StatusUnauthorized {
+		t.Errorf("legacy key while turned off: status %d, want 401", got)
+	}
+
+	services.SetLegacyAPIKey("shared")
+	t.Cleanup(func() { services.SetLegacyAPIKey("") })
+	if got := request(router, "/users/user_2", "X-API-Key", "shared"); got != http.StatusOK {
+		t.Errorf("legacy key: status %d, want 200", got)
+	}
+	if got := request(router, "/users/user_2", "X-API-Key", "shared", "Authorization", token); got != http.StatusForbidden {
+		t.Errorf("legacy key with another user's token: status %d, want 403", got)
+	}
+	if got := request(router, "/users/user_1", "X-API-Key", "shared", "Authorization", token); got != http.StatusOK {
+		t.Errorf("legacy key with the user's token: status %d, want 200", got)
+	}
+	if got := request(router, "/admin/keys", "X-API-Key", "shared"); got != http.StatusForbidden {
+		t.Errorf("legacy key on an admin route: status %d, want 403", got)
+	}
+}
+
+func TestJWTMiddlewareWithoutJWKSURL(t *testing.T) {
+	t.Setenv("CLERK_JWKS_URL", "")
+	if _, err := utils.JWTMiddleware(); err == nil {
+		t.Error("JWTMiddleware without CLERK_JWKS_URL succeeded, want an error")
+	}
+}